@@ -64,6 +64,7 @@ type apiData struct {
 	vde         bool
 	vm          *script.VM
 	token       *jwt.Token
+	rawResult   bool // the response has been written by the handler
 }
 
 // ParamString reaturs string value of the api params
@@ -174,6 +175,7 @@ func DefaultHandler(method, pattern string, params map[string]int, handlers ...a
 
 	return hr.Handle(func(w http.ResponseWriter, r *http.Request, ps hr.Params) {
 		counterName := statsd.APIRouteCounterName(method, pattern)
+		class := routeClass(method, pattern)
 		statsd.Client.Inc(counterName+statsd.Count, 1, 1.0)
 		startTime := time.Now()
 		var (
@@ -226,6 +228,12 @@ func DefaultHandler(method, pattern string, params map[string]int, handlers ...a
 				data.keyId = converter.StrToInt64(claims.KeyID)
 			}
 		}
+		if !apiLimiter.allow(class, routeLimits(class), data.keyId, remoteIP(r)) {
+			statsd.Client.Inc(counterName+statsd.RateLimited, 1, 1.0)
+			requestLogger.WithFields(log.Fields{"type": consts.ParameterExceeded, "key_id": data.keyId}).Warning("too many requests")
+			errorAPI(w, `E_LIMITREQUEST`, http.StatusTooManyRequests)
+			return
+		}
 		// Getting and validating request parameters
 		r.ParseForm()
 		data.params = make(map[string]interface{})
@@ -270,6 +278,9 @@ func DefaultHandler(method, pattern string, params map[string]int, handlers ...a
 				return
 			}
 		}
+		if data.rawResult {
+			return
+		}
 		jsonResult, err := json.Marshal(data.result)
 		if err != nil {
 			requestLogger.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marhsalling http response to json")
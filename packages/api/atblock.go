@@ -0,0 +1,122 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/GACHAIN/go-gachain/packages/conf"
+	"github.com/GACHAIN/go-gachain/packages/consts"
+	"github.com/GACHAIN/go-gachain/packages/converter"
+	"github.com/GACHAIN/go-gachain/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// pastState contains the differences between the current rows of the table and the rows at the past block
+type pastState struct {
+	inserted map[string]bool              // rows which have been inserted after the block
+	changes  map[string]map[string]string // values of the changed columns at the block
+}
+
+// newPastState applies rollback records backwards, they must be sorted from the latest to the earliest
+func newPastState(rollbacks []model.RollbackTx) (*pastState, error) {
+	state := &pastState{inserted: make(map[string]bool), changes: make(map[string]map[string]string)}
+	for _, item := range rollbacks {
+		if len(item.Data) == 0 {
+			state.inserted[item.TableID] = true
+			continue
+		}
+		values := make(map[string]string)
+		if err := json.Unmarshal([]byte(item.Data), &values); err != nil {
+			return nil, err
+		}
+		if state.changes[item.TableID] == nil {
+			state.changes[item.TableID] = make(map[string]string)
+		}
+		for key, val := range values {
+			state.changes[item.TableID][key] = val
+		}
+	}
+	return state, nil
+}
+
+// insertedIDs returns the list of rows which didn't exist at the block
+func (ps *pastState) insertedIDs() []int64 {
+	ids := make([]int64, 0, len(ps.inserted))
+	for id := range ps.inserted {
+		ids = append(ids, converter.StrToInt64(id))
+	}
+	return ids
+}
+
+// restore returns the row as it was at the block, nil means that the row didn't exist
+func (ps *pastState) restore(row map[string]string) map[string]string {
+	if ps.inserted[row[`id`]] {
+		return nil
+	}
+	for key, val := range ps.changes[row[`id`]] {
+		if _, ok := row[key]; ok {
+			row[key] = val
+		}
+	}
+	return row
+}
+
+// atBlockState starts read-only transaction and loads the changes of the table after at_block parameter.
+// It returns nil transaction if at_block is not specified.
+func atBlockState(w http.ResponseWriter, data *apiData, logger *log.Entry, table, id string) (
+	*model.DbTransaction, *pastState, error) {
+
+	blockID := data.params[`at_block`].(int64)
+	if blockID <= 0 {
+		return nil, nil, nil
+	}
+	block := &model.Block{}
+	if _, err := block.GetMaxBlock(); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting max block")
+		return nil, nil, errorAPI(w, err, http.StatusInternalServerError)
+	}
+	if blockID > block.ID || block.ID-blockID > conf.Config.MaxAtBlockDepth {
+		logger.WithFields(log.Fields{"type": consts.ParameterExceeded, "block_id": blockID, "max_block_id": block.ID}).Error("at_block is out of range")
+		return nil, nil, errorAPI(w, `E_ATBLOCK`, http.StatusBadRequest, blockID, conf.Config.MaxAtBlockDepth)
+	}
+	transaction, err := model.StartReadOnlyTransaction()
+	if err != nil {
+		return nil, nil, errorAPI(w, err, http.StatusInternalServerError)
+	}
+	rollbacks, err := (&model.RollbackTx{}).GetRollbacksAfterBlock(transaction, table, id, blockID)
+	if err != nil {
+		transaction.Rollback()
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table}).Error("getting rollbacks after block")
+		return nil, nil, errorAPI(w, err, http.StatusInternalServerError)
+	}
+	state, err := newPastState(rollbacks)
+	if err != nil {
+		transaction.Rollback()
+		logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling rollbackTx.Data from JSON")
+		return nil, nil, errorAPI(w, err, http.StatusInternalServerError)
+	}
+	return transaction, state, nil
+}
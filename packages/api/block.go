@@ -25,6 +25,7 @@ package api
 import (
 	"net/http"
 
+	"github.com/GACHAIN/go-gachain/packages/conf"
 	"github.com/GACHAIN/go-gachain/packages/consts"
 	"github.com/GACHAIN/go-gachain/packages/converter"
 	"github.com/GACHAIN/go-gachain/packages/model"
@@ -33,7 +34,9 @@ import (
 )
 
 type GetMaxBlockIDResult struct {
-	MaxBlockID int64 `json:"max_block_id"`
+	MaxBlockID    int64 `json:"max_block_id"`
+	Pruned        bool  `json:"pruned,omitempty"`
+	PrunedBlockID int64 `json:"pruned_block_id,omitempty"`
 }
 
 func getMaxBlockID(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) (err error) {
@@ -47,7 +50,14 @@ func getMaxBlockID(w http.ResponseWriter, r *http.Request, data *apiData, logger
 		log.WithFields(log.Fields{"type": consts.NotFound}).Error("last block not found")
 		return errorAPI(w, `E_NOTFOUND`, http.StatusNotFound)
 	}
-	data.result = &GetMaxBlockIDResult{block.ID}
+	result := &GetMaxBlockIDResult{MaxBlockID: block.ID, Pruned: conf.Config.PruneBlocks > 0}
+	if result.Pruned {
+		if result.PrunedBlockID, err = model.GetPrunedBlockID(); err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting pruned block")
+			return errorAPI(w, err, http.StatusInternalServerError)
+		}
+	}
+	data.result = result
 	return nil
 }
 
@@ -58,6 +68,7 @@ type GetBlockInfoResult struct {
 	Time          int64  `json:"time"`
 	Tx            int32  `json:"tx_count"`
 	RollbacksHash []byte `json:"rollbacks_hash"`
+	Pruned        bool   `json:"pruned,omitempty"`
 }
 
 func getBlockInfo(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) (err error) {
@@ -72,6 +83,6 @@ func getBlockInfo(w http.ResponseWriter, r *http.Request, data *apiData, logger
 		log.WithFields(log.Fields{"type": consts.NotFound, "id": blockID}).Error("block with id not found")
 		return errorAPI(w, `E_NOTFOUND`, http.StatusNotFound)
 	}
-	data.result = &GetBlockInfoResult{Hash: block.Hash, EcosystemID: block.EcosystemID, KeyID: block.KeyID, Time: block.Time, Tx: block.Tx, RollbacksHash: block.RollbacksHash}
+	data.result = &GetBlockInfoResult{Hash: block.Hash, EcosystemID: block.EcosystemID, KeyID: block.KeyID, Time: block.Time, Tx: block.Tx, RollbacksHash: block.RollbacksHash, Pruned: block.IsPruned()}
 	return nil
 }
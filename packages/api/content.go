@@ -23,10 +23,12 @@
 package api
 
 import (
+	"context"
 	"encoding/hex"
 	"encoding/json"
+	"html"
 	"net/http"
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/GACHAIN/go-gachain/packages/conf"
@@ -40,10 +42,15 @@ import (
 )
 
 type contentResult struct {
-	Menu     string          `json:"menu,omitempty"`
-	MenuTree json.RawMessage `json:"menutree,omitempty"`
-	Title    string          `json:"title,omitempty"`
-	Tree     json.RawMessage `json:"tree"`
+	Menu     string           `json:"menu,omitempty"`
+	MenuTree json.RawMessage  `json:"menutree,omitempty"`
+	Title    string           `json:"title,omitempty"`
+	Tree     json.RawMessage  `json:"tree"`
+	Budget   *template.Budget `json:"budget,omitempty"`
+}
+
+type sourceResult struct {
+	Source json.RawMessage `json:"source"`
 }
 
 type hashResult struct {
@@ -67,6 +74,11 @@ func initVars(r *http.Request, data *apiData) *map[string]string {
 }
 
 func getPage(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	key := template.CacheKey(`page/`+getPrefix(data)+`/`+data.params[`name`].(string), *initVars(r, data))
+	if cachedContent(key, data) {
+		return nil
+	}
+	gen := template.CacheGeneration()
 
 	page := &model.Page{}
 	page.SetTablePrefix(getPrefix(data))
@@ -85,41 +97,128 @@ func getPage(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.
 		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting single from DB")
 		return errorAPI(w, `E_SERVER`, http.StatusInternalServerError)
 	}
-	var wg sync.WaitGroup
-	var timeout bool
-	wg.Add(2)
-	success := make(chan bool, 1)
-	go func() {
-		defer wg.Done()
+	ctx, cancel := renderContext(r)
+	defer cancel()
+	budget := renderBudget(data)
+	deps := renderDeps(data, `pages`, `menu`)
+	ret, err := template.Template2JSON(ctx, page.Value, budget, deps, initVars(r, data))
+	if err != nil {
+		return renderError(w, err, page.Name, logger)
+	}
+	retmenu, err := template.Template2JSON(ctx, menu, budget, deps, initVars(r, data))
+	if err != nil {
+		return renderError(w, err, page.Name, logger)
+	}
+	result := &contentResult{Tree: ret, Menu: page.Menu, MenuTree: retmenu, Budget: budget}
+	cacheContent(key, result, deps, gen)
+	data.result = result
+	return nil
+}
 
-		ret := template.Template2JSON(page.Value, &timeout, initVars(r, data))
-		if timeout {
-			return
-		}
-		retmenu := template.Template2JSON(menu, &timeout, initVars(r, data))
-		if timeout {
-			return
-		}
-		data.result = &contentResult{Tree: ret, Menu: page.Menu, MenuTree: retmenu}
-		success <- true
-	}()
-	go func() {
-		defer wg.Done()
-		if conf.Config.MaxPageGenerationTime == 0 {
-			return
-		}
-		select {
-		case <-time.After(time.Duration(conf.Config.MaxPageGenerationTime) * time.Millisecond):
-			timeout = true
-		case <-success:
-		}
-	}()
-	wg.Wait()
-	close(success)
-	if timeout {
-		log.WithFields(log.Fields{"type": consts.InvalidObject}).Error(page.Name + " is a heavy page")
-		return errorAPI(w, `E_HEAVYPAGE`, http.StatusInternalServerError)
+// cachedContent puts the copy of the cached result into data
+func cachedContent(key string, data *apiData) bool {
+	cached, ok := template.CacheGet(key)
+	if !ok {
+		return false
+	}
+	result := *cached.(*contentResult)
+	data.result = &result
+	return true
+}
+
+func cacheContent(key string, result *contentResult, deps *template.Deps, gen uint64) {
+	cached := *result
+	template.CacheSet(key, &cached, deps, gen)
+}
+
+// renderDeps returns the dependencies of the rendered content. They include the parameters
+// of the ecosystem because the budget of rendering is defined by them.
+func renderDeps(data *apiData, tables ...string) *template.Deps {
+	deps := template.NewDeps()
+	deps.Add(getPrefix(data) + `_parameters`)
+	for _, table := range tables {
+		deps.Add(getPrefix(data) + `_` + table)
+	}
+	return deps
+}
+
+// renderContext returns the context of page rendering which is cancelled
+// after MaxPageGenerationTime or when the client has gone
+func renderContext(r *http.Request) (context.Context, context.CancelFunc) {
+	if conf.Config.MaxPageGenerationTime == 0 {
+		return context.WithCancel(r.Context())
+	}
+	return context.WithTimeout(r.Context(), time.Duration(conf.Config.MaxPageGenerationTime)*time.Millisecond)
+}
+
+func renderBudget(data *apiData) *template.Budget {
+	return template.NewBudget(template.GetLimits(data.ecosystemId, data.vde))
+}
+
+func renderError(w http.ResponseWriter, err error, name string, logger *log.Entry) error {
+	if budgetErr, ok := err.(*template.BudgetError); ok {
+		logger.WithFields(log.Fields{"type": consts.ParameterExceeded, "error": err}).Error(name + " has exceeded the rendering budget")
+		return errorAPI(w, `E_PAGEBUDGET`, http.StatusInternalServerError, budgetErr.Error())
+	}
+	logger.WithFields(log.Fields{"type": consts.InvalidObject, "error": err}).Error(name + " is a heavy page")
+	return errorAPI(w, `E_HEAVYPAGE`, http.StatusInternalServerError)
+}
+
+// getSource regenerates the source of the page with the requested offset and order
+func getSource(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	page := &model.Page{}
+	page.SetTablePrefix(getPrefix(data))
+	found, err := page.Get(data.params[`name`].(string))
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting page")
+		return err
+	}
+	if !found {
+		logger.WithFields(log.Fields{"type": consts.NotFound}).Error("page not found")
+		return errorAPI(w, `E_NOTFOUND`, http.StatusNotFound)
+	}
+	ctx, cancel := renderContext(r)
+	defer cancel()
+	req := &template.SourceRequest{Source: data.params[`source`].(string),
+		Offset: data.params[`offset`].(int64), Order: data.params[`order`].(string)}
+	ret, err := template.Template2Source(ctx, page.Value, req, renderBudget(data), initVars(r, data))
+	if err != nil {
+		return renderError(w, err, page.Name, logger)
+	}
+	if ret == nil {
+		logger.WithFields(log.Fields{"type": consts.NotFound, "source": req.Source}).Error("source not found")
+		return errorAPI(w, `E_NOSOURCE`, http.StatusNotFound, req.Source)
 	}
+	data.result = &sourceResult{Source: ret}
+	return nil
+}
+
+// pageHTML renders the page as HTML document if the client accepts text/html
+func pageHTML(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	if !strings.Contains(r.Header.Get(`Accept`), `text/html`) {
+		return nil
+	}
+	result := data.result.(*contentResult)
+	// the api routes of pages require the authorization header, so the links lead to the front-end
+	opts := template.HTMLOptions{PagePath: conf.Config.PagePath}
+	page, err := template.Tree2HTML(result.Tree, opts)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("rendering page to html")
+		return errorAPI(w, `E_SERVER`, http.StatusInternalServerError)
+	}
+	menu, err := template.Menu2HTML(result.MenuTree, opts)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("rendering menu to html")
+		return errorAPI(w, `E_SERVER`, http.StatusInternalServerError)
+	}
+	title := page.Title
+	if len(title) == 0 {
+		title = data.params[`name`].(string)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(`<!DOCTYPE html><html><head><meta charset="utf-8"><title>` + html.EscapeString(title) +
+		`</title></head><body><nav>` + menu + `</nav><main>` + page.Body + `</main></body></html>`))
+	data.rawResult = true
 	return nil
 }
 
@@ -127,7 +226,9 @@ func getPageHash(w http.ResponseWriter, r *http.Request, data *apiData, logger *
 	err = getPage(w, r, data, logger)
 	if err == nil {
 		var out, ret []byte
-		out, err = json.Marshal(data.result.(*contentResult))
+		result := data.result.(*contentResult)
+		result.Budget = nil
+		out, err = json.Marshal(result)
 		if err != nil {
 			log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("getting string for hash")
 			return errorAPI(w, `E_SERVER`, http.StatusInternalServerError)
@@ -143,6 +244,12 @@ func getPageHash(w http.ResponseWriter, r *http.Request, data *apiData, logger *
 }
 
 func getMenu(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	key := template.CacheKey(`menu/`+getPrefix(data)+`/`+data.params[`name`].(string), *initVars(r, data))
+	if cachedContent(key, data) {
+		return nil
+	}
+	gen := template.CacheGeneration()
+
 	menu := &model.Menu{}
 	menu.SetTablePrefix(getPrefix(data))
 	found, err := menu.Get(data.params[`name`].(string))
@@ -155,15 +262,44 @@ func getMenu(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.
 		logger.WithFields(log.Fields{"type": consts.NotFound}).Error("menu not found")
 		return errorAPI(w, `E_NOTFOUND`, http.StatusNotFound)
 	}
-	var timeout bool
-	ret := template.Template2JSON(menu.Value, &timeout, initVars(r, data))
-	data.result = &contentResult{Tree: ret, Title: menu.Title}
+	ctx, cancel := renderContext(r)
+	defer cancel()
+	budget := renderBudget(data)
+	deps := renderDeps(data, `menu`)
+	ret, err := template.Template2JSON(ctx, menu.Value, budget, deps, initVars(r, data))
+	if err != nil {
+		return renderError(w, err, menu.Name, logger)
+	}
+	result := &contentResult{Tree: ret, Title: menu.Title, Budget: budget}
+	cacheContent(key, result, deps, gen)
+	data.result = result
 	return nil
 }
 
 func jsonContent(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
-	var timeout bool
-	ret := template.Template2JSON(data.params[`template`].(string), &timeout, initVars(r, data))
-	data.result = &contentResult{Tree: ret}
+	ctx, cancel := renderContext(r)
+	defer cancel()
+	budget := renderBudget(data)
+	ret, err := template.Template2JSON(ctx, data.params[`template`].(string), budget, nil, initVars(r, data))
+	if err != nil {
+		return renderError(w, err, `template`, logger)
+	}
+	data.result = &contentResult{Tree: ret, Budget: budget}
+	return nil
+}
+
+func validateContent(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	var params []string
+	if len(data.ParamString(`params`)) > 0 {
+		params = strings.Split(data.ParamString(`params`), `,`)
+		for i, name := range params {
+			params[i] = strings.TrimSpace(name)
+		}
+	}
+	result := template.Validate(data.ParamString(`template`), params...)
+	if err := template.CheckRefs(result, data.ecosystemId, data.vde); err != nil {
+		return errorAPI(w, `E_SERVER`, http.StatusInternalServerError)
+	}
+	data.result = result
 	return nil
 }
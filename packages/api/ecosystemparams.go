@@ -55,7 +55,14 @@ func ecosystemParams(w http.ResponseWriter, r *http.Request, data *apiData, logg
 	}
 	sp := &model.StateParameter{}
 	sp.SetTablePrefix(prefix)
-	list, err := sp.GetAllStateParameters()
+	transaction, state, err := atBlockState(w, data, logger, sp.TableName(), ``)
+	if err != nil {
+		return err
+	}
+	if transaction != nil {
+		defer transaction.Rollback()
+	}
+	list, err := sp.GetAllStateParameters(transaction)
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("Getting all state parameters")
 	}
@@ -67,11 +74,20 @@ func ecosystemParams(w http.ResponseWriter, r *http.Request, data *apiData, logg
 		}
 	}
 	for _, item := range list {
-		if names != nil && !names[item.Name] {
+		value := paramValue{ID: converter.Int64ToStr(item.ID),
+			Name: item.Name, Value: item.Value, Conditions: item.Conditions}
+		if state != nil {
+			row := state.restore(map[string]string{`id`: value.ID, `name`: value.Name,
+				`value`: value.Value, `conditions`: value.Conditions})
+			if row == nil {
+				continue
+			}
+			value = paramValue{ID: row[`id`], Name: row[`name`], Value: row[`value`], Conditions: row[`conditions`]}
+		}
+		if names != nil && !names[value.Name] {
 			continue
 		}
-		result.List = append(result.List, paramValue{ID: converter.Int64ToStr(item.ID),
-			Name: item.Name, Value: item.Value, Conditions: item.Conditions})
+		result.List = append(result.List, value)
 	}
 	data.result = &result
 	return
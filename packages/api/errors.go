@@ -24,18 +24,25 @@ package api
 
 var (
 	apiErrors = map[string]string{
+		`E_ATBLOCK`:       `Block %d is out of range of the last %d blocks`,
 		`E_CONTRACT`:      `There is not %s contract`,
 		`E_DBNIL`:         `DB is nil`,
 		`E_ECOSYSTEM`:     `Ecosystem %d doesn't exist`,
 		`E_EMPTYPUBLIC`:   `Public key is undefined`,
 		`E_EMPTYSIGN`:     `Signature is undefined`,
+		`E_EXPORTFORMAT`:  `Export format %s is not supported`,
 		`E_HASHWRONG`:     `Hash is incorrect`,
 		`E_HASHNOTFOUND`:  `Hash has not been found`,
 		`E_HEAVYPAGE`:     `This page is heavy`,
 		`E_INSTALLED`:     `GAChain is already installed`,
 		`E_INVALIDWALLET`: `Wallet %s is not valid`,
+		`E_LIMITREQUEST`:  `Too many requests`,
+		`E_NOPEER`:        `Peer %s has not been found`,
+		`E_NOSEARCH`:      `Table %s doesn't have searchable columns`,
+		`E_NOSOURCE`:      `Source %s has not been found`,
 		`E_NOTFOUND`:      `Page not found`,
 		`E_NOTINSTALLED`:  `GAChain is not installed`,
+		`E_PAGEBUDGET`:    `Page rendering is stopped: %s`,
 		`E_PERMISSION`:    `Permission denied`,
 		`E_QUERY`:         `DB query is wrong`,
 		`E_RECOVERED`:     `API recovered`,
@@ -0,0 +1,182 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/GACHAIN/go-gachain/packages/consts"
+	"github.com/GACHAIN/go-gachain/packages/converter"
+	"github.com/GACHAIN/go-gachain/packages/model"
+	"github.com/GACHAIN/go-gachain/packages/smart"
+	"github.com/GACHAIN/go-gachain/packages/utils/tx"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	exportCSV    = `csv`
+	exportNDJSON = `ndjson`
+
+	exportChunk = 500
+)
+
+// readContract returns the smart contract which is used for checking the read access of the user
+func readContract(data *apiData) *smart.SmartContract {
+	return &smart.SmartContract{VDE: data.vde, VM: data.vm,
+		TxSmart: tx.SmartContract{Header: tx.Header{EcosystemID: data.ecosystemId, KeyID: data.keyId}}}
+}
+
+// readColumns returns the list of columns which can be read by the user, id column goes first
+func readColumns(data *apiData, table string, logger *log.Entry) ([]string, error) {
+	var cols []string
+	if len(data.params[`columns`].(string)) > 0 {
+		for _, col := range strings.Split(data.params[`columns`].(string), `,`) {
+			if col = strings.TrimSpace(col); len(col) > 0 && col != `id` {
+				cols = append(cols, strings.Trim(converter.EscapeName(col), `"`))
+			}
+		}
+	}
+	sc := readContract(data)
+	isCustom, err := sc.IsCustomTable(table)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table}).Error("checking custom table")
+		return nil, err
+	}
+	if isCustom {
+		if _, err = sc.AccessTablePerm(table, `read`); err != nil {
+			return nil, err
+		}
+		if len(cols) == 0 {
+			cols = []string{`*`}
+		}
+		if err = sc.AccessColumns(table, &cols, false); err != nil {
+			return nil, err
+		}
+		sort.Strings(cols)
+	} else if len(cols) == 0 {
+		if cols, err = model.GetColumnNames(table); err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table}).Error("getting column names")
+			return nil, err
+		}
+	}
+	ret := []string{`id`}
+	for _, col := range cols {
+		if col != `id` {
+			ret = append(ret, col)
+		}
+	}
+	return ret, nil
+}
+
+func exportTable(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	format := data.params[`format`].(string)
+	if len(format) == 0 {
+		format = exportCSV
+	}
+	if format != exportCSV && format != exportNDJSON {
+		logger.WithFields(log.Fields{"type": consts.InvalidObject, "format": format}).Error("unknown export format")
+		return errorAPI(w, `E_EXPORTFORMAT`, http.StatusBadRequest, format)
+	}
+	name := data.params[`name`].(string)
+	table := getPrefix(data) + `_` + name
+	if !model.IsTable(table) {
+		logger.WithFields(log.Fields{"type": consts.NotFound, "table": table}).Error("table not found")
+		return errorAPI(w, `E_TABLENOTFOUND`, http.StatusBadRequest, name)
+	}
+	cols, err := readColumns(data, table, logger)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.AccessDenied, "error": err, "table": table}).Error("checking read access")
+		return errorAPI(w, `E_PERMISSION`, http.StatusForbidden)
+	}
+	escaped := make([]string, len(cols))
+	for i, col := range cols {
+		escaped[i] = converter.EscapeName(col)
+	}
+	query := `select ` + strings.Join(escaped, `,`) + ` from ` + converter.EscapeName(table) +
+		` where id > ? order by id`
+
+	var csvWriter *csv.Writer
+	jsonEncoder := json.NewEncoder(w)
+	if format == exportCSV {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		csvWriter = csv.NewWriter(w)
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson; charset=utf-8")
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="`+strings.Trim(converter.EscapeName(name), `"`)+`.`+format+`"`)
+	data.rawResult = true
+
+	var lastID int64
+	for {
+		list, err := model.GetAll(query, exportChunk, lastID)
+		if err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table}).Error("getting rows for export")
+			if lastID == 0 {
+				w.Header().Set("Content-Type", "application/json; charset=utf-8")
+				w.Header().Del("Content-Disposition")
+				return errorAPI(w, `E_QUERY`, http.StatusInternalServerError)
+			}
+			return err
+		}
+		if lastID == 0 && csvWriter != nil {
+			csvWriter.Write(cols)
+		}
+		for _, item := range list {
+			values := make([]string, len(cols))
+			for i, col := range cols {
+				if values[i] = item[col]; values[i] == `NULL` {
+					values[i] = ``
+				}
+			}
+			if csvWriter != nil {
+				err = csvWriter.Write(values)
+			} else {
+				row := make(map[string]string, len(cols))
+				for i, col := range cols {
+					row[col] = values[i]
+				}
+				err = jsonEncoder.Encode(row)
+			}
+			if err != nil {
+				logger.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("writing export data")
+				return err
+			}
+			lastID = converter.StrToInt64(item[`id`])
+		}
+		if csvWriter != nil {
+			csvWriter.Flush()
+		}
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		if len(list) < exportChunk {
+			break
+		}
+	}
+	return nil
+}
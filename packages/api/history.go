@@ -23,41 +23,167 @@
 package api
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"net/http"
 
 	"github.com/GACHAIN/go-gachain/packages/consts"
+	"github.com/GACHAIN/go-gachain/packages/converter"
 	"github.com/GACHAIN/go-gachain/packages/model"
 	log "github.com/sirupsen/logrus"
 )
 
 const rollbackHistoryLimit = 100
 
+type historyDiff struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+type historyVersion struct {
+	BlockID   int64                  `json:"block_id"`
+	BlockTime int64                  `json:"block_time"`
+	TxHash    string                 `json:"tx_hash"`
+	KeyID     string                 `json:"key_id"`
+	Diff      map[string]historyDiff `json:"diff"`
+}
+
 type historyResult struct {
-	List []map[string]string `json:"list"`
+	List     []map[string]string `json:"list"`
+	Versions []historyVersion    `json:"versions"`
+	// PrunedBlockID is the last block whose changes aren't kept, zero means the whole history is available
+	PrunedBlockID int64 `json:"pruned_block_id,omitempty"`
+}
+
+// historyBoundary returns the last block whose rollback data has been removed by the pruning
+// or hasn't been received because the state has been restored from the snapshot
+func historyBoundary() (int64, error) {
+	prunedBlockID, err := model.GetPrunedBlockID()
+	if err != nil {
+		return 0, err
+	}
+	snapshotBlockID, err := model.GetSnapshotBlockID()
+	if err != nil {
+		return 0, err
+	}
+	if snapshotBlockID > prunedBlockID {
+		return snapshotBlockID, nil
+	}
+	return prunedBlockID, nil
+}
+
+// rowVersions restores the versions of the row going from the current values back through the history.
+// The history must be sorted from the latest changes to the earliest ones, the first skip changes
+// are only used for restoring of the values and aren't returned.
+// It returns rollback data and versions for the rest of changes in chronological order.
+func rowVersions(current map[string]string, history []model.RowHistory, skip int) (
+	[]map[string]string, []historyVersion, error) {
+
+	state := make(map[string]string)
+	for key, val := range current {
+		state[key] = val
+	}
+	rollbackList := make([]map[string]string, 0)
+	versions := make([]historyVersion, 0)
+	for i, item := range history {
+		rollback := map[string]string{}
+		if len(item.Data) > 0 {
+			if err := json.Unmarshal([]byte(item.Data), &rollback); err != nil {
+				return nil, nil, err
+			}
+		}
+		diff := make(map[string]historyDiff)
+		if len(item.Data) == 0 {
+			// the row has been inserted
+			for key, val := range state {
+				diff[key] = historyDiff{New: val}
+			}
+		} else {
+			for key, val := range rollback {
+				if state[key] != val {
+					diff[key] = historyDiff{Old: val, New: state[key]}
+				}
+				state[key] = val
+			}
+		}
+		if i < skip {
+			continue
+		}
+		if len(item.Data) > 0 {
+			rollbackList = append(rollbackList, rollback)
+		}
+		versions = append(versions, historyVersion{
+			BlockID:   item.BlockID,
+			BlockTime: item.BlockTime,
+			TxHash:    hex.EncodeToString(item.TxHash),
+			KeyID:     converter.Int64ToStr(item.KeyID),
+			Diff:      diff,
+		})
+	}
+	for i, j := 0, len(versions)-1; i < j; i, j = i+1, j-1 {
+		versions[i], versions[j] = versions[j], versions[i]
+	}
+	for i, j := 0, len(rollbackList)-1; i < j; i, j = i+1, j-1 {
+		rollbackList[i], rollbackList[j] = rollbackList[j], rollbackList[i]
+	}
+	return rollbackList, versions, nil
+}
+
+// newerChanges returns the changes of the row which have been made after the change
+func newerChanges(table, id string, change *model.RowHistory) ([]model.RowHistory, error) {
+	rollbacks, err := (&model.RollbackTx{}).GetRollbacksAfterBlock(nil, table, id, change.BlockID-1)
+	if err != nil {
+		return nil, err
+	}
+	newer := make([]model.RowHistory, 0, len(rollbacks))
+	for _, item := range rollbacks {
+		if item.ID > change.ID {
+			newer = append(newer, model.RowHistory{ID: item.ID, BlockID: item.BlockID, Data: item.Data})
+		}
+	}
+	return newer, nil
 }
 
 func getHistory(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
 	table := getPrefix(data) + "_" + data.params["table"].(string)
 	id := data.params["id"].(string)
-	rollbackTx := &model.RollbackTx{}
-	txs, err := rollbackTx.GetRollbackTxsByTableIDAndTableName(id, table, rollbackHistoryLimit)
+	current, err := model.GetOneRow(`SELECT * FROM `+converter.EscapeName(table)+` WHERE id = ?`, id).String()
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table, "id": id}).Error("getting one row")
+		return errorAPI(w, `E_QUERY`, http.StatusInternalServerError)
+	}
+	limit := int(data.params["limit"].(int64))
+	if limit <= 0 || limit > rollbackHistoryLimit {
+		limit = rollbackHistoryLimit
+	}
+	offset := int(data.params["offset"].(int64))
+	if offset < 0 {
+		offset = 0
+	}
+	history, err := model.GetRowHistory(table, id, data.params["from_block"].(int64),
+		data.params["to_block"].(int64), offset, limit)
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("rollback history")
 		return errorAPI(w, err, http.StatusInternalServerError)
 	}
-	rollbackList := []map[string]string{}
-	for _, tx := range *txs {
-		if tx.Data == "" {
-			continue
-		}
-		rollback := map[string]string{}
-		if err := json.Unmarshal([]byte(tx.Data), &rollback); err != nil {
-			logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling rollbackTx.Data from JSON")
+	var newer []model.RowHistory
+	if len(history) > 0 {
+		// the values of the row at the latest change of the page are restored from the current values
+		if newer, err = newerChanges(table, id, &history[0]); err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("rollback history")
 			return errorAPI(w, err, http.StatusInternalServerError)
 		}
-		rollbackList = append(rollbackList, rollback)
 	}
-	data.result = &historyResult{rollbackList}
+	rollbackList, versions, err := rowVersions(current, append(newer, history...), len(newer))
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling rollbackTx.Data from JSON")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	prunedBlockID, err := historyBoundary()
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting pruned block")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	data.result = &historyResult{List: rollbackList, Versions: versions, PrunedBlockID: prunedBlockID}
 	return nil
 }
@@ -25,6 +25,8 @@ package api
 import (
 	stdErrors "errors"
 	"testing"
+
+	"github.com/GACHAIN/go-gachain/packages/model"
 )
 
 func TestHistory(t *testing.T) {
@@ -52,3 +54,41 @@ func TestHistory(t *testing.T) {
 		t.Error(stdErrors.New("History should be empty"))
 	}
 }
+
+func TestRowVersions(t *testing.T) {
+	history := []model.RowHistory{
+		{BlockID: 7, TxHash: []byte{3}, KeyID: 3, Data: `{"name":"second","value":"2"}`},
+		{BlockID: 5, TxHash: []byte{2}, KeyID: 2, Data: `{"value":"1"}`},
+		{BlockID: 2, TxHash: []byte{1}, KeyID: 1},
+	}
+	current := map[string]string{`id`: `1`, `name`: `third`, `value`: `3`}
+	list, versions, err := rowVersions(current, history, 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(list) != 2 || len(versions) != 3 {
+		t.Errorf(`wrong count of versions %d %d`, len(list), len(versions))
+		return
+	}
+	if versions[0].BlockID != 2 || versions[0].Diff[`name`].New != `second` || versions[0].Diff[`value`].New != `1` {
+		t.Errorf(`wrong insert version %v`, versions[0])
+	}
+	if diff := versions[1].Diff; len(diff) != 1 || diff[`value`] != (historyDiff{Old: `1`, New: `2`}) {
+		t.Errorf(`wrong second version %v`, diff)
+	}
+	if diff := versions[2].Diff; len(diff) != 2 || diff[`name`] != (historyDiff{Old: `second`, New: `third`}) ||
+		versions[2].KeyID != `3` || versions[2].TxHash != `03` {
+		t.Errorf(`wrong third version %v`, versions[2])
+	}
+
+	// the first change is out of the page
+	_, versions, err = rowVersions(current, history, 1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(versions) != 2 || versions[1].Diff[`value`].New != `2` {
+		t.Errorf(`wrong versions of block range %v`, versions)
+	}
+}
@@ -31,7 +31,6 @@ import (
 
 	"github.com/GACHAIN/go-gachain/packages/conf"
 
-	"github.com/GACHAIN/go-gachain/packages/config/syspar"
 	"github.com/GACHAIN/go-gachain/packages/consts"
 	"github.com/GACHAIN/go-gachain/packages/daylight/daemonsctl"
 	"github.com/GACHAIN/go-gachain/packages/install"
@@ -69,12 +68,8 @@ func installCommon(data *installParams, logger *log.Entry) (err error) {
 
 	conf.Config.LogLevel = data.logLevel
 
-	if len(data.firstLoadBlockchainURL) == 0 {
-		log.WithFields(log.Fields{
-			"url": syspar.GetBlockchainURL(),
-		}).Info("firstLoadBlockchainURL is not set through POST data, setting it to first load blockchain url from syspar")
-		data.firstLoadBlockchainURL = syspar.GetBlockchainURL()
-	}
+	// the blockchain is loaded from the archive only if its url is set explicitly, otherwise blocks are got from nodes
+	conf.Config.FirstLoadBlockchainURL = data.firstLoadBlockchainURL
 
 	conf.Config.DB.Host = data.dbHost
 	conf.Config.DB.Port = converter.StrToInt(data.dbPort)
@@ -42,13 +42,21 @@ type listResult struct {
 func list(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) (err error) {
 	var limit int
 
-	table := converter.EscapeName(getPrefix(data) + `_` + data.params[`name`].(string))
+	tableName := getPrefix(data) + `_` + data.params[`name`].(string)
+	table := converter.EscapeName(tableName)
 	cols := `*`
 	if len(data.params[`columns`].(string)) > 0 {
 		cols = `id,` + converter.EscapeName(data.params[`columns`].(string))
 	}
 
-	count, err := model.GetNextID(nil, strings.Trim(table, `"`))
+	transaction, state, err := atBlockState(w, data, logger, tableName, ``)
+	if err != nil {
+		return err
+	}
+	if transaction != nil {
+		defer transaction.Rollback()
+	}
+	count, err := model.GetNextID(transaction, strings.Trim(table, `"`))
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table}).Error("Getting next table id")
 		return errorAPI(w, `E_TABLENOTFOUND`, http.StatusBadRequest, data.params[`name`].(string))
@@ -59,12 +67,26 @@ func list(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Ent
 	} else {
 		limit = 25
 	}
-	list, err := model.GetAll(`select `+cols+` from `+table+` order by id desc`+
-		fmt.Sprintf(` offset %d `, data.params[`offset`].(int64)), limit)
+	var (
+		where string
+		args  []interface{}
+	)
+	if state != nil && len(state.inserted) > 0 {
+		where = ` where id not in (?)`
+		args = append(args, state.insertedIDs())
+		count -= int64(len(state.inserted))
+	}
+	list, err := model.GetAllTransaction(transaction, `select `+cols+` from `+table+where+` order by id desc`+
+		fmt.Sprintf(` offset %d `, data.params[`offset`].(int64)), limit, args...)
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table}).Error("Getting rows from table")
 		return errorAPI(w, err.Error(), http.StatusInternalServerError)
 	}
+	if state != nil {
+		for _, item := range list {
+			state.restore(item)
+		}
+	}
 	data.result = &listResult{
 		Count: converter.Int64ToStr(count - 1), List: list,
 	}
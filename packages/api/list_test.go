@@ -24,9 +24,12 @@ package api
 
 import (
 	"fmt"
+	"net/url"
 	"testing"
 
 	"github.com/GACHAIN/go-gachain/packages/converter"
+	"github.com/GACHAIN/go-gachain/packages/crypto"
+	"github.com/GACHAIN/go-gachain/packages/model"
 )
 
 func TestList(t *testing.T) {
@@ -50,3 +53,71 @@ func TestList(t *testing.T) {
 		return
 	}
 }
+
+func TestPastState(t *testing.T) {
+	state, err := newPastState([]model.RollbackTx{
+		{TableID: `3`},
+		{TableID: `2`, Data: `{"amount":"20"}`},
+		{TableID: `2`, Data: `{"amount":"10","name":"old"}`},
+		{TableID: `1`, Data: `{"name":"first"}`},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if row := state.restore(map[string]string{`id`: `3`, `name`: `new`}); row != nil {
+		t.Errorf(`row 3 must be absent %v`, row)
+	}
+	row := state.restore(map[string]string{`id`: `2`, `name`: `cur`, `amount`: `30`})
+	if row[`name`] != `old` || row[`amount`] != `10` {
+		t.Errorf(`wrong row 2 %v`, row)
+	}
+	row = state.restore(map[string]string{`id`: `1`, `amount`: `5`})
+	if _, ok := row[`name`]; ok || row[`amount`] != `5` {
+		t.Errorf(`wrong row 1 %v`, row)
+	}
+	if ids := state.insertedIDs(); len(ids) != 1 || ids[0] != 3 {
+		t.Errorf(`wrong inserted ids %v`, ids)
+	}
+}
+
+func TestRowAtBlock(t *testing.T) {
+	if err := keyLogin(1); err != nil {
+		t.Error(err)
+		return
+	}
+	rnd := `rnd` + crypto.RandSeq(6)
+	form := url.Values{`Value`: {`contract ` + rnd + ` {
+		action { $result = "ok" }}`}, `Conditions`: {`true`}}
+	created, _, err := postTxResult(`NewContract`, &form)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var contract getContractResult
+	if err = sendGet(`contract/`+rnd, nil, &contract); err != nil {
+		t.Error(err)
+		return
+	}
+	if err = postTx(`ActivateContract`, &url.Values{`Id`: {contract.TableID}}); err != nil {
+		t.Error(err)
+		return
+	}
+	var row rowResult
+	err = sendGet(`row/contracts/`+contract.TableID+`?columns=active&at_block=`+converter.Int64ToStr(created), nil, &row)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if row.Value[`active`] != `0` {
+		t.Errorf(`wrong past row %v`, row.Value)
+	}
+	err = sendGet(`row/contracts/`+contract.TableID+`?columns=active&at_block=`+converter.Int64ToStr(created-1), nil, &row)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(row.Value) != 0 {
+		t.Errorf(`row inserted after the block must be empty %v`, row.Value)
+	}
+}
@@ -0,0 +1,67 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/GACHAIN/go-gachain/packages/conf"
+	"github.com/GACHAIN/go-gachain/packages/consts"
+	"github.com/GACHAIN/go-gachain/packages/peers"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type peersResult struct {
+	List []peers.Peer `json:"list"`
+}
+
+type unbanResult struct {
+	Result bool `json:"result"`
+}
+
+// authNode allows the request only for the owner of the node
+func authNode(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	if data.keyId != conf.Config.KeyID || data.ecosystemId != 1 || data.vde {
+		logger.WithFields(log.Fields{"type": consts.AccessDenied, "error": fmt.Errorf(`Access denied`)}).Error("node administration")
+		return errorAPI(w, `E_PERMISSION`, http.StatusUnauthorized)
+	}
+	return nil
+}
+
+func getPeers(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	data.result = &peersResult{List: peers.Peers()}
+	return nil
+}
+
+func unbanPeer(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	host := data.params[`host`].(string)
+	if !peers.Unban(host) {
+		logger.WithFields(log.Fields{"type": consts.NotFound, "host": host}).Error("unbanning peer")
+		return errorAPI(w, `E_NOPEER`, http.StatusNotFound, host)
+	}
+	logger.WithFields(log.Fields{"host": host}).Info("peer has been unbanned")
+	data.result = &unbanResult{Result: true}
+	return nil
+}
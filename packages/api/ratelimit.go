@@ -0,0 +1,153 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GACHAIN/go-gachain/packages/conf"
+	"github.com/GACHAIN/go-gachain/packages/converter"
+)
+
+const (
+	routeRead = iota
+	routeRender
+	routeSubmit
+
+	// buckets which have not been used longer than this are removed
+	bucketIdleTime = 10 * time.Minute
+)
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// burst returns the capacity of the bucket, at least one request must pass, otherwise
+// the missing Burst in the config would reject all requests
+func burst(limit conf.RateLimitConfig) float64 {
+	if limit.Burst < 1 {
+		return 1
+	}
+	return float64(limit.Burst)
+}
+
+// refill adds tokens according to the elapsed time
+func (b *tokenBucket) refill(limit conf.RateLimitConfig, now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * limit.Rate
+	if b.tokens > burst(limit) {
+		b.tokens = burst(limit)
+	}
+	b.last = now
+}
+
+type rateLimiter struct {
+	mutex     sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastClean time.Time
+	now       func() time.Time
+}
+
+var apiLimiter = newRateLimiter()
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{buckets: make(map[string]*tokenBucket), now: time.Now}
+}
+
+func (rl *rateLimiter) bucket(name string, limit conf.RateLimitConfig, now time.Time) *tokenBucket {
+	bucket, ok := rl.buckets[name]
+	if !ok {
+		bucket = &tokenBucket{tokens: burst(limit), last: now}
+		rl.buckets[name] = bucket
+	}
+	bucket.refill(limit, now)
+	return bucket
+}
+
+// allow returns false if the request of the key or ip exceeds the limits of the route class.
+// A token is taken from the buckets only when the request is allowed by all of them.
+func (rl *rateLimiter) allow(class int, limits conf.RouteLimitConfig, keyID int64, ip string) bool {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	now := rl.now()
+	if now.Sub(rl.lastClean) > bucketIdleTime {
+		for name, bucket := range rl.buckets {
+			if now.Sub(bucket.last) > bucketIdleTime {
+				delete(rl.buckets, name)
+			}
+		}
+		rl.lastClean = now
+	}
+	prefix := converter.IntToStr(class)
+	buckets := make([]*tokenBucket, 0, 2)
+	if len(ip) > 0 && limits.PerIP.Rate > 0 {
+		buckets = append(buckets, rl.bucket(prefix+`ip`+ip, limits.PerIP, now))
+	}
+	if keyID != 0 && limits.PerKey.Rate > 0 {
+		buckets = append(buckets, rl.bucket(prefix+`key`+converter.Int64ToStr(keyID), limits.PerKey, now))
+	}
+	for _, bucket := range buckets {
+		if bucket.tokens < 1 {
+			return false
+		}
+	}
+	for _, bucket := range buckets {
+		bucket.tokens--
+	}
+	return true
+}
+
+func routeClass(method, pattern string) int {
+	switch {
+	case strings.HasPrefix(pattern, `content`):
+		return routeRender
+	case method == `POST` && (strings.HasPrefix(pattern, `contract/`) ||
+		strings.HasPrefix(pattern, `prepare/`) || strings.HasPrefix(pattern, `node/`) ||
+		strings.HasPrefix(pattern, `vde/`)):
+		return routeSubmit
+	}
+	return routeRead
+}
+
+func routeLimits(class int) conf.RouteLimitConfig {
+	switch class {
+	case routeRender:
+		return conf.Config.APIRateLimit.Render
+	case routeSubmit:
+		return conf.Config.APIRateLimit.Submit
+	}
+	return conf.Config.APIRateLimit.Read
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
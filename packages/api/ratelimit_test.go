@@ -0,0 +1,83 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GACHAIN/go-gachain/packages/conf"
+)
+
+func TestRateLimit(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	rl := newRateLimiter()
+	rl.now = func() time.Time { return now }
+	limits := conf.RouteLimitConfig{PerKey: conf.RateLimitConfig{Rate: 1, Burst: 2},
+		PerIP: conf.RateLimitConfig{Rate: 10, Burst: 3}}
+
+	for i := 0; i < 2; i++ {
+		if !rl.allow(routeRead, limits, 1, `10.0.0.1`) {
+			t.Errorf(`request %d must be allowed`, i)
+		}
+	}
+	if rl.allow(routeRead, limits, 1, `10.0.0.1`) {
+		t.Error(`key limit has been exceeded`)
+	}
+	if !rl.allow(routeRender, limits, 1, `10.0.0.1`) {
+		t.Error(`route classes must have separate buckets`)
+	}
+	if !rl.allow(routeRead, limits, 2, `10.0.0.1`) {
+		t.Error(`another key must be allowed`)
+	}
+	if rl.allow(routeRead, limits, 3, `10.0.0.1`) {
+		t.Error(`ip limit has been exceeded`)
+	}
+	now = now.Add(time.Second)
+	if !rl.allow(routeRead, limits, 1, `10.0.0.1`) {
+		t.Error(`bucket has not been refilled`)
+	}
+	if !rl.allow(routeRead, conf.RouteLimitConfig{}, 1, `10.0.0.1`) {
+		t.Error(`zero rate must be unlimited`)
+	}
+	noBurst := conf.RouteLimitConfig{PerKey: conf.RateLimitConfig{Rate: 1}}
+	if !rl.allow(routeSubmit, noBurst, 5, ``) {
+		t.Error(`zero burst must allow one request`)
+	}
+	if rl.allow(routeSubmit, noBurst, 5, ``) {
+		t.Error(`zero burst must be one token`)
+	}
+	now = now.Add(time.Second)
+	if !rl.allow(routeSubmit, noBurst, 5, ``) {
+		t.Error(`bucket with zero burst has not been refilled`)
+	}
+	for pattern, class := range map[string]int{`content/page/:name`: routeRender,
+		`contract/:name`: routeSubmit, `prepare/:name`: routeSubmit, `list/:name`: routeRead} {
+		if routeClass(`POST`, pattern) != class {
+			t.Errorf(`wrong class of %s`, pattern)
+		}
+	}
+	if routeClass(`GET`, `contract/:name`) != routeRead {
+		t.Error(`getting of contract must be read class`)
+	}
+}
@@ -55,28 +55,34 @@ func Route(route *hr.Router) {
 
 	route.Handle(`OPTIONS`, consts.ApiPath+`*name`, optionsHandler())
 	route.Handle(`GET`, consts.ApiPath+`data/:table/:id/:column/:hash`, dataHandler())
+	route.Handle(`GET`, consts.ApiPath+`snapshot`, snapshotHandler())
 
 	get(`balance/:wallet`, `?ecosystem:int64`, authWallet, balance)
 	get(`contract/:name`, ``, authWallet, getContract)
 	get(`contracts`, `?limit ?offset:int64`, authWallet, getContracts)
 	get(`ecosystemparam/:name`, `?ecosystem:int64`, authWallet, ecosystemParam)
-	get(`ecosystemparams`, `?ecosystem:int64,?names:string`, authWallet, ecosystemParams)
+	get(`ecosystemparams`, `?ecosystem ?at_block:int64,?names:string`, authWallet, ecosystemParams)
 	get(`ecosystems`, ``, authWallet, ecosystems)
+	get(`export/:name`, `?format ?columns:string`, authWallet, exportTable)
 	get(`getuid`, ``, getUID)
-	get(`list/:name`, `?limit ?offset:int64,?columns:string`, authWallet, list)
-	get(`row/:name/:id`, `?columns:string`, authWallet, row)
+	get(`list/:name`, `?limit ?offset ?at_block:int64,?columns:string`, authWallet, list)
+	get(`row/:name/:id`, `?at_block:int64,?columns:string`, authWallet, row)
+	get(`search/:table`, `query:string,?columns:string,?limit ?offset:int64`, authWallet, search)
 	get(`systemparams`, `?names:string`, authWallet, systemParams)
 	get(`table/:name`, ``, authWallet, table)
 	get(`tables`, `?limit ?offset:int64`, authWallet, tables)
 	get(`txstatus/:hash`, ``, authWallet, txstatus)
 	get(`test/:name`, ``, getTest)
-	get(`history/:table/:id`, ``, authWallet, getHistory)
+	get(`history/:table/:id`, `?from_block ?to_block ?offset ?limit:int64`, authWallet, getHistory)
 	get(`block/:id`, ``, getBlockInfo)
 	get(`maxblockid`, ``, getMaxBlockID)
+	get(`peers`, ``, authWallet, authNode, getPeers)
 
-	post(`content/page/:name`, ``, authWallet, getPage)
+	post(`content/page/:name`, ``, authWallet, getPage, pageHTML)
 	post(`content/menu/:name`, ``, authWallet, getMenu)
 	post(`content/hash/:name`, ``, authWallet, getPageHash)
+	post(`content/source/:name`, `source:string,?offset:int64,?order:string`, authWallet, getSource)
+	post(`peers/:host/unban`, ``, authWallet, authNode, unbanPeer)
 	post(`install`, `?first_load_blockchain_url ?first_block_dir log_level type db_host db_port 
 	db_name db_pass db_user ?centrifugo_url ?centrifugo_secret:string,?generate_first_block:int64`, doInstall)
 	post(`vde/create`, ``, authWallet, vdeCreate)
@@ -86,6 +92,7 @@ func Route(route *hr.Router) {
 	post(`signtest/`, `forsign private:string`, signTest)
 	post(`test/:name`, ``, getTest)
 	post(`content`, `template:string`, jsonContent)
+	post(`content/validate`, `template:string,?params:string`, authWallet, validateContent)
 
 	methodRoute(route, `POST`, `node/:name`, `?token_ecosystem:int64,?max_sum ?payover:string`, nodeContract)
 }
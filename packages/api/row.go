@@ -39,14 +39,28 @@ type rowResult struct {
 func row(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) (err error) {
 	cols := `*`
 	if len(data.params[`columns`].(string)) > 0 {
-		cols = converter.EscapeName(data.params[`columns`].(string))
+		// id is required for the restoring of the row at the past block
+		cols = `id,` + converter.EscapeName(data.params[`columns`].(string))
 	}
-	table := converter.EscapeName(getPrefix(data) + `_` + data.params[`name`].(string))
-	row, err := model.GetOneRow(`SELECT `+cols+` FROM `+table+` WHERE id = ?`, data.params[`id`].(string)).String()
+	tableName := getPrefix(data) + `_` + data.params[`name`].(string)
+	table := converter.EscapeName(tableName)
+	transaction, state, err := atBlockState(w, data, logger, tableName, data.params[`id`].(string))
+	if err != nil {
+		return err
+	}
+	if transaction != nil {
+		defer transaction.Rollback()
+	}
+	row, err := model.GetOneRowTransaction(transaction, `SELECT `+cols+` FROM `+table+` WHERE id = ?`, data.params[`id`].(string)).String()
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": data.params["name"].(string), "id": data.params["id"].(string)}).Error("getting one row")
 		return errorAPI(w, `E_QUERY`, http.StatusInternalServerError)
 	}
+	if state != nil && len(row) > 0 {
+		if row = state.restore(row); row == nil {
+			row = make(map[string]string)
+		}
+	}
 
 	data.result = &rowResult{Value: row}
 	return
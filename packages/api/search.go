@@ -0,0 +1,87 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/GACHAIN/go-gachain/packages/consts"
+	"github.com/GACHAIN/go-gachain/packages/converter"
+	"github.com/GACHAIN/go-gachain/packages/model"
+	"github.com/GACHAIN/go-gachain/packages/smart"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func search(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) (err error) {
+	var limit int
+
+	name := data.params[`table`].(string)
+	tableName := getPrefix(data) + `_` + name
+	if !model.IsTable(tableName) {
+		logger.WithFields(log.Fields{"type": consts.NotFound, "table": tableName}).Error("table not found")
+		return errorAPI(w, `E_TABLENOTFOUND`, http.StatusBadRequest, name)
+	}
+	cols, err := readColumns(data, tableName, logger)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.AccessDenied, "error": err, "table": tableName}).Error("checking read access")
+		return errorAPI(w, `E_PERMISSION`, http.StatusForbidden)
+	}
+	searchCols, err := smart.SearchColumns(readContract(data), tableName, true)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": tableName}).Error("getting searchable columns")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	if len(searchCols) == 0 {
+		logger.WithFields(log.Fields{"type": consts.NotFound, "table": tableName}).Error("searchable columns not found")
+		return errorAPI(w, `E_NOSEARCH`, http.StatusBadRequest, name)
+	}
+	for i, col := range cols {
+		cols[i] = converter.EscapeName(col)
+	}
+	table := converter.EscapeName(tableName)
+	where, args := model.SearchWhere(searchCols, data.params[`query`].(string))
+
+	count, err := model.Single(`select count(*) from `+table+` where `+where, args...).Int64()
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table}).Error("counting found rows")
+		return errorAPI(w, `E_QUERY`, http.StatusInternalServerError)
+	}
+	if data.params[`limit`].(int64) > 0 {
+		limit = int(data.params[`limit`].(int64))
+	} else {
+		limit = 25
+	}
+	list, err := model.GetAllTransaction(nil, `select `+strings.Join(cols, `,`)+` from `+table+` where `+where+
+		` order by id desc`+fmt.Sprintf(` offset %d `, data.params[`offset`].(int64)), limit, args...)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table}).Error("searching rows in table")
+		return errorAPI(w, `E_QUERY`, http.StatusInternalServerError)
+	}
+	data.result = &listResult{
+		Count: converter.Int64ToStr(count), List: list,
+	}
+	return
+}
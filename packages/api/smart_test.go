@@ -234,6 +234,42 @@ func TestPage(t *testing.T) {
 	}
 }
 
+func TestBlockParams(t *testing.T) {
+	if err := keyLogin(1); err != nil {
+		t.Error(err)
+		return
+	}
+	name := randName(`blockpar`)
+	form := url.Values{"Name": {name}, "Value": {`Span(#title#)`}, "Params": {`title`},
+		"Conditions": {"ContractConditions(`MainCondition`)"}}
+	_, id, err := postTxResult(`NewBlock`, &form)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var row rowResult
+	if err = sendGet(`row/blocks/`+id, nil, &row); err != nil {
+		t.Error(err)
+		return
+	}
+	if row.Value[`params`] != `title` {
+		t.Errorf(`wrong params %v`, row.Value)
+	}
+	form = url.Values{"Id": {id}, "Value": {`Span(Text)`}, "Params": {``},
+		"Conditions": {"ContractConditions(`MainCondition`)"}}
+	if err = postTx(`EditBlock`, &form); err != nil {
+		t.Error(err)
+		return
+	}
+	if err = sendGet(`row/blocks/`+id, nil, &row); err != nil {
+		t.Error(err)
+		return
+	}
+	if row.Value[`params`] != `` {
+		t.Errorf(`params have not been cleared %v`, row.Value)
+	}
+}
+
 func TestNewTable(t *testing.T) {
 	if err := keyLogin(1); err != nil {
 		t.Error(err)
@@ -39,7 +39,7 @@ func systemParams(w http.ResponseWriter, r *http.Request, data *apiData, logger
 	)
 	sp := &model.StateParameter{}
 	sp.SetTablePrefix(`system`)
-	list, err := sp.GetAllStateParameters()
+	list, err := sp.GetAllStateParameters(nil)
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("Getting all system parameters")
 	}
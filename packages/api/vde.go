@@ -35,6 +35,7 @@ import (
 	"github.com/GACHAIN/go-gachain/packages/model"
 	"github.com/GACHAIN/go-gachain/packages/script"
 	"github.com/GACHAIN/go-gachain/packages/smart"
+	"github.com/GACHAIN/go-gachain/packages/template"
 
 	"github.com/shopspring/decimal"
 	log "github.com/sirupsen/logrus"
@@ -184,7 +185,10 @@ func VDEContract(contractData []byte, data *apiData) (result *contractResult, er
 			sc.TxData[`auth_token`] = auth
 		}
 	}
-	if ret, err = sc.CallContract(smart.CallInit | smart.CallCondition | smart.CallAction); err == nil {
+	ret, err = sc.CallContract(smart.CallInit | smart.CallCondition | smart.CallAction)
+	// VDE contracts don't make blocks, so their changes are visible at once
+	template.InvalidateTables(sc.TxTables)
+	if err == nil {
 		result.Result = ret
 	} else {
 		if errResult := json.Unmarshal([]byte(err.Error()), &result.Message); errResult != nil {
@@ -0,0 +1,523 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package archive stores blocks of the blockchain in the portable format.
+// The archive is a directory with the manifest and the segments, every segment contains
+// a sequence of blocks. The data file of the segment is gzip members, one member per block,
+// and the index file of the segment has the offset, the size and the crc32 of every block,
+// so a block can be read by its id without reading the other blocks.
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	// Version is the version of the archive format
+	Version = 1
+	// DefaultSegmentBlocks is the default number of blocks in the segment
+	DefaultSegmentBlocks = 10000
+	// ManifestFile is the name of the manifest file
+	ManifestFile = "manifest.json"
+
+	dataExt   = ".gz"
+	indexExt  = ".idx"
+	entrySize = 16 // offset (8), size (4), crc32 (4)
+
+	// maxBlockSize is the max size of the unpacked block, it's the same limit as for the blocks
+	// which are received from hosts, so the small gzip member can't unpack to the huge block
+	maxBlockSize = 10485760
+)
+
+var (
+	// ErrNotFound is returned if the block isn't in the archive
+	ErrNotFound = errors.New("block not found in archive")
+	// ErrChecksum is returned if the data doesn't match the checksum
+	ErrChecksum = errors.New("wrong checksum of archive")
+)
+
+// Segment is the description of the segment in the manifest
+type Segment struct {
+	Name          string `json:"name"`
+	FirstBlockID  int64  `json:"first_block_id"`
+	LastBlockID   int64  `json:"last_block_id"`
+	Size          int64  `json:"size"`
+	Checksum      string `json:"checksum"`
+	IndexChecksum string `json:"index_checksum"`
+}
+
+// DataFile returns the name of the data file
+func (s *Segment) DataFile() string {
+	return s.Name + dataExt
+}
+
+// IndexFile returns the name of the index file
+func (s *Segment) IndexFile() string {
+	return s.Name + indexExt
+}
+
+func (s *Segment) count() int64 {
+	return s.LastBlockID - s.FirstBlockID + 1
+}
+
+// Manifest describes the segments of the archive
+type Manifest struct {
+	Version       int       `json:"version"`
+	SegmentBlocks int64     `json:"segment_blocks"`
+	FirstBlockID  int64     `json:"first_block_id"`
+	LastBlockID   int64     `json:"last_block_id"`
+	Segments      []Segment `json:"segments"`
+}
+
+// Validate checks that the manifest is consistent
+func (m *Manifest) Validate() error {
+	if m.Version != Version {
+		return fmt.Errorf("unsupported version %d of archive", m.Version)
+	}
+	if m.SegmentBlocks <= 0 {
+		return fmt.Errorf("wrong number of blocks %d in segment", m.SegmentBlocks)
+	}
+	if len(m.Segments) == 0 {
+		if m.FirstBlockID != 0 || m.LastBlockID != 0 {
+			return errors.New("archive without segments has blocks")
+		}
+		return nil
+	}
+	next := m.FirstBlockID
+	for _, s := range m.Segments {
+		if s.Name != segmentName(s.FirstBlockID) {
+			return fmt.Errorf("wrong name %q of segment", s.Name)
+		}
+		if s.FirstBlockID != next || s.LastBlockID < s.FirstBlockID || s.count() > m.SegmentBlocks {
+			return fmt.Errorf("wrong blocks %d-%d of segment %s", s.FirstBlockID, s.LastBlockID, s.Name)
+		}
+		if s.Size < 0 {
+			return fmt.Errorf("wrong size %d of segment %s", s.Size, s.Name)
+		}
+		next = s.LastBlockID + 1
+	}
+	if m.FirstBlockID < 1 || next != m.LastBlockID+1 {
+		return fmt.Errorf("wrong blocks %d-%d of archive", m.FirstBlockID, m.LastBlockID)
+	}
+	return nil
+}
+
+// segment returns the index of the segment which contains the block
+func (m *Manifest) segment(blockID int64) (int, error) {
+	if len(m.Segments) == 0 || blockID < m.FirstBlockID || blockID > m.LastBlockID {
+		return 0, ErrNotFound
+	}
+	return sort.Search(len(m.Segments), func(i int) bool {
+		return m.Segments[i].LastBlockID >= blockID
+	}), nil
+}
+
+func segmentName(firstBlockID int64) string {
+	return fmt.Sprintf("%010d", firstBlockID)
+}
+
+// Archive is the directory with the blocks
+type Archive struct {
+	Manifest
+
+	dir   string
+	data  *os.File // data file of the last segment which is opened for appending
+	index *os.File
+	dirty map[int]bool // segments which are changed after the last commit
+}
+
+// Open opens the archive in the directory, the empty archive is created if the directory
+// doesn't contain the manifest, segmentBlocks is used only for the new archive.
+// The blocks which have been appended after the last commit are discarded.
+func Open(dir string, segmentBlocks int64) (*Archive, error) {
+	if segmentBlocks <= 0 {
+		segmentBlocks = DefaultSegmentBlocks
+	}
+	a := &Archive{
+		Manifest: Manifest{Version: Version, SegmentBlocks: segmentBlocks},
+		dir:      dir,
+		dirty:    make(map[int]bool),
+	}
+	m, err := ReadManifest(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return a, os.MkdirAll(dir, 0755)
+		}
+		return nil, err
+	}
+	a.Manifest = *m
+	return a, nil
+}
+
+// ReadManifest reads and validates the manifest of the archive in the directory
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	return parseManifest(data)
+}
+
+func parseManifest(data []byte) (*Manifest, error) {
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Append appends the block to the archive, the blocks are appended in the order of id.
+// The block isn't seen by the readers of the directory until Commit is called.
+func (a *Archive) Append(blockID int64, data []byte) error {
+	if a.LastBlockID > 0 && blockID != a.LastBlockID+1 {
+		return fmt.Errorf("block %d doesn't follow the last block %d of archive", blockID, a.LastBlockID)
+	}
+	if blockID < 1 {
+		return fmt.Errorf("wrong block id %d", blockID)
+	}
+	if len(data) > maxBlockSize {
+		return fmt.Errorf("block %d is too large", blockID)
+	}
+
+	last := len(a.Segments) - 1
+	if last < 0 || a.Segments[last].count() >= a.SegmentBlocks {
+		if err := a.closeFiles(); err != nil {
+			return err
+		}
+		a.Segments = append(a.Segments, Segment{
+			Name:         segmentName(blockID),
+			FirstBlockID: blockID,
+			LastBlockID:  blockID - 1,
+		})
+		last++
+	}
+	s := &a.Segments[last]
+	if a.data == nil {
+		if err := a.openFiles(s); err != nil {
+			return err
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	entry := make([]byte, entrySize)
+	binary.BigEndian.PutUint64(entry, uint64(s.Size))
+	binary.BigEndian.PutUint32(entry[8:], uint32(buf.Len()))
+	binary.BigEndian.PutUint32(entry[12:], crc32.ChecksumIEEE(data))
+	if _, err := a.data.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	if _, err := a.index.Write(entry); err != nil {
+		return err
+	}
+
+	s.Size += int64(buf.Len())
+	s.LastBlockID = blockID
+	if a.FirstBlockID == 0 {
+		a.FirstBlockID = blockID
+	}
+	a.LastBlockID = blockID
+	a.dirty[last] = true
+	return nil
+}
+
+// openFiles opens the files of the segment for appending, the files are truncated
+// to the sizes from the manifest in order to drop the data of the uncommitted blocks
+func (a *Archive) openFiles(s *Segment) (err error) {
+	if a.data, err = openAt(filepath.Join(a.dir, s.DataFile()), s.Size); err != nil {
+		return err
+	}
+	if a.index, err = openAt(filepath.Join(a.dir, s.IndexFile()), s.count()*entrySize); err != nil {
+		a.data.Close()
+		a.data = nil
+		return err
+	}
+	return nil
+}
+
+func openAt(fileName string, size int64) (*os.File, error) {
+	f, err := os.OpenFile(fileName, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err = f.Truncate(size); err == nil {
+		_, err = f.Seek(size, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func (a *Archive) closeFiles() error {
+	if a.data == nil {
+		return nil
+	}
+	err := a.data.Sync()
+	if errIndex := a.index.Sync(); err == nil {
+		err = errIndex
+	}
+	a.data.Close()
+	a.index.Close()
+	a.data, a.index = nil, nil
+	return err
+}
+
+// Commit writes the appended blocks on the disk and updates the manifest
+func (a *Archive) Commit() error {
+	if len(a.dirty) == 0 {
+		return nil
+	}
+	if a.data != nil {
+		if err := a.data.Sync(); err != nil {
+			return err
+		}
+		if err := a.index.Sync(); err != nil {
+			return err
+		}
+	}
+	for i := range a.dirty {
+		s := &a.Segments[i]
+		var err error
+		if s.Checksum, err = fileChecksum(filepath.Join(a.dir, s.DataFile())); err != nil {
+			return err
+		}
+		if s.IndexChecksum, err = fileChecksum(filepath.Join(a.dir, s.IndexFile())); err != nil {
+			return err
+		}
+	}
+	if err := writeManifest(a.dir, &a.Manifest); err != nil {
+		return err
+	}
+	a.dirty = make(map[int]bool)
+	return nil
+}
+
+// Close commits the appended blocks and closes the archive
+func (a *Archive) Close() error {
+	err := a.Commit()
+	if errClose := a.closeFiles(); err == nil {
+		err = errClose
+	}
+	return err
+}
+
+// writeManifest replaces the manifest atomically
+func writeManifest(dir string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	fileName := filepath.Join(dir, ManifestFile)
+	if err = writeFileSync(fileName+".tmp", data); err != nil {
+		return err
+	}
+	return os.Rename(fileName+".tmp", fileName)
+}
+
+func writeFileSync(fileName string, data []byte) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	return err
+}
+
+// Block returns the data of the block
+func (a *Archive) Block(blockID int64) ([]byte, error) {
+	i, err := a.segment(blockID)
+	if err != nil {
+		return nil, err
+	}
+	s := &a.Segments[i]
+
+	index, err := os.Open(filepath.Join(a.dir, s.IndexFile()))
+	if err != nil {
+		return nil, err
+	}
+	defer index.Close()
+	entry := make([]byte, entrySize)
+	if _, err = index.ReadAt(entry, (blockID-s.FirstBlockID)*entrySize); err != nil {
+		return nil, err
+	}
+
+	data, err := os.Open(filepath.Join(a.dir, s.DataFile()))
+	if err != nil {
+		return nil, err
+	}
+	defer data.Close()
+	return readBlock(data, entry, s.Size)
+}
+
+// ForEach calls fn for the blocks starting with fromBlockID in the order of id
+func (a *Archive) ForEach(fromBlockID int64, fn func(blockID int64, data []byte) error) error {
+	if fromBlockID < a.FirstBlockID {
+		fromBlockID = a.FirstBlockID
+	}
+	if fromBlockID > a.LastBlockID {
+		return nil
+	}
+	first, err := a.segment(fromBlockID)
+	if err != nil {
+		return err
+	}
+	for _, s := range a.Segments[first:] {
+		if err = forEachInSegment(a.dir, &s, fromBlockID, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func forEachInSegment(dir string, s *Segment, fromBlockID int64, fn func(int64, []byte) error) error {
+	index, err := ioutil.ReadFile(filepath.Join(dir, s.IndexFile()))
+	if err != nil {
+		return err
+	}
+	if int64(len(index)) < s.count()*entrySize {
+		return fmt.Errorf("index of segment %s is truncated", s.Name)
+	}
+	data, err := os.Open(filepath.Join(dir, s.DataFile()))
+	if err != nil {
+		return err
+	}
+	defer data.Close()
+
+	blockID := s.FirstBlockID
+	if fromBlockID > blockID {
+		blockID = fromBlockID
+	}
+	for ; blockID <= s.LastBlockID; blockID++ {
+		pos := (blockID - s.FirstBlockID) * entrySize
+		block, err := readBlock(data, index[pos:pos+entrySize], s.Size)
+		if err != nil {
+			return err
+		}
+		if err = fn(blockID, block); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readBlock reads the block which is described by the index entry
+func readBlock(r io.ReaderAt, entry []byte, limit int64) ([]byte, error) {
+	offset := int64(binary.BigEndian.Uint64(entry))
+	size := int64(binary.BigEndian.Uint32(entry[8:]))
+	if offset < 0 || offset+size > limit {
+		return nil, fmt.Errorf("wrong offset %d of block in archive", offset)
+	}
+	zr, err := gzip.NewReader(io.NewSectionReader(r, offset, size))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(zr, maxBlockSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxBlockSize {
+		return nil, errors.New("block in archive is too large")
+	}
+	if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(entry[12:]) {
+		return nil, ErrChecksum
+	}
+	return data, nil
+}
+
+// Verify checks the files of the segments against the checksums of the manifest
+func (a *Archive) Verify() error {
+	for _, s := range a.Segments {
+		if err := verifySegment(a.dir, &s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func verifySegment(dir string, s *Segment) error {
+	if err := verifyFile(filepath.Join(dir, s.DataFile()), s.Size, s.Checksum); err != nil {
+		return err
+	}
+	return verifyFile(filepath.Join(dir, s.IndexFile()), s.count()*entrySize, s.IndexChecksum)
+}
+
+func verifyFile(fileName string, size int64, checksum string) error {
+	fi, err := os.Stat(fileName)
+	if err != nil {
+		return err
+	}
+	if fi.Size() != size {
+		return fmt.Errorf("%s: size %d doesn't match size %d in manifest", fileName, fi.Size(), size)
+	}
+	sum, err := fileChecksum(fileName)
+	if err != nil {
+		return err
+	}
+	if sum != checksum {
+		return fmt.Errorf("%s: %v", fileName, ErrChecksum)
+	}
+	return nil
+}
+
+func fileChecksum(fileName string) (string, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
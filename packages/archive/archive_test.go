@@ -0,0 +1,199 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testBlock(id int64) []byte {
+	return []byte(fmt.Sprintf("block %d %s", id, bytes.Repeat([]byte{byte(id)}, int(id))))
+}
+
+func appendBlocks(t *testing.T, a *Archive, from, to int64) {
+	for id := from; id <= to; id++ {
+		if err := a.Append(id, testBlock(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a, err := Open(dir, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendBlocks(t, a, 1, 6)
+	if err = a.Append(8, testBlock(8)); err == nil {
+		t.Error("gap in blocks must be an error")
+	}
+	if err = a.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the uncommitted blocks are discarded after reopening
+	if a, err = Open(dir, 0); err != nil {
+		t.Fatal(err)
+	}
+	appendBlocks(t, a, 7, 8)
+	a.closeFiles()
+	if a, err = Open(dir, 0); err != nil {
+		t.Fatal(err)
+	}
+	if a.LastBlockID != 6 || a.SegmentBlocks != 4 {
+		t.Fatalf("wrong manifest %+v", a.Manifest)
+	}
+
+	// incremental append
+	appendBlocks(t, a, 7, 10)
+	if err = a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if a, err = Open(dir, 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Segments) != 3 || a.Segments[2].Name != "0000000009" || a.LastBlockID != 10 {
+		t.Fatalf("wrong segments %+v", a.Segments)
+	}
+	if err = a.Verify(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []int64{10, 1, 5, 4} {
+		data, err := a.Block(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, testBlock(id)) {
+			t.Errorf("wrong data of block %d", id)
+		}
+	}
+	if _, err = a.Block(11); err != ErrNotFound {
+		t.Errorf("wrong error %v", err)
+	}
+
+	next := int64(3)
+	err = a.ForEach(3, func(id int64, data []byte) error {
+		if id != next || !bytes.Equal(data, testBlock(id)) {
+			return fmt.Errorf("wrong block %d", id)
+		}
+		next++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next != 11 {
+		t.Errorf("blocks after %d are skipped", next)
+	}
+
+	// download to the other directory
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer srv.Close()
+	copyDir := filepath.Join(dir, "copy")
+	if _, err = Download(context.Background(), srv.URL, copyDir); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Open(copyDir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Verify(); err != nil {
+		t.Fatal(err)
+	}
+
+	// corrupted segment
+	fileName := filepath.Join(copyDir, c.Segments[1].DataFile())
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 0xff
+	if err = ioutil.WriteFile(fileName, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Verify(); err == nil {
+		t.Error("corrupted segment isn't found")
+	}
+	if _, err = c.Block(8); err == nil {
+		t.Error("corrupted block is read")
+	}
+	if _, err = Download(context.Background(), srv.URL, copyDir); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBlockSize(t *testing.T) {
+	pack := func(data []byte) (*bytes.Reader, []byte) {
+		buf := &bytes.Buffer{}
+		zw := gzip.NewWriter(buf)
+		zw.Write(data)
+		zw.Close()
+		entry := make([]byte, entrySize)
+		binary.BigEndian.PutUint32(entry[8:], uint32(buf.Len()))
+		binary.BigEndian.PutUint32(entry[12:], crc32.ChecksumIEEE(data))
+		return bytes.NewReader(buf.Bytes()), entry
+	}
+	r, entry := pack(make([]byte, maxBlockSize))
+	if data, err := readBlock(r, entry, r.Size()); err != nil || len(data) != maxBlockSize {
+		t.Errorf("max block isn't read: %v", err)
+	}
+	r, entry = pack(make([]byte, maxBlockSize+1))
+	if _, err := readBlock(r, entry, r.Size()); err == nil {
+		t.Error("too large block must be an error")
+	}
+
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a, err := Open(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	if err = a.Append(1, make([]byte, maxBlockSize+1)); err == nil {
+		t.Error("too large block must not be appended")
+	}
+}
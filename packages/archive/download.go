@@ -0,0 +1,119 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/context/ctxhttp"
+)
+
+// maxManifestSize is the max size of the downloaded manifest
+const maxManifestSize = 16 << 20
+
+// Download copies the archive from the url to the directory. The files which are already
+// in the directory and match the checksums aren't downloaded again, so the download can be
+// resumed and the local copy of the archive can be updated incrementally.
+// The manifest is written after all segments have been downloaded and verified.
+func Download(ctx context.Context, url, dir string) (*Manifest, error) {
+	url = strings.TrimSuffix(url, "/")
+	resp, err := get(ctx, url+"/"+ManifestFile)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxManifestSize))
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	m, err := parseManifest(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	for _, s := range m.Segments {
+		if err = downloadFile(ctx, url, dir, s.DataFile(), s.Size, s.Checksum); err != nil {
+			return nil, err
+		}
+		if err = downloadFile(ctx, url, dir, s.IndexFile(), s.count()*entrySize, s.IndexChecksum); err != nil {
+			return nil, err
+		}
+	}
+	if err = writeManifest(dir, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func get(ctx context.Context, url string) (*http.Response, error) {
+	resp, err := ctxhttp.Get(ctx, &http.Client{}, url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return resp, nil
+}
+
+func downloadFile(ctx context.Context, url, dir, name string, size int64, checksum string) error {
+	fileName := filepath.Join(dir, name)
+	if verifyFile(fileName, size, checksum) == nil {
+		return nil
+	}
+
+	resp, err := get(ctx, url+"/"+name)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	tmpName := fileName + ".download"
+	f, err := os.Create(tmpName)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, io.LimitReader(resp.Body, size+1))
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = verifyFile(tmpName, size, checksum)
+	}
+	if err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return os.Rename(tmpName, fileName)
+}
@@ -69,6 +69,41 @@ type AutoupdateConfig struct {
 	PublicKeyPath string
 }
 
+// RateLimitConfig is the settings of a token bucket, zero Rate means unlimited
+type RateLimitConfig struct {
+	Rate  float64 // tokens per second
+	Burst int     // max tokens, it's at least one if Rate is set
+}
+
+// RouteLimitConfig is the limits of one class of api routes
+type RouteLimitConfig struct {
+	PerKey RateLimitConfig
+	PerIP  RateLimitConfig
+}
+
+// TCPLimitsConfig is the limits of incoming connections of the tcp server, zero means unlimited
+type TCPLimitsConfig struct {
+	MaxConnections int // max number of connections
+	MaxPerPeer     int // max number of connections from one address
+	IdleTimeout    int // seconds to wait for the request
+	ReadTimeout    int // seconds to wait for the next data of the request
+	WriteTimeout   int // seconds to wait for the sending of the response
+}
+
+// SnapshotConfig is the settings of the snapshots of the state for the fast sync of new nodes
+type SnapshotConfig struct {
+	Interval int64  // number of blocks between snapshots, zero disables the creating of snapshots
+	Keep     int    // number of the latest snapshots which are kept
+	URL      string // snapshot which is loaded by the new node instead of playing all blocks
+}
+
+// APIRateLimitConfig is the limits for read, render and submit api routes
+type APIRateLimitConfig struct {
+	Read   RouteLimitConfig
+	Render RouteLimitConfig
+	Submit RouteLimitConfig
+}
+
 // SavedConfig parameters saved in "config.toml"
 type SavedConfig struct {
 	LogLevel    string
@@ -86,19 +121,30 @@ type SavedConfig struct {
 	FirstLoadBlockchainURL string
 	FirstLoadBlockchain    string
 
-	MaxPageGenerationTime int64 // in milliseconds
+	MaxPageGenerationTime int64  // in milliseconds
+	MaxAtBlockDepth       int64  // max number of blocks for reading of tables at the past block
+	RenderCacheSize       int    // max number of rendered pages in the cache, 0 disables the cache
+	PagePath              string // front-end path of pages which prefixes the links of pages rendered to HTML
 
 	TCPServer HostPort
 	HTTP      HostPort
 	DB        DBConfig
 	StatsD    StatsDConfig
 
+	LegacyPlaintext bool // allows plaintext connections with nodes which don't support encryption
+	TCPLimits       TCPLimitsConfig
+	PublicAddress   string // tcp address which is announced to other nodes, empty disables the announcement
+	Snapshot        SnapshotConfig
+	PruneBlocks     int64 // number of the latest blocks whose bodies and rollback data are kept, zero disables the pruning
+
 	WorkDir    string // application work dir (cwd by default)
 	PrivateDir string // place for private keys files: NodePrivateKey, PrivateKey
 
 	Centrifugo CentrifugoConfig
 
 	Autoupdate AutoupdateConfig
+
+	APIRateLimit APIRateLimitConfig
 }
 
 // Installed web UI installation mode
@@ -106,10 +152,16 @@ var Installed bool
 
 // Config global parameters
 var Config = SavedConfig{
-	InstallType:  "PRIVATE_NET",
-	NodeStateID:  "*",
-	StartDaemons: "",
-	StatsD:       StatsDConfig{Name: "gachain", HostPort: HostPort{Host: "127.0.0.1", Port: 8125}},
+	InstallType:     "PRIVATE_NET",
+	NodeStateID:     "*",
+	StartDaemons:    "",
+	MaxAtBlockDepth: 1000,
+	RenderCacheSize: 1000,
+	PagePath:        "/page/",
+	LegacyPlaintext: true,
+	TCPLimits:       TCPLimitsConfig{MaxConnections: 100, MaxPerPeer: 10, IdleTimeout: 10, ReadTimeout: 20, WriteTimeout: 20},
+	StatsD:          StatsDConfig{Name: "gachain", HostPort: HostPort{Host: "127.0.0.1", Port: 8125}},
+	Snapshot:        SnapshotConfig{Keep: 2},
 }
 
 // GetConfigPath returns path from command line arg or default
@@ -125,6 +177,21 @@ func GetPidFile() string {
 	return filepath.Join(Config.WorkDir, consts.PidFilename)
 }
 
+// GetPeersFile returns path to the file with the reputation of remote nodes
+func GetPeersFile() string {
+	return filepath.Join(Config.WorkDir, consts.PeersFilename)
+}
+
+// GetNodeTableFile returns path to the file with nodes learnt from other nodes
+func GetNodeTableFile() string {
+	return filepath.Join(Config.WorkDir, consts.NodeTableFilename)
+}
+
+// GetSnapshotsDir returns path to the directory with the snapshots of the state
+func GetSnapshotsDir() string {
+	return filepath.Join(Config.WorkDir, consts.SnapshotsDirname)
+}
+
 // LoadConfig from configFile
 // the function has side effect updating global var Config
 func LoadConfig() error {
@@ -143,6 +143,12 @@ var (
 	// RollbackToBlockID is the target block for rollback
 	RollbackToBlockID = flag.Int64("rollbackToBlockId", 0, "Rollback to block_id")
 
+	// ExportArchive is the directory of the archive where the blocks of the blockchain are exported
+	ExportArchive = flag.String("exportArchive", "", "Export blocks to the archive in the directory and exit")
+
+	// ImportArchive is the directory of the archive from which the blocks are imported
+	ImportArchive = flag.String("importArchive", "", "Import blocks from the archive in the directory and exit")
+
 	// TLS is a directory for .well-known and keys. It is required for https
 	TLS = flag.String("tls", "", "Enable https. Ddirectory for .well-known and keys")
 
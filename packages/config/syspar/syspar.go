@@ -154,6 +154,18 @@ func GetNode(wallet int64) *FullNode {
 	return nil
 }
 
+// GetNodeByHost returns KeyID and the full node with the host, the node is nil if the host is not listed in full_nodes
+func GetNodeByHost(host string) (int64, *FullNode) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	for keyID, node := range nodes {
+		if node.Host == host {
+			return keyID, node
+		}
+	}
+	return 0, nil
+}
+
 // GetNodePositionByKeyID is returning node position by key id
 func GetNodePositionByKeyID(keyID int64) (int64, error) {
 	mutex.RLock()
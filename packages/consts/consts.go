@@ -70,6 +70,40 @@ const DATA_TYPE_MAX_BLOCK_ID = 10
 // DATA_TYPE_BLOCK_BODY is body block datatype
 const DATA_TYPE_BLOCK_BODY = 7
 
+// DATA_TYPE_BLOCK_RANGE is the datatype of the contiguous range of block bodies
+const DATA_TYPE_BLOCK_RANGE = 11
+
+// MAX_BLOCK_RANGE is the max number of blocks which are sent for one range request
+const MAX_BLOCK_RANGE = 100
+
+// DATA_TYPE_HANDSHAKE is the datatype of the first message of the connection between nodes
+const DATA_TYPE_HANDSHAKE = 12
+
+// DATA_TYPE_PEERS is the datatype of the exchange of known nodes
+const DATA_TYPE_PEERS = 13
+
+// DATA_TYPE_COMPACT_BLOCK is the datatype of the block which is sent as the header and the hashes of transactions
+const DATA_TYPE_COMPACT_BLOCK = 14
+
+// DATA_TYPE_SNAPSHOT_HEADER is the datatype of the signed header of the snapshot of the state at the block
+const DATA_TYPE_SNAPSHOT_HEADER = 15
+
+// MAX_PEERS_EXCHANGE is the max number of nodes which are sent for the peers request
+const MAX_PEERS_EXCHANGE = 100
+
+// MAX_SYNC_PEERS is the max number of known nodes which are queried for the last block in one round of syncing
+const MAX_SYNC_PEERS = 10
+
+// MAX_VERIFY_PEERS is the max number of received addresses which are verified in one round of syncing
+const MAX_VERIFY_PEERS = 5
+
+// PROTOCOL_VERSION is the version of the node-to-node protocol
+const PROTOCOL_VERSION = 2
+
+// MIN_PROTOCOL_VERSION is the min version of the protocol of remote nodes,
+// 0 allows nodes which don't send the handshake
+const MIN_PROTOCOL_VERSION = 0
+
 // UPD_AND_VER_URL is root url
 const UPD_AND_VER_URL = "https://gachain.org"
 
@@ -122,6 +156,18 @@ const KeyIDFilename = "KeyID"
 // RollbackResultFilename rollback result file
 const RollbackResultFilename = "rollback_result"
 
+// PeersFilename is the file with the reputation of remote nodes
+const PeersFilename = "peers.json"
+
+// NodeTableFilename is the file with nodes which have been learnt through the peer exchange
+const NodeTableFilename = "nodes.json"
+
+// SnapshotsDirname is the directory with the snapshots of the state of the blockchain
+const SnapshotsDirname = "snapshots"
+
+// ArchiveDirname is the directory with the archive of the blockchain which is downloaded for the first load
+const ArchiveDirname = "archive"
+
 // WellKnownRoute TLS route
 const WellKnownRoute = "/.well-known/*filepath"
 
@@ -72,4 +72,6 @@ const (
 	MigrationError           = "MigrationError"
 	AutoupdateError          = "AutoupdateError"
 	SchedulerError           = "SchedulerError"
+	PeerBanned               = "PeerBanned"
+	SnapshotError            = "SnapshotError"
 )
@@ -0,0 +1,198 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package daemons
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/GACHAIN/go-gachain/packages/archive"
+	"github.com/GACHAIN/go-gachain/packages/conf"
+	"github.com/GACHAIN/go-gachain/packages/config/syspar"
+	"github.com/GACHAIN/go-gachain/packages/consts"
+	"github.com/GACHAIN/go-gachain/packages/model"
+	"github.com/GACHAIN/go-gachain/packages/parser"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// exportBatch is the number of blocks which are read from the database at once
+const exportBatch = 1000
+
+var errArchiveEnd = errors.New("end block of archive import")
+
+// ExportArchive appends the blocks of the blockchain to the archive in the directory.
+// The archive is created if the directory doesn't contain it, otherwise only the new blocks are appended.
+// Only the blocks which can't be rolled back are exported, so the archive isn't changed by forks.
+func ExportArchive(dir string) error {
+	logger := log.WithFields(log.Fields{"dir": dir})
+	a, err := archive.Open(dir, 0)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("opening archive")
+		return err
+	}
+	defer a.Close()
+
+	if a.LastBlockID > 0 {
+		// the archive can't be continued if the blockchain has been changed by the rollback
+		data, err := a.Block(a.LastBlockID)
+		if err != nil {
+			logger.WithFields(log.Fields{"type": consts.IOError, "error": err, "block_id": a.LastBlockID}).Error("reading block from archive")
+			return err
+		}
+		block := &model.Block{}
+		found, err := block.Get(a.LastBlockID)
+		if err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "block_id": a.LastBlockID}).Error("getting block")
+			return err
+		}
+		if !found || !bytes.Equal(block.Data, data) {
+			return fmt.Errorf("block %d of archive differs from blockchain", a.LastBlockID)
+		}
+	}
+
+	lastBlockID, err := exportBoundary(logger)
+	if err != nil {
+		return err
+	}
+	for a.LastBlockID < lastBlockID {
+		toBlockID := a.LastBlockID + exportBatch
+		if toBlockID > lastBlockID {
+			toBlockID = lastBlockID
+		}
+		blocks, err := model.GetBlockchain(a.LastBlockID, toBlockID)
+		if err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "block_id": a.LastBlockID}).Error("getting blocks")
+			return err
+		}
+		if len(blocks) == 0 {
+			break
+		}
+		for _, block := range blocks {
+			if block.IsPruned() {
+				return fmt.Errorf("block %d is pruned", block.ID)
+			}
+			if err = a.Append(block.ID, block.Data); err != nil {
+				logger.WithFields(log.Fields{"type": consts.IOError, "error": err, "block_id": block.ID}).Error("appending block to archive")
+				return err
+			}
+		}
+		if err = a.Commit(); err != nil {
+			logger.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("committing archive")
+			return err
+		}
+	}
+	logger.WithFields(log.Fields{"block_id": a.LastBlockID, "segments": len(a.Segments)}).Info("blockchain has been exported")
+	return nil
+}
+
+// exportBoundary returns the last block which can be exported, the latest rb_blocks_1 blocks
+// and the blocks after the confirmed block can be rolled back
+func exportBoundary(logger *log.Entry) (int64, error) {
+	infoBlock := &model.InfoBlock{}
+	if _, err := infoBlock.Get(); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting info block")
+		return 0, err
+	}
+	lastBlockID := infoBlock.BlockID - syspar.GetRbBlocks1()
+
+	confirmation := &model.Confirmation{}
+	found, err := confirmation.GetGoodBlock(consts.MIN_CONFIRMED_NODES)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting confirmed block")
+		return 0, err
+	}
+	if found && confirmation.BlockID < lastBlockID {
+		lastBlockID = confirmation.BlockID
+	}
+	return lastBlockID, nil
+}
+
+// ImportArchive plays the blocks of the archive in the directory which follow the last block
+// of the blockchain, the blocks are checked as the blocks which are got from the other nodes
+func ImportArchive(ctx context.Context, dir string) error {
+	logger := log.WithFields(log.Fields{"dir": dir})
+	a, err := archive.Open(dir, 0)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("opening archive")
+		return err
+	}
+	if err = a.Verify(); err != nil {
+		logger.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("verifying archive")
+		return err
+	}
+
+	infoBlock := &model.InfoBlock{}
+	if _, err = infoBlock.Get(); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting info block")
+		return err
+	}
+	if a.LastBlockID <= infoBlock.BlockID {
+		logger.WithFields(log.Fields{"block_id": a.LastBlockID}).Info("archive doesn't contain new blocks")
+		return nil
+	}
+	if a.FirstBlockID > infoBlock.BlockID+1 {
+		return fmt.Errorf("archive begins with block %d after the last block %d", a.FirstBlockID, infoBlock.BlockID)
+	}
+
+	err = a.ForEach(infoBlock.BlockID+1, func(blockID int64, data []byte) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if *conf.EndBlockID > 0 && blockID > *conf.EndBlockID {
+			return errArchiveEnd
+		}
+		if err := parser.InsertBlockWOForks(data); err != nil {
+			logger.WithFields(log.Fields{"type": consts.ParserError, "error": err, "block_id": blockID}).Error("inserting block from archive")
+			return err
+		}
+		return nil
+	})
+	if err != nil && err != errArchiveEnd {
+		return err
+	}
+	logger.Info("blockchain has been imported")
+	return nil
+}
+
+// loadArchive downloads the archive of the blockchain and plays its blocks,
+// the blocks after the archive are got by blocksCollection as usual
+func loadArchive(ctx context.Context, d *daemon) error {
+	dir := filepath.Join(conf.Config.WorkDir, consts.ArchiveDirname)
+	if _, err := archive.Download(ctx, conf.Config.FirstLoadBlockchainURL, dir); err != nil {
+		return err
+	}
+
+	DBLock()
+	defer DBUnlock()
+
+	if err := ImportArchive(ctx, dir); err != nil {
+		return err
+	}
+	// the downloaded archive is kept until the blocks are played, so the download can be resumed
+	return os.RemoveAll(dir)
+}
@@ -38,6 +38,7 @@ import (
 	"github.com/GACHAIN/go-gachain/packages/converter"
 	"github.com/GACHAIN/go-gachain/packages/model"
 	"github.com/GACHAIN/go-gachain/packages/parser"
+	"github.com/GACHAIN/go-gachain/packages/peers"
 	"github.com/GACHAIN/go-gachain/packages/utils"
 
 	log "github.com/sirupsen/logrus"
@@ -72,6 +73,18 @@ func initialLoad(ctx context.Context, d *daemon) error {
 		if err := firstLoad(ctx, d); err != nil {
 			return err
 		}
+
+		if len(conf.Config.Snapshot.URL) > 0 {
+			if err := loadSnapshot(ctx, d); err != nil {
+				d.logger.WithFields(log.Fields{"type": consts.SnapshotError, "error": err, "url": conf.Config.Snapshot.URL}).Warning("blocks are played without snapshot")
+			}
+		}
+
+		if len(conf.Config.FirstLoadBlockchainURL) > 0 {
+			if err := loadArchive(ctx, d); err != nil {
+				d.logger.WithFields(log.Fields{"type": consts.IOError, "error": err, "url": conf.Config.FirstLoadBlockchainURL}).Warning("blocks are got from nodes without archive")
+			}
+		}
 	}
 
 	return nil
@@ -79,14 +92,6 @@ func initialLoad(ctx context.Context, d *daemon) error {
 
 func blocksCollection(ctx context.Context, d *daemon) error {
 
-	hosts := syspar.GetRemoteHosts()
-
-	// get a host with the biggest block id
-	host, maxBlockID, err := chooseBestHost(ctx, hosts, d.logger)
-	if err != nil {
-		return err
-	}
-
 	// NOTE: should be generalized in separate method
 	infoBlock := &model.InfoBlock{}
 	found, err := infoBlock.Get()
@@ -99,6 +104,17 @@ func blocksCollection(ctx context.Context, d *daemon) error {
 		return errors.New("Info block not found")
 	}
 
+	// nodes learnt through the peer exchange are queried too, so syncing doesn't burden validators,
+	// banned hosts are skipped until the ban is over
+	hosts := peers.Filter(syncHosts())
+	exchangePeers(hosts, infoBlock.BlockID, d.logger)
+
+	// get a host with the biggest block id
+	host, maxBlockID, err := chooseBestHost(ctx, hosts, d.logger)
+	if err != nil {
+		return err
+	}
+
 	if infoBlock.BlockID >= maxBlockID {
 		log.WithFields(log.Fields{"blockID": infoBlock.BlockID, "maxBlockID": maxBlockID}).Debug("Max block is already in the host")
 		return nil
@@ -110,7 +126,23 @@ func blocksCollection(ctx context.Context, d *daemon) error {
 	return UpdateChain(ctx, d, host, maxBlockID)
 }
 
-// best host is a host with the biggest last block ID
+// syncHosts returns full nodes, random nodes from the table of known nodes and received addresses
+// which are verified by the request of the last block, so the number of connections is limited
+func syncHosts() []string {
+	hosts := make([]string, 0)
+	added := make(map[string]bool)
+	known := append(peers.Sample(consts.MAX_SYNC_PEERS), peers.Candidates(consts.MAX_VERIFY_PEERS)...)
+	for _, h := range append(syspar.GetRemoteHosts(), known...) {
+		if h = getHostPort(h); !added[h] && h != conf.Config.PublicAddress {
+			added[h] = true
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts
+}
+
+// best host is a host with the biggest last block ID, validators are chosen
+// only if other nodes don't have the same block
 func chooseBestHost(ctx context.Context, hosts []string, logger *log.Entry) (string, int64, error) {
 	type blockAndHost struct {
 		host    string
@@ -119,6 +151,11 @@ func chooseBestHost(ctx context.Context, hosts []string, logger *log.Entry) (str
 	}
 	c := make(chan blockAndHost, len(hosts))
 
+	validators := make(map[string]bool)
+	for _, h := range syspar.GetRemoteHosts() {
+		validators[getHostPort(h)] = true
+	}
+
 	var wg sync.WaitGroup
 	for _, h := range hosts {
 		if ctx.Err() != nil {
@@ -145,11 +182,16 @@ func chooseBestHost(ctx context.Context, hosts []string, logger *log.Entry) (str
 	for i := 0; i < len(hosts); i++ {
 		bl := <-c
 
-		if bl.blockID > maxBlockID {
+		// zero block is sent by the node which can't send all blocks, so it isn't kept in the table
+		if bl.err == nil && bl.blockID > 0 && !validators[bl.host] {
+			peers.Seen(bl.host, bl.blockID)
+		}
+		if bl.blockID > maxBlockID || (bl.blockID == maxBlockID && validators[bestHost] && !validators[bl.host]) {
 			maxBlockID = bl.blockID
 			bestHost = bl.host
 		}
 	}
+	peers.Save()
 
 	return bestHost, maxBlockID, nil
 }
@@ -173,6 +215,7 @@ func getHostBlockID(host string, logger *log.Entry) (int64, error) {
 	blockIDBin := make([]byte, 4)
 	_, err = conn.Read(blockIDBin)
 	if err != nil {
+		peers.PenalizeNet(host, err)
 		logger.WithFields(log.Fields{"error": err, "type": consts.ConnectionError, "host": host}).Error("reading max block id from host")
 		return 0, err
 	}
@@ -190,62 +233,81 @@ func UpdateChain(ctx context.Context, d *daemon, host string, maxBlockID int64)
 		return err
 	}
 
-	for blockID := curBlock.BlockID + 1; blockID <= maxBlockID; blockID++ {
-		if ctx.Err() != nil {
-			d.logger.WithFields(log.Fields{"type": consts.ContextError, "error": ctx.Err()}).Error("context error")
-			return ctx.Err()
-		}
-
-		blockBin, err := utils.GetBlockBody(host, blockID, consts.DATA_TYPE_BLOCK_BODY)
-		if err != nil {
-			d.logger.WithFields(log.Fields{"error": err, "type": consts.BlockError}).Error("getting block body")
-			return err
-		}
-
-		block, err := parser.ProcessBlockWherePrevFromBlockchainTable(blockBin)
-		if err != nil {
-			// we got bad block and should ban this host
-			banNode(host, err)
-			d.logger.WithFields(log.Fields{"error": err, "type": consts.BlockError}).Error("processing block")
-			return err
-		}
-
-		// hash compare could be failed in the case of fork
-		hashMatched, thisErrIsOk := block.CheckHash()
-		if thisErrIsOk != nil {
-			d.logger.WithFields(log.Fields{"error": err, "type": consts.BlockError}).Error("checking block hash")
-		}
-
-		if !hashMatched {
-			// it should be fork, replace our previous blocks to ones from the host
-			err := parser.GetBlocks(blockID-1, host)
-			if err != nil {
-				d.logger.WithFields(log.Fields{"error": err, "type": consts.ParserError}).Error("processing block")
-				banNode(host, err)
-				return err
+	// the batches are downloaded in parallel but come in the order of block ids
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for batch := range downloadBlocks(ctx, host, curBlock.BlockID+1, maxBlockID, d.logger) {
+		for i, blockBin := range batch.blocks {
+			if ctx.Err() != nil {
+				d.logger.WithFields(log.Fields{"type": consts.ContextError, "error": ctx.Err()}).Error("context error")
+				return ctx.Err()
 			}
-		} else {
-			/* TODO should we uncomment this ?????????????
-			_, err := model.MarkTransactionsUnverified()
-			if err != nil {
+			if err := updateBlock(d, batch.host, batch.from+int64(i), blockBin); err != nil {
 				return err
 			}
-			*/
 		}
+		if batch.err != nil {
+			d.logger.WithFields(log.Fields{"error": batch.err, "type": consts.BlockError}).Error("getting block bodies")
+			return batch.err
+		}
+	}
+	if ctx.Err() != nil {
+		d.logger.WithFields(log.Fields{"type": consts.ContextError, "error": ctx.Err()}).Error("context error")
+		return ctx.Err()
+	}
+	return nil
+}
 
-		block.PrevHeader, err = parser.GetBlockDataFromBlockChain(block.Header.BlockID - 1)
+// updateBlock checks and plays the block which has been received from the host
+func updateBlock(d *daemon, host string, blockID int64, blockBin []byte) error {
+	block, err := parser.ProcessBlockWherePrevFromBlockchainTable(blockBin)
+	if err != nil {
+		// we got bad block and should ban this host
+		banNode(host, peers.InvalidBlock, err)
+		d.logger.WithFields(log.Fields{"error": err, "type": consts.BlockError}).Error("processing block")
+		return err
+	}
+	if block.Header.BlockID != blockID {
+		err = fmt.Errorf("block %d has been received instead of %d", block.Header.BlockID, blockID)
+		banNode(host, peers.ProtocolError, err)
+		d.logger.WithFields(log.Fields{"error": err, "type": consts.BlockError}).Error("processing block")
+		return err
+	}
+
+	// hash compare could be failed in the case of fork
+	hashMatched, thisErrIsOk := block.CheckHash()
+	if thisErrIsOk != nil {
+		d.logger.WithFields(log.Fields{"error": err, "type": consts.BlockError}).Error("checking block hash")
+	}
+
+	if !hashMatched {
+		// it should be fork, replace our previous blocks to ones from the host,
+		// GetBlocks penalizes the host itself if the received blocks are wrong
+		err := parser.GetBlocks(blockID-1, host)
 		if err != nil {
-			banNode(host, err)
-			return utils.ErrInfo(fmt.Errorf("can't get block %d", block.Header.BlockID-1))
-		}
-		if err = block.CheckBlock(); err != nil {
-			banNode(host, err)
+			d.logger.WithFields(log.Fields{"error": err, "type": consts.ParserError}).Error("processing block")
 			return err
 		}
-		if err = block.PlayBlockSafe(); err != nil {
-			banNode(host, err)
+	} else {
+		/* TODO should we uncomment this ?????????????
+		_, err := model.MarkTransactionsUnverified()
+		if err != nil {
 			return err
 		}
+		*/
+	}
+
+	block.PrevHeader, err = parser.GetBlockDataFromBlockChain(block.Header.BlockID - 1)
+	if err != nil {
+		return utils.ErrInfo(fmt.Errorf("can't get block %d", block.Header.BlockID-1))
+	}
+	if err = block.CheckBlock(); err != nil {
+		banNode(host, peers.InvalidBlock, err)
+		return err
+	}
+	if err = block.PlayBlockSafe(); err != nil {
+		banNode(host, peers.InvalidBlock, err)
+		return err
 	}
 	return nil
 }
@@ -305,8 +367,8 @@ func needLoad(logger *log.Entry) (bool, error) {
 	return false, nil
 }
 
-func banNode(host string, err error) {
-	// TODO
+func banNode(host string, offence peers.Offence, err error) {
+	peers.Penalize(host, offence, err)
 }
 
 func loadFromFile(ctx context.Context, fileName string, logger *log.Entry) error {
@@ -0,0 +1,152 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package daemons
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/GACHAIN/go-gachain/packages/config/syspar"
+	"github.com/GACHAIN/go-gachain/packages/consts"
+	"github.com/GACHAIN/go-gachain/packages/network"
+	"github.com/GACHAIN/go-gachain/packages/peers"
+	"github.com/GACHAIN/go-gachain/packages/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxDownloadHosts is the max number of hosts which blocks are downloaded from at the same time
+const maxDownloadHosts = 4
+
+// blocksBatch is the contiguous range of block bodies received from the host
+type blocksBatch struct {
+	host   string
+	from   int64
+	blocks [][]byte
+	err    error
+}
+
+// downloadHosts returns the host with the biggest block id and other hosts which are not banned
+func downloadHosts(host string) []string {
+	hosts := []string{host}
+	for _, h := range peers.Filter(syspar.GetRemoteHosts()) {
+		if h = getHostPort(h); h != host && len(hosts) < maxDownloadHosts {
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts
+}
+
+// downloadBlocks downloads blocks from first to last by batches of consts.MAX_BLOCK_RANGE blocks.
+// The batches are requested from several hosts in parallel and are sent to the channel in order.
+// The channel is closed after the error batch or when the context is done.
+func downloadBlocks(ctx context.Context, host string, first, last int64, logger *log.Entry) <-chan *blocksBatch {
+	hosts := downloadHosts(host)
+	out := make(chan *blocksBatch)
+	// pending limits the number of batches which are downloaded at the same time
+	pending := make(chan chan *blocksBatch, len(hosts))
+
+	go func() {
+		defer close(pending)
+		for i, from := 0, first; from <= last; i, from = i+1, from+consts.MAX_BLOCK_RANGE {
+			count := last - from + 1
+			if count > consts.MAX_BLOCK_RANGE {
+				count = consts.MAX_BLOCK_RANGE
+			}
+			result := make(chan *blocksBatch, 1)
+			select {
+			case pending <- result:
+			case <-ctx.Done():
+				return
+			}
+			go func(h string, from, count int64) {
+				result <- downloadBatch(h, host, from, count, logger)
+			}(hosts[i%len(hosts)], from, count)
+		}
+	}()
+
+	go func() {
+		defer close(out)
+		for result := range pending {
+			var batch *blocksBatch
+			select {
+			case batch = <-result:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case out <- batch:
+			case <-ctx.Done():
+				return
+			}
+			if batch.err != nil {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// downloadBatch gets the range of blocks from the host. If it fails, the blocks are
+// requested from the best host which surely has all of them.
+func downloadBatch(host, bestHost string, from, count int64, logger *log.Entry) *blocksBatch {
+	if host != bestHost {
+		blocks, err := utils.GetBlocksRange(host, from, count)
+		if err == nil && int64(len(blocks)) == count {
+			return &blocksBatch{host: host, from: from, blocks: blocks}
+		}
+		// the host can be behind us or can be an old node which closes the connection
+		// on unknown requests, so only the broken response is penalized
+		if err != nil && err != io.EOF && err != network.ErrUnsupported {
+			peers.PenalizeNet(host, err)
+		}
+		logger.WithFields(log.Fields{"host": host, "from": from, "count": len(blocks), "error": err}).Debug("downloading blocks from the best host")
+	}
+	blocks, err := utils.GetBlocksRange(bestHost, from, count)
+	if err != nil {
+		// the host doesn't support range requests, so blocks are requested one by one
+		logger.WithFields(log.Fields{"host": bestHost, "from": from, "error": err}).Debug("downloading blocks one by one")
+		blocks = make([][]byte, 0, count)
+		for blockID := from; blockID < from+count; blockID++ {
+			blockBin, err := utils.GetBlockBody(bestHost, blockID, consts.DATA_TYPE_BLOCK_BODY)
+			if err != nil {
+				peers.PenalizeNet(bestHost, err)
+				return &blocksBatch{host: bestHost, from: from, err: err}
+			}
+			blocks = append(blocks, blockBin)
+		}
+	}
+	if len(blocks) == 0 {
+		err = fmt.Errorf("host %s has not returned block %d", bestHost, from)
+		banNode(bestHost, peers.ProtocolError, err)
+		return &blocksBatch{host: bestHost, from: from, err: err}
+	}
+	// if the best host has returned fewer blocks, the received ones are played
+	// and the download is stopped because the next batches don't follow them
+	if int64(len(blocks)) < count {
+		err = fmt.Errorf("host %s has returned %d blocks instead of %d", bestHost, len(blocks), count)
+		return &blocksBatch{host: bestHost, from: from, blocks: blocks, err: err}
+	}
+	return &blocksBatch{host: bestHost, from: from, blocks: blocks}
+}
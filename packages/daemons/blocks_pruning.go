@@ -0,0 +1,135 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package daemons
+
+import (
+	"context"
+	"time"
+
+	"github.com/GACHAIN/go-gachain/packages/conf"
+	"github.com/GACHAIN/go-gachain/packages/config/syspar"
+	"github.com/GACHAIN/go-gachain/packages/consts"
+	"github.com/GACHAIN/go-gachain/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	pruningInterval = time.Minute
+	// pruningBatch is the max number of blocks which are pruned at once
+	pruningBatch = 10000
+)
+
+// BlocksPruning removes the bodies and the rollback data of old blocks on the node which doesn't
+// validate blocks. The headers of blocks are kept, the latest blocks are kept for rollbacks of forks
+// and for reading of tables at the past blocks, the blocks which aren't confirmed are never pruned.
+func BlocksPruning(ctx context.Context, d *daemon) error {
+	d.sleepTime = pruningInterval
+	if conf.Config.PruneBlocks <= 0 {
+		return nil
+	}
+	if syspar.GetNode(conf.Config.KeyID) != nil {
+		d.logger.WithFields(log.Fields{"type": consts.ConfigError, "key_id": conf.Config.KeyID}).Warning("full node doesn't prune blocks")
+		return nil
+	}
+
+	toBlockID, err := pruningBoundary(d.logger)
+	if err != nil {
+		return err
+	}
+	prunedBlockID, err := model.GetPrunedBlockID()
+	if err != nil {
+		d.logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting pruned block")
+		return err
+	}
+	if toBlockID > prunedBlockID+pruningBatch {
+		toBlockID = prunedBlockID + pruningBatch
+	}
+	if toBlockID <= prunedBlockID {
+		return nil
+	}
+
+	tr, err := model.StartTransaction()
+	if err != nil {
+		return err
+	}
+	blocks, err := model.PruneBlocks(tr, prunedBlockID, toBlockID)
+	if err != nil {
+		tr.Rollback()
+		d.logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "block_id": toBlockID}).Error("pruning blocks")
+		return err
+	}
+	rollbacks, err := model.DeleteRollbacksTill(tr, toBlockID)
+	if err != nil {
+		tr.Rollback()
+		d.logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "block_id": toBlockID}).Error("deleting rollbacks")
+		return err
+	}
+	if err = tr.Commit(); err != nil {
+		d.logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("committing pruning")
+		return err
+	}
+	d.logger.WithFields(log.Fields{"block_id": toBlockID, "blocks": blocks, "rollbacks": rollbacks}).Info("blocks have been pruned")
+	return nil
+}
+
+// pruningBoundary returns the last block which can be pruned
+func pruningBoundary(logger *log.Entry) (int64, error) {
+	infoBlock := &model.InfoBlock{}
+	if _, err := infoBlock.Get(); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting info block")
+		return 0, err
+	}
+	confirmation := &model.Confirmation{}
+	found, err := confirmation.GetGoodBlock(consts.MIN_CONFIRMED_NODES)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting confirmed block")
+		return 0, err
+	}
+	if !found {
+		logger.Debug("there aren't confirmed blocks for pruning")
+		return 0, nil
+	}
+	return lastPrunedBlock(infoBlock.BlockID, confirmation.BlockID, conf.Config.PruneBlocks,
+		syspar.GetRbBlocks1(), conf.Config.MaxAtBlockDepth), nil
+}
+
+// lastPrunedBlock returns the last block which can be pruned, the latest blocks are kept
+// for the pruning, for rollbacks and for reading of tables at the past blocks,
+// the blocks after the confirmed block are never pruned
+func lastPrunedBlock(lastBlockID, confirmedBlockID int64, keep ...int64) int64 {
+	var maxKeep int64
+	for _, k := range keep {
+		if k > maxKeep {
+			maxKeep = k
+		}
+	}
+	toBlockID := lastBlockID - maxKeep
+	if confirmedBlockID < toBlockID {
+		toBlockID = confirmedBlockID
+	}
+	if toBlockID < 0 {
+		return 0
+	}
+	return toBlockID
+}
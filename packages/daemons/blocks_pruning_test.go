@@ -0,0 +1,44 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package daemons
+
+import "testing"
+
+func TestLastPrunedBlock(t *testing.T) {
+	for i, item := range []struct {
+		last, confirmed int64
+		keep            []int64
+		want            int64
+	}{
+		{1000, 1000, []int64{100, 60, 0}, 900},
+		{1000, 1000, []int64{100, 60, 300}, 700},
+		{1000, 1000, []int64{10, 60, 0}, 940},
+		{1000, 850, []int64{100, 60, 0}, 850},
+		{1000, 0, []int64{100, 60, 0}, 0},
+		{50, 50, []int64{100, 60, 0}, 0},
+	} {
+		if got := lastPrunedBlock(item.last, item.confirmed, item.keep...); got != item.want {
+			t.Errorf(`%d: wrong pruned block %d instead of %d`, i, got, item.want)
+		}
+	}
+}
@@ -57,6 +57,7 @@ var daemonsList = map[string]func(context.Context, *daemon) error{
 	"Confirmations":     Confirmations,
 	"Notificator":       Notificate,
 	"Scheduler":         Scheduler,
+	"BlocksPruning":     BlocksPruning,
 }
 
 var serverList = []string{
@@ -68,6 +69,7 @@ var serverList = []string{
 	"Confirmations",
 	"Notificator",
 	"Scheduler",
+	"BlocksPruning",
 }
 
 var rollbackList = []string{
@@ -0,0 +1,97 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package daemons
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/GACHAIN/go-gachain/packages/consts"
+	"github.com/GACHAIN/go-gachain/packages/network"
+	"github.com/GACHAIN/go-gachain/packages/parser"
+	"github.com/GACHAIN/go-gachain/packages/peers"
+	"github.com/GACHAIN/go-gachain/packages/tcpserver"
+	"github.com/GACHAIN/go-gachain/packages/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// getCompactBlock gets the block from the host as the header and the hashes of transactions,
+// restores the block from the local transactions and downloads only the missing ones.
+// It is called by queue_parser_blocks for the announced block, which downloads the full block
+// body if getCompactBlock fails.
+func getCompactBlock(host string, blockID int64, logger *log.Entry) ([]byte, error) {
+	if !network.HostSupports(host, consts.DATA_TYPE_COMPACT_BLOCK) {
+		return nil, network.ErrUnsupported
+	}
+	conn, err := utils.TCPConn(host)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	type compactBlockRequest struct {
+		Type    uint16
+		BlockID uint32
+	}
+	err = tcpserver.SendRequest(&compactBlockRequest{Type: consts.DATA_TYPE_COMPACT_BLOCK, BlockID: uint32(blockID)}, conn)
+	if err != nil {
+		return nil, err
+	}
+	resp := &tcpserver.CompactBlockResponse{}
+	if err = tcpserver.ReadRequest(resp, conn); err != nil {
+		return nil, err
+	}
+	if len(resp.Header) == 0 {
+		return nil, fmt.Errorf("block %d not found", blockID)
+	}
+	hashes, err := parser.SplitHashes(resp.Hashes)
+	if err != nil {
+		peers.Penalize(host, peers.ProtocolError, err)
+		return nil, err
+	}
+
+	txs, missing, err := parser.LocalTransactions(hashes)
+	if err != nil {
+		return nil, err
+	}
+	if err = tcpserver.SendRequest(&tcpserver.MissingTxsRequest{Hashes: bytes.Join(missing, nil)}, conn); err != nil {
+		return nil, err
+	}
+	txsResp := &tcpserver.MissingTxsResponse{}
+	if err = tcpserver.ReadRequest(txsResp, conn); err != nil {
+		return nil, err
+	}
+	if err = parser.AddMissingTransactions(txs, missing, txsResp.Data); err != nil {
+		peers.Penalize(host, peers.ProtocolError, err)
+		return nil, err
+	}
+
+	block, err := parser.RestoreBlock(resp.Header, hashes, txs)
+	if err != nil {
+		peers.Penalize(host, peers.ProtocolError, err)
+		return nil, err
+	}
+	logger.WithFields(log.Fields{"block_id": blockID, "host": host, "txs": len(hashes), "missing": len(missing)}).Debug("block is restored from the compact block")
+	return block, nil
+}
@@ -24,7 +24,6 @@ package daemons
 
 import (
 	"context"
-	"net"
 	"strconv"
 	"time"
 
@@ -34,6 +33,7 @@ import (
 	"github.com/GACHAIN/go-gachain/packages/converter"
 	"github.com/GACHAIN/go-gachain/packages/model"
 	"github.com/GACHAIN/go-gachain/packages/tcpserver"
+	"github.com/GACHAIN/go-gachain/packages/utils"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -162,16 +162,13 @@ func Confirmations(ctx context.Context, d *daemon) error {
 }
 
 func checkConf(host string, blockID int64, logger *log.Entry) string {
-	conn, err := net.DialTimeout("tcp", host, 5*time.Second)
+	conn, err := utils.TCPConn(host)
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.ConnectionError, "error": err, "host": host, "block_id": blockID}).Debug("dialing to host")
 		return "0"
 	}
 	defer conn.Close()
 
-	conn.SetReadDeadline(time.Now().Add(consts.READ_TIMEOUT * time.Second))
-	conn.SetWriteDeadline(time.Now().Add(consts.WRITE_TIMEOUT * time.Second))
-
 	type confRequest struct {
 		Type    uint16
 		BlockID uint32
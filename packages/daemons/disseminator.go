@@ -25,6 +25,7 @@ package daemons
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"sync"
 
@@ -33,6 +34,7 @@ import (
 	"github.com/GACHAIN/go-gachain/packages/consts"
 	"github.com/GACHAIN/go-gachain/packages/converter"
 	"github.com/GACHAIN/go-gachain/packages/model"
+	"github.com/GACHAIN/go-gachain/packages/peers"
 	"github.com/GACHAIN/go-gachain/packages/utils"
 
 	log "github.com/sirupsen/logrus"
@@ -236,7 +238,7 @@ func MarshallTrHash(tr model.Transaction) []byte {
 
 func sendPacketToAll(reqType int, buf []byte, respHand func(resp []byte, w io.Writer, logger *log.Entry) error, logger *log.Entry) error {
 
-	hosts := syspar.GetRemoteHosts()
+	hosts := peers.Filter(syspar.GetRemoteHosts())
 	var wg sync.WaitGroup
 
 	for _, host := range hosts {
@@ -295,6 +297,7 @@ func sendDRequest(host string, reqType int, buf []byte, respHandler func([]byte,
 		// read data size
 		_, err = io.ReadFull(conn, buf)
 		if err != nil {
+			peers.PenalizeNet(host, err)
 			if err == io.EOF {
 				logger.WithFields(log.Fields{"type": consts.IOError, "error": err, "host": host}).Warn("connection closed unexpectedly")
 			} else {
@@ -305,17 +308,20 @@ func sendDRequest(host string, reqType int, buf []byte, respHandler func([]byte,
 		respSize := converter.BinToDec(buf)
 		if respSize > syspar.GetMaxTxSize() {
 			logger.WithFields(log.Fields{"size": respSize, "max_size": syspar.GetMaxTxSize(), "type": consts.ParameterExceeded}).Warning("response size is larger than max tx size")
+			peers.Penalize(host, peers.ProtocolError, fmt.Errorf("response size %d is larger than max tx size", respSize))
 			return nil
 		}
 		// read the data
 		resp := make([]byte, respSize)
 		_, err = io.ReadFull(conn, resp)
 		if err != nil {
+			peers.PenalizeNet(host, err)
 			logger.WithFields(log.Fields{"type": consts.IOError, "error": err, "host": host}).Error("reading data")
 			return err
 		}
 		err = respHandler(resp, conn, logger)
 		if err != nil {
+			peers.Penalize(host, peers.ProtocolError, err)
 			logger.WithFields(log.Fields{"type": consts.IOError, "error": err, "host": host}).Error("reading data")
 			return err
 		}
@@ -31,6 +31,7 @@ import (
 	"github.com/GACHAIN/go-gachain/packages/consts"
 	"github.com/GACHAIN/go-gachain/packages/converter"
 	"github.com/GACHAIN/go-gachain/packages/model"
+	"github.com/GACHAIN/go-gachain/packages/tcpserver"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -76,6 +77,12 @@ func Monitoring(w http.ResponseWriter, r *http.Request) {
 	}
 	addKey(&buf, "transactions_count", trCount)
 
+	tcpStats := tcpserver.Stats()
+	addKey(&buf, "tcp_connections", tcpStats.Active)
+	addKey(&buf, "tcp_accepted", tcpStats.Accepted)
+	addKey(&buf, "tcp_rejected_total", tcpStats.RejectedTotal)
+	addKey(&buf, "tcp_rejected_per_peer", tcpStats.RejectedPerPeer)
+
 	w.Write(buf.Bytes())
 }
 
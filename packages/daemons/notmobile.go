@@ -30,6 +30,7 @@ import (
 	"github.com/GACHAIN/go-gachain/packages/conf"
 	"github.com/GACHAIN/go-gachain/packages/consts"
 	"github.com/GACHAIN/go-gachain/packages/model"
+	"github.com/GACHAIN/go-gachain/packages/tcpserver"
 	"github.com/GACHAIN/go-gachain/packages/utils"
 
 	log "github.com/sirupsen/logrus"
@@ -76,6 +77,7 @@ func WaitForSignals() {
 		signal.Notify(SigChan, os.Interrupt, os.Kill, Term)
 		<-SigChan
 
+		tcpserver.Shutdown()
 		if utils.CancelFunc != nil {
 			utils.CancelFunc()
 			for i := 0; i < utils.DaemonsCount; i++ {
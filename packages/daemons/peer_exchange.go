@@ -0,0 +1,106 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package daemons
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+
+	"github.com/GACHAIN/go-gachain/packages/conf"
+	"github.com/GACHAIN/go-gachain/packages/consts"
+	"github.com/GACHAIN/go-gachain/packages/model"
+	"github.com/GACHAIN/go-gachain/packages/network"
+	"github.com/GACHAIN/go-gachain/packages/peers"
+	"github.com/GACHAIN/go-gachain/packages/tcpserver"
+	"github.com/GACHAIN/go-gachain/packages/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// exchangePeers announces this node to the random host and adds the nodes which are known to the host
+// to candidates, they are verified by the request of the last block
+func exchangePeers(hosts []string, blockID int64, logger *log.Entry) {
+	candidates := make([]string, 0, len(hosts))
+	for _, h := range hosts {
+		if h = getHostPort(h); network.HostSupports(h, consts.DATA_TYPE_PEERS) {
+			candidates = append(candidates, h)
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+	host := candidates[rand.Intn(len(candidates))]
+	nodes, err := requestPeers(host, blockID)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.ConnectionError, "error": err, "host": host}).Debug("exchanging peers")
+		return
+	}
+	if len(nodes) > consts.MAX_PEERS_EXCHANGE {
+		peers.Penalize(host, peers.ProtocolError, fmt.Errorf("host has sent %d nodes", len(nodes)))
+		return
+	}
+	// this node must not be added to its own table
+	known := nodes[:0]
+	for _, node := range nodes {
+		if node.Addr != conf.Config.PublicAddress {
+			known = append(known, node)
+		}
+	}
+	peers.Merge(known)
+}
+
+func requestPeers(host string, blockID int64) ([]peers.Node, error) {
+	conn, err := utils.TCPConn(host)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	type peersRequest struct {
+		Type    uint16
+		BlockID uint32
+		Addr    []byte
+	}
+	// the node without the bodies of all blocks isn't announced, because it can't send them to syncing nodes
+	addr := conf.Config.PublicAddress
+	if complete, err := model.IsChainComplete(); err != nil || !complete {
+		addr = ``
+	}
+	err = tcpserver.SendRequest(&peersRequest{Type: consts.DATA_TYPE_PEERS, BlockID: uint32(blockID),
+		Addr: []byte(addr)}, conn)
+	if err != nil {
+		return nil, err
+	}
+	resp := &tcpserver.PeersResponse{}
+	if err = tcpserver.ReadRequest(resp, conn); err != nil {
+		return nil, err
+	}
+	var nodes []peers.Node
+	if err = json.Unmarshal(resp.Data, &nodes); err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err, "host": host}).Error("unmarshalling nodes")
+		peers.Penalize(host, peers.ProtocolError, err)
+		return nil, err
+	}
+	return nodes, nil
+}
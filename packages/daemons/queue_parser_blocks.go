@@ -95,6 +95,14 @@ func QueueParserBlocks(ctx context.Context, d *daemon) error {
 	blockID := queueBlock.BlockID
 
 	host := getHostPort(nodeHost)
+	// the next block is restored from the local transactions, so only the missing ones are downloaded
+	if blockID == infoBlock.BlockID+1 {
+		blockBin, err := getCompactBlock(host, blockID, d.logger)
+		if err == nil {
+			return updateBlock(d, host, blockID, blockBin)
+		}
+		d.logger.WithFields(log.Fields{"type": consts.ConnectionError, "error": err, "host": host, "block_id": blockID}).Debug("getting compact block")
+	}
 	// update our chain till maxBlockID from the host
 	return UpdateChain(ctx, d, host, blockID)
 }
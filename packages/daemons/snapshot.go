@@ -0,0 +1,159 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package daemons
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/GACHAIN/go-gachain/packages/conf"
+	"github.com/GACHAIN/go-gachain/packages/config/syspar"
+	"github.com/GACHAIN/go-gachain/packages/consts"
+	"github.com/GACHAIN/go-gachain/packages/language"
+	"github.com/GACHAIN/go-gachain/packages/model"
+	"github.com/GACHAIN/go-gachain/packages/network"
+	"github.com/GACHAIN/go-gachain/packages/smart"
+	"github.com/GACHAIN/go-gachain/packages/snapshot"
+	"github.com/GACHAIN/go-gachain/packages/tcpserver"
+	"github.com/GACHAIN/go-gachain/packages/template"
+	"github.com/GACHAIN/go-gachain/packages/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const snapshotDownloadFilename = "snapshot.download"
+
+// loadSnapshot downloads the snapshot of the state and restores it if the majority of full nodes
+// have signed the snapshots with the same state at the block, so the full nodes must create snapshots
+// at the same interval. The blocks after the snapshot are got by blocksCollection as usual.
+func loadSnapshot(ctx context.Context, d *daemon) error {
+	fileName := filepath.Join(conf.Config.WorkDir, snapshotDownloadFilename)
+	defer os.Remove(fileName)
+	if _, err := downloadToFile(ctx, conf.Config.Snapshot.URL, fileName, d.logger); err != nil {
+		return err
+	}
+
+	r, err := snapshot.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	node := syspar.GetNode(r.Header.KeyID)
+	if node == nil {
+		return fmt.Errorf("snapshot is signed by %d which isn't full node", r.Header.KeyID)
+	}
+	if err = r.Header.CheckSign(node.Public); err != nil {
+		return err
+	}
+	if !isStateConfirmed(&r.Header, d.logger) {
+		return fmt.Errorf("state of snapshot %d isn't confirmed by full nodes", r.Header.BlockID)
+	}
+
+	DBLock()
+	defer DBUnlock()
+
+	infoBlock := &model.InfoBlock{}
+	if _, err = infoBlock.Get(); err != nil {
+		d.logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting info block")
+		return err
+	}
+	if infoBlock.BlockID >= r.Header.BlockID {
+		return fmt.Errorf("block %d of snapshot isn't after the last block %d", r.Header.BlockID, infoBlock.BlockID)
+	}
+	if err = snapshot.Restore(r); err != nil {
+		return err
+	}
+	// the pages and the language resources of the previous state are cached
+	template.ClearCache()
+	language.ClearLang()
+	if err = syspar.SysUpdate(nil); err != nil {
+		d.logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("updating syspar")
+		return err
+	}
+	return smart.ReloadContracts()
+}
+
+// isStateConfirmed returns true if the majority of full nodes have signed the snapshots
+// with the same block and state as the header
+func isStateConfirmed(header *snapshot.Header, logger *log.Entry) bool {
+	hosts := syspar.GetRemoteHosts()
+	if len(hosts) == 0 {
+		return false
+	}
+	ch := make(chan bool, len(hosts))
+	for _, host := range hosts {
+		go func(host string) {
+			ch <- confirmState(host, header, logger)
+		}(host)
+	}
+	var confirmed int
+	for range hosts {
+		if <-ch {
+			confirmed++
+		}
+	}
+	logger.WithFields(log.Fields{"block_id": header.BlockID, "confirmed": confirmed, "hosts": len(hosts)}).Debug("confirmations of snapshot state")
+	return confirmed*2 > len(hosts)
+}
+
+// confirmState returns true if the full node has signed the snapshot with the same block and state
+func confirmState(host string, header *snapshot.Header, logger *log.Entry) bool {
+	keyID, node := syspar.GetNodeByHost(host)
+	if node == nil {
+		return false
+	}
+	host = getHostPort(host)
+	if !network.HostSupports(host, consts.DATA_TYPE_SNAPSHOT_HEADER) {
+		return false
+	}
+	conn, err := utils.TCPConn(host)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+
+	type snapshotHeaderRequest struct {
+		Type    uint16
+		BlockID uint32
+	}
+	err = tcpserver.SendRequest(&snapshotHeaderRequest{Type: consts.DATA_TYPE_SNAPSHOT_HEADER, BlockID: uint32(header.BlockID)}, conn)
+	if err != nil {
+		return false
+	}
+	resp := &tcpserver.SnapshotHeaderResponse{}
+	if err = tcpserver.ReadRequest(resp, conn); err != nil || len(resp.Data) == 0 {
+		return false
+	}
+	remote := &snapshot.Header{}
+	if err = json.Unmarshal(resp.Data, remote); err != nil {
+		logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err, "host": host}).Error("unmarshalling snapshot header")
+		return false
+	}
+	return remote.KeyID == keyID && remote.CheckSign(node.Public) == nil && remote.BlockID == header.BlockID &&
+		bytes.Equal(remote.BlockHash, header.BlockHash) && bytes.Equal(remote.StateHash, header.StateHash)
+}
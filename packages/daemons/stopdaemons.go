@@ -29,6 +29,7 @@ import (
 
 	"github.com/GACHAIN/go-gachain/packages/consts"
 	"github.com/GACHAIN/go-gachain/packages/model"
+	"github.com/GACHAIN/go-gachain/packages/tcpserver"
 	"github.com/GACHAIN/go-gachain/packages/utils"
 
 	log "github.com/sirupsen/logrus"
@@ -54,6 +55,7 @@ func WaitStopTime() {
 			log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("selecting stop_time from StopDaemons")
 		}
 		if dExists > 0 {
+			tcpserver.Shutdown()
 			utils.CancelFunc()
 			for i := 0; i < utils.DaemonsCount; i++ {
 				name := <-utils.ReturnCh
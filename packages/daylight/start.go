@@ -23,6 +23,7 @@
 package daylight
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -44,6 +45,7 @@ import (
 	logtools "github.com/GACHAIN/go-gachain/packages/log"
 	"github.com/GACHAIN/go-gachain/packages/model"
 	"github.com/GACHAIN/go-gachain/packages/parser"
+	"github.com/GACHAIN/go-gachain/packages/peers"
 	"github.com/GACHAIN/go-gachain/packages/publisher"
 	"github.com/GACHAIN/go-gachain/packages/smart"
 	"github.com/GACHAIN/go-gachain/packages/statsd"
@@ -138,6 +140,23 @@ func delPidFile() {
 	os.Remove(conf.GetPidFile())
 }
 
+func exportArchive(dir string) error {
+	if err := syspar.SysUpdate(nil); err != nil {
+		return err
+	}
+	return daemons.ExportArchive(dir)
+}
+
+func importArchive(dir string) error {
+	if err := syspar.SysUpdate(nil); err != nil {
+		return err
+	}
+	if err := smart.LoadContracts(nil); err != nil {
+		return err
+	}
+	return daemons.ImportArchive(context.Background(), dir)
+}
+
 func rollbackToBlock(blockID int64) error {
 	if err := smart.LoadContracts(nil); err != nil {
 		return err
@@ -330,10 +349,33 @@ func Start() {
 		Exit(0)
 	}
 
+	// export or import of the blockchain archive
+	if len(*conf.ExportArchive) > 0 {
+		if err := exportArchive(*conf.ExportArchive); err != nil {
+			log.WithError(err).Error("Export archive error")
+			Exit(1)
+		}
+		Exit(0)
+	}
+	if len(*conf.ImportArchive) > 0 {
+		if err := importArchive(*conf.ImportArchive); err != nil {
+			log.WithError(err).Error("Import archive error")
+			Exit(1)
+		}
+		Exit(0)
+	}
+
 	if *conf.NoStart {
 		Exit(0)
 	}
 
+	if err := peers.Init(conf.GetPeersFile()); err != nil {
+		log.WithError(err).Error("can't load peers")
+	}
+	if err := peers.InitTable(conf.GetNodeTableFile()); err != nil {
+		log.WithError(err).Error("can't load node table")
+	}
+
 	if model.DBConn != nil {
 		// The installation process is already finished (where user has specified DB and where wallet has been restarted)
 		err := daemonsctl.RunAllDaemons()
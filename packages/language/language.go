@@ -84,6 +84,11 @@ func UpdateLang(state int, name, value string, vde bool) {
 	}
 }
 
+// ClearLang removes the loaded language resources of all states, they are loaded again when they are used
+func ClearLang() {
+	lang = make(map[int]*cacheLang)
+}
+
 // loadLang download the language sources from database for the state
 func loadLang(state int, vde bool) error {
 	language := &model.Language{}
@@ -116,11 +121,33 @@ func loadLang(state int, vde bool) error {
 	return nil
 }
 
-// LangText looks for the specified word through language sources and returns the meaning of the source
-// if it is found. Search goes according to the languages specified in 'accept'
-func LangText(in string, state int, accept string, vde bool) (string, bool) {
+// acceptChain returns the languages of Accept-Language with their fallbacks, e.g. pt-BR, pt
+func acceptChain(accept string) []string {
+	chain := make([]string, 0)
+	for _, val := range strings.Split(accept, `,`) {
+		if off := strings.IndexByte(val, ';'); off >= 0 {
+			val = val[:off]
+		}
+		val = strings.Replace(strings.TrimSpace(val), `_`, `-`, -1)
+		if len(val) < 2 {
+			break
+		}
+		base := strings.ToLower(val[:2])
+		if !IsLang(base) {
+			continue
+		}
+		if len(val) > 2 && val[2] == '-' {
+			chain = append(chain, base+strings.ToUpper(val[2:]))
+		}
+		chain = append(chain, base)
+	}
+	return chain
+}
+
+// langResource returns the language resource and the language which it has been found for
+func langResource(in string, state int, accept string, vde bool) (string, string, bool) {
 	if strings.IndexByte(in, ' ') >= 0 || state == 0 {
-		return in, false
+		return in, ``, false
 	}
 	istate := state
 	if vde {
@@ -128,32 +155,48 @@ func LangText(in string, state int, accept string, vde bool) (string, bool) {
 	}
 	if _, ok := lang[istate]; !ok {
 		if err := loadLang(state, vde); err != nil {
-			return err.Error(), false
+			return err.Error(), ``, false
 		}
 	}
 	if lres, ok := (*lang[istate]).res[in]; ok {
-		langs := strings.Split(accept, `,`)
 		lng := DefLang()
-		for _, val := range langs {
-			if len(val) < 2 {
-				break
-			}
-			if !IsLang(val[:2]) {
-				continue
-			}
-			if _, ok := (*lres)[val[:2]]; ok {
-				lng = val[:2]
+		for _, val := range acceptChain(accept) {
+			if _, ok := (*lres)[val]; ok {
+				lng = val
 				break
 			}
 		}
 		if len((*lres)[lng]) == 0 {
-			for _, val := range *lres {
-				return val, true
+			for key, val := range *lres {
+				return val, key, true
 			}
 		}
-		return (*lres)[lng], true
+		return (*lres)[lng], lng, true
+	}
+	return in, ``, false
+}
+
+// LangText looks for the specified word through language sources and returns the meaning of the source
+// if it is found. Search goes according to the languages specified in 'accept', a language with
+// the region like pt-BR falls back to pt
+func LangText(in string, state int, accept string, vde bool) (string, bool) {
+	text, _, ok := langResource(in, state, accept, vde)
+	return text, ok
+}
+
+// LangFormat looks for the language resource like LangText and formats it with the arguments
+// by FormatMessage according to the found language
+func LangFormat(in string, state int, accept string, vde bool, args map[string]string) (string, bool) {
+	text, lng, ok := langResource(in, state, accept, vde)
+	if !ok {
+		return text, false
+	}
+	out, err := FormatMessage(text, lng, args)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.ParseError, "name": in, "error": err}).Warning("formatting language resource")
+		return text, true
 	}
-	return in, false
+	return out, true
 }
 
 // LangMacro replaces all inclusions of $resname$ in the incoming text with the corresponding language resources,
@@ -0,0 +1,77 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package language
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatMessage(t *testing.T) {
+	items := `{count, plural, =0 {No items} one {# item} other {# items}}`
+	for _, item := range []struct {
+		msg, lang string
+		args      map[string]string
+		want      string
+	}{
+		{`Hello, {name}!`, `en`, map[string]string{`name`: `John`}, `Hello, John!`},
+		{`Hello, {name}!`, `en`, nil, `Hello, {name}!`},
+		{items, `en`, map[string]string{`count`: `0`}, `No items`},
+		{items, `en`, map[string]string{`count`: `1`}, `1 item`},
+		{items, `en`, map[string]string{`count`: `1200`}, `1,200 items`},
+		{`{n, plural, one {# файл} few {# файла} many {# файлов} other {# файла}}`, `ru-RU`,
+			map[string]string{`n`: `22`}, `22 файла`},
+		{`{n, plural, one {# файл} few {# файла} many {# файлов} other {# файла}}`, `ru`,
+			map[string]string{`n`: `11`}, `11 файлов`},
+		{`{n, plural, one {# fichier} other {# fichiers}}`, `fr`, map[string]string{`n`: `0`}, `0 fichier`},
+		{`{n, plural, offset:1 =0 {nobody} one {you and # other} other {you and # others}}`, `en`,
+			map[string]string{`n`: `3`}, `you and 2 others`},
+		{`{g, select, male {He} female {She} other {They}} paid {a, number, .00}`, `de`,
+			map[string]string{`g`: `female`, `a`: `1234.5`}, `She paid 1.234,50`},
+		{`{p, number, percent}`, `en`, map[string]string{`p`: `0.256`}, `26%`},
+		{`{d, date}`, `pt-BR`, map[string]string{`d`: `2018-03-05 10:20:30`}, `05/03/2018`},
+		{`{d, date, long}`, `en`, map[string]string{`d`: `1520245230`}, `03/05/2018 10:20:30`},
+		{`'{name}' is {name}`, `en`, map[string]string{`name`: `x`}, `{name} is x`},
+	} {
+		out, err := FormatMessage(item.msg, item.lang, item.args)
+		if err != nil {
+			t.Errorf("%s: %v", item.msg, err)
+			continue
+		}
+		if out != item.want {
+			t.Errorf("wrong message %s != %s", out, item.want)
+		}
+	}
+	for _, msg := range []string{`{count, plural, one {#}`, `{n, plural, one {#}}`, `{n, money}`} {
+		if _, err := FormatMessage(msg, `en`, map[string]string{`count`: `1`, `n`: `1`}); err == nil {
+			t.Errorf("%s must be wrong", msg)
+		}
+	}
+}
+
+func TestAcceptChain(t *testing.T) {
+	chain := strings.Join(acceptChain(`pt-br;q=0.9, en_US, ru`), `,`)
+	if chain != `pt-BR,pt,en-US,en,ru` {
+		t.Errorf("wrong chain %s", chain)
+	}
+}
@@ -0,0 +1,468 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package language
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GACHAIN/go-gachain/packages/converter"
+
+	"github.com/shopspring/decimal"
+)
+
+// Plural categories of CLDR
+const (
+	pluralZero  = `zero`
+	pluralOne   = `one`
+	pluralTwo   = `two`
+	pluralFew   = `few`
+	pluralMany  = `many`
+	pluralOther = `other`
+)
+
+var errUnbalanced = errors.New(`unbalanced braces in the message`)
+
+// pluralRule returns the plural category by the integer part i and the number of fraction digits v
+type pluralRule func(n decimal.Decimal, i int64, v int) string
+
+var (
+	ruleOne = func(n decimal.Decimal, i int64, v int) string {
+		if i == 1 && v == 0 {
+			return pluralOne
+		}
+		return pluralOther
+	}
+	ruleZeroOne = func(n decimal.Decimal, i int64, v int) string {
+		if i == 0 || i == 1 {
+			return pluralOne
+		}
+		return pluralOther
+	}
+	ruleSlavic = func(n decimal.Decimal, i int64, v int) string {
+		switch {
+		case v != 0:
+			return pluralOther
+		case i%10 == 1 && i%100 != 11:
+			return pluralOne
+		case i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14):
+			return pluralFew
+		}
+		return pluralMany
+	}
+	rulePolish = func(n decimal.Decimal, i int64, v int) string {
+		switch {
+		case v != 0:
+			return pluralOther
+		case i == 1:
+			return pluralOne
+		case i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14):
+			return pluralFew
+		}
+		return pluralMany
+	}
+	ruleCzech = func(n decimal.Decimal, i int64, v int) string {
+		switch {
+		case v != 0:
+			return pluralMany
+		case i == 1:
+			return pluralOne
+		case i >= 2 && i <= 4:
+			return pluralFew
+		}
+		return pluralOther
+	}
+	ruleArabic = func(n decimal.Decimal, i int64, v int) string {
+		if v != 0 {
+			return pluralOther
+		}
+		switch mod := i % 100; {
+		case i == 0:
+			return pluralZero
+		case i == 1:
+			return pluralOne
+		case i == 2:
+			return pluralTwo
+		case mod >= 3 && mod <= 10:
+			return pluralFew
+		case mod >= 11:
+			return pluralMany
+		}
+		return pluralOther
+	}
+	ruleNone = func(n decimal.Decimal, i int64, v int) string {
+		return pluralOther
+	}
+
+	pluralRules = map[string]pluralRule{
+		`ar`: ruleArabic,
+		`be`: ruleSlavic, `ru`: ruleSlavic, `uk`: ruleSlavic,
+		`cs`: ruleCzech, `sk`: ruleCzech,
+		`pl`: rulePolish,
+		`fr`: ruleZeroOne, `pt`: ruleZeroOne, `hi`: ruleZeroOne,
+		`id`: ruleNone, `ja`: ruleNone, `ko`: ruleNone, `ms`: ruleNone, `th`: ruleNone,
+		`tr`: ruleNone, `vi`: ruleNone, `zh`: ruleNone,
+	}
+
+	// numberSymbols are the decimal and the group separators of the languages
+	numberSymbols = map[string][2]string{
+		`de`: {`,`, `.`}, `es`: {`,`, `.`}, `id`: {`,`, `.`}, `it`: {`,`, `.`}, `nl`: {`,`, `.`},
+		`pt`: {`,`, `.`}, `tr`: {`,`, `.`}, `vi`: {`,`, `.`},
+		`be`: {`,`, " "}, `cs`: {`,`, " "}, `fr`: {`,`, " "}, `pl`: {`,`, " "},
+		`ru`: {`,`, " "}, `sk`: {`,`, " "}, `uk`: {`,`, " "},
+	}
+
+	// dateFormats are the short formats of the date of the languages
+	dateFormats = map[string]string{
+		`en`: `MM/DD/YYYY`,
+		`be`: `DD.MM.YYYY`, `cs`: `DD.MM.YYYY`, `de`: `DD.MM.YYYY`, `pl`: `DD.MM.YYYY`,
+		`ru`: `DD.MM.YYYY`, `sk`: `DD.MM.YYYY`, `tr`: `DD.MM.YYYY`, `uk`: `DD.MM.YYYY`,
+		`es`: `DD/MM/YYYY`, `fr`: `DD/MM/YYYY`, `it`: `DD/MM/YYYY`, `pt`: `DD/MM/YYYY`, `vi`: `DD/MM/YYYY`,
+		`ja`: `YYYY/MM/DD`, `ko`: `YYYY.MM.DD`, `zh`: `YYYY/MM/DD`,
+	}
+)
+
+// baseLang returns the language without the region, e.g. pt for pt-BR
+func baseLang(lang string) string {
+	if off := strings.IndexAny(lang, `-_`); off >= 0 {
+		lang = lang[:off]
+	}
+	return strings.ToLower(strings.TrimSpace(lang))
+}
+
+// PluralCategory returns the plural category (zero, one, two, few, many, other) of the number
+// for the language
+func PluralCategory(lang string, n decimal.Decimal) string {
+	rule, ok := pluralRules[baseLang(lang)]
+	if !ok {
+		rule = ruleOne
+	}
+	v := 0
+	if s := n.String(); strings.IndexByte(s, '.') >= 0 {
+		v = len(s) - strings.IndexByte(s, '.') - 1
+	}
+	return rule(n, n.Abs().IntPart(), v)
+}
+
+// FormatNumber formats the number according to the language. The style can be integer, percent
+// or the pattern of fraction digits like .00
+func FormatNumber(lang, value, style string) (string, error) {
+	n, err := decimal.NewFromString(strings.TrimSpace(value))
+	if err != nil {
+		return ``, err
+	}
+	switch style = strings.TrimSpace(style); {
+	case style == `integer`:
+		n = n.Round(0)
+	case style == `percent`:
+		n = n.Mul(decimal.New(100, 0)).Round(0)
+	case strings.HasPrefix(style, `.`):
+		n = n.Round(int32(len(style) - 1))
+	case len(style) > 0:
+		return ``, fmt.Errorf(`unknown number style %s`, style)
+	}
+	str := n.String()
+	if strings.HasPrefix(style, `.`) {
+		str = n.StringFixed(int32(len(style) - 1))
+	}
+	symbols, ok := numberSymbols[baseLang(lang)]
+	if !ok {
+		symbols = [2]string{`.`, `,`}
+	}
+	sign := ``
+	if strings.HasPrefix(str, `-`) {
+		sign, str = `-`, str[1:]
+	}
+	fraction := ``
+	if off := strings.IndexByte(str, '.'); off >= 0 {
+		str, fraction = str[:off], symbols[0]+str[off+1:]
+	}
+	var out []byte
+	for i := range str {
+		if i > 0 && (len(str)-i)%3 == 0 {
+			out = append(out, symbols[1]...)
+		}
+		out = append(out, str[i])
+	}
+	ret := sign + string(out) + fraction
+	if style == `percent` {
+		ret += `%`
+	}
+	return ret, nil
+}
+
+// TimeLayout converts the format like YYYY-MM-DD HH:MI:SS to the layout of time package
+func TimeLayout(format string) string {
+	for _, item := range [][2]string{{`YYYY`, `2006`}, {`YY`, `06`}, {`MM`, `01`}, {`DD`, `02`},
+		{`HH`, `15`}, {`MI`, `04`}, {`SS`, `05`}} {
+		format = strings.Replace(format, item[0], item[1], -1)
+	}
+	return format
+}
+
+// FormatDate formats the date according to the language. The value can be the unix time or
+// the time like YYYY-MM-DD HH:MI:SS. The style is short, medium, long or the format like DD.MM.YYYY
+func FormatDate(lang, value, style string) (string, error) {
+	var (
+		t   time.Time
+		err error
+	)
+	value = strings.TrimSpace(value)
+	if unix := converter.StrToInt64(value); unix > 0 && converter.Int64ToStr(unix) == value {
+		t = time.Unix(unix, 0).UTC()
+	} else {
+		for _, layout := range []string{`2006-01-02T15:04:05`, `2006-01-02 15:04:05`, `2006-01-02`} {
+			if len(value) >= len(layout) {
+				if t, err = time.Parse(layout, value[:len(layout)]); err == nil {
+					break
+				}
+			}
+		}
+		if t.IsZero() {
+			return ``, fmt.Errorf(`wrong date %s`, value)
+		}
+	}
+	return t.Format(TimeLayout(DateFormat(lang, style))), nil
+}
+
+// DateFormat returns the format like DD.MM.YYYY of the style short, medium, long or time
+// for the language. Other styles are returned as is.
+func DateFormat(lang, style string) string {
+	format, ok := dateFormats[baseLang(lang)]
+	if !ok {
+		format = `YYYY-MM-DD`
+	}
+	switch style = strings.TrimSpace(style); style {
+	case ``, `short`:
+	case `medium`:
+		format += ` HH:MI`
+	case `long`:
+		format += ` HH:MI:SS`
+	case `time`:
+		format = `HH:MI`
+	default:
+		format = style
+	}
+	return format
+}
+
+type messageFormat struct {
+	lang string
+	args map[string]string
+}
+
+// FormatMessage formats the message in ICU style. The message can contain the arguments like
+// {name}, {amount, number, .00}, {date, date, short}, {count, plural, =0 {none} one {# item} other {# items}}
+// and {gender, select, male {he} female {she} other {they}}. Unknown arguments are left as is.
+func FormatMessage(msg, lang string, args map[string]string) (string, error) {
+	if strings.IndexByte(msg, '{') < 0 {
+		return msg, nil
+	}
+	m := &messageFormat{lang: lang, args: args}
+	return m.format([]rune(msg), ``)
+}
+
+// closing returns the index of the brace which closes the brace at the start position
+func closing(runes []rune, start int) (int, error) {
+	depth := 0
+	quoted := false
+	for i := start; i < len(runes); i++ {
+		switch runes[i] {
+		case '\'':
+			if i+1 < len(runes) && (quoted || runes[i+1] == '{' || runes[i+1] == '}') {
+				quoted = !quoted
+			}
+		case '{':
+			if !quoted {
+				depth++
+			}
+		case '}':
+			if !quoted {
+				if depth--; depth == 0 {
+					return i, nil
+				}
+			}
+		}
+	}
+	return 0, errUnbalanced
+}
+
+func (m *messageFormat) format(runes []rune, hash string) (string, error) {
+	var out bytes.Buffer
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '\'':
+			if i+1 < len(runes) && runes[i+1] == '\'' {
+				out.WriteRune(r)
+				i++
+			} else if i+1 < len(runes) && strings.ContainsRune(`{}#`, runes[i+1]) {
+				end := i + 1
+				for end < len(runes) && runes[end] != '\'' {
+					end++
+				}
+				out.WriteString(string(runes[i+1 : end]))
+				i = end
+			} else {
+				out.WriteRune(r)
+			}
+		case '#':
+			if len(hash) > 0 {
+				out.WriteString(hash)
+			} else {
+				out.WriteRune(r)
+			}
+		case '{':
+			end, err := closing(runes, i)
+			if err != nil {
+				return ``, err
+			}
+			val, err := m.argument(runes[i+1 : end])
+			if err != nil {
+				return ``, err
+			}
+			out.WriteString(val)
+			i = end
+		case '}':
+			return ``, errUnbalanced
+		default:
+			out.WriteRune(r)
+		}
+	}
+	return out.String(), nil
+}
+
+// argument formats the argument like name, type, style
+func (m *messageFormat) argument(body []rune) (string, error) {
+	parts := make([]string, 0, 3)
+	start := 0
+	for i := 0; i < len(body) && len(parts) < 2; i++ {
+		if body[i] == '{' {
+			break
+		}
+		if body[i] == ',' {
+			parts = append(parts, strings.TrimSpace(string(body[start:i])))
+			start = i + 1
+		}
+	}
+	parts = append(parts, strings.TrimSpace(string(body[start:])))
+	name := parts[0]
+	value, ok := m.args[name]
+	if !ok {
+		return `{` + string(body) + `}`, nil
+	}
+	if len(parts) == 1 {
+		return value, nil
+	}
+	style := ``
+	if len(parts) > 2 {
+		style = parts[2]
+	}
+	switch parts[1] {
+	case `number`:
+		return FormatNumber(m.lang, value, style)
+	case `date`:
+		return FormatDate(m.lang, value, style)
+	case `time`:
+		if len(style) == 0 {
+			style = `time`
+		}
+		return FormatDate(m.lang, value, style)
+	case `plural`:
+		return m.plural(value, []rune(style))
+	case `select`:
+		options, _, err := m.options([]rune(style))
+		if err != nil {
+			return ``, err
+		}
+		if msg, ok := options[value]; ok {
+			return m.format(msg, ``)
+		}
+		return m.format(options[pluralOther], ``)
+	}
+	return ``, fmt.Errorf(`unknown type %s of argument %s`, parts[1], name)
+}
+
+// options parses the list of options like "offset:1 =0 {text} one {text} other {text}"
+func (m *messageFormat) options(style []rune) (map[string][]rune, decimal.Decimal, error) {
+	options := make(map[string][]rune)
+	offset := decimal.Zero
+	for i := 0; i < len(style); {
+		for i < len(style) && (style[i] == ' ' || style[i] == '\t' || style[i] == '\n' || style[i] == '\r') {
+			i++
+		}
+		if i >= len(style) {
+			break
+		}
+		start := i
+		for i < len(style) && style[i] != '{' && style[i] != ' ' && style[i] != '\t' && style[i] != '\n' {
+			i++
+		}
+		selector := string(style[start:i])
+		if strings.HasPrefix(selector, `offset:`) {
+			var err error
+			if offset, err = decimal.NewFromString(selector[len(`offset:`):]); err != nil {
+				return nil, offset, err
+			}
+			continue
+		}
+		for i < len(style) && style[i] != '{' {
+			i++
+		}
+		end, err := closing(style, i)
+		if err != nil {
+			return nil, offset, err
+		}
+		options[selector] = style[i+1 : end]
+		i = end + 1
+	}
+	if _, ok := options[pluralOther]; !ok {
+		return nil, offset, errors.New(`option other is required`)
+	}
+	return options, offset, nil
+}
+
+func (m *messageFormat) plural(value string, style []rune) (string, error) {
+	options, offset, err := m.options(style)
+	if err != nil {
+		return ``, err
+	}
+	n, err := decimal.NewFromString(strings.TrimSpace(value))
+	if err != nil {
+		return ``, err
+	}
+	msg, ok := options[`=`+n.String()]
+	n = n.Sub(offset)
+	if !ok {
+		if msg, ok = options[PluralCategory(m.lang, n)]; !ok {
+			msg = options[pluralOther]
+		}
+	}
+	hash, err := FormatNumber(m.lang, n.String(), ``)
+	if err != nil {
+		return ``, err
+	}
+	return m.format(msg, hash)
+}
@@ -251,4 +251,18 @@ var (
 		"stop_time" int NOT NULL DEFAULT '0'
 		);
 		`
+
+	migrationBlockParams = `DO $$
+		DECLARE
+			eco record;
+		BEGIN
+			FOR eco IN SELECT id FROM "system_states" LOOP
+				EXECUTE format('ALTER TABLE %I ADD COLUMN IF NOT EXISTS "params" text NOT NULL DEFAULT ''''', eco.id || '_blocks');
+				EXECUTE format('UPDATE %I SET "columns" = "columns" || ''{"params": "ContractConditions(\"MainCondition\")"}'' WHERE "name" = ''blocks''', eco.id || '_tables');
+				IF to_regclass(quote_ident(eco.id || '_vde_blocks')) IS NOT NULL THEN
+					EXECUTE format('ALTER TABLE %I ADD COLUMN IF NOT EXISTS "params" text NOT NULL DEFAULT ''''', eco.id || '_vde_blocks');
+					EXECUTE format('UPDATE %I SET "columns" = "columns" || ''{"params": "ContractConditions(\"MainCondition\")"}'' WHERE "name" = ''blocks''', eco.id || '_vde_tables');
+				END IF;
+			END LOOP;
+		END $$;`
 )
@@ -55,6 +55,7 @@ var (
 		  "id" bigint  NOT NULL DEFAULT '0',
 		  "name" character varying(255) UNIQUE NOT NULL DEFAULT '',
 		  "value" text NOT NULL DEFAULT '',
+		  "params" text NOT NULL DEFAULT '',
 		  "conditions" text NOT NULL DEFAULT ''
 	  );
 	  ALTER TABLE ONLY "%[1]d_vde_blocks" ADD CONSTRAINT "%[1]d_vde_blocks_pkey" PRIMARY KEY (id);
@@ -153,6 +154,7 @@ var (
 				"new_column": "ContractConditions(\"MainCondition\")"}',
 			  '{"name": "ContractConditions(\"MainCondition\")",
 		  "value": "ContractConditions(\"MainCondition\")",
+		  "params": "ContractConditions(\"MainCondition\")",
 		  "conditions": "ContractConditions(\"MainCondition\")"
 			  }', 'ContractAccess("EditTable")'),
 			  ('6', 'signatures', 
@@ -438,6 +440,7 @@ var (
 		  data {
 			  Name       string
 			  Value      string
+			  Params     string "optional"
 			  Conditions string
 		  }
 		  conditions {
@@ -449,13 +452,14 @@ var (
 			  }
 		  }
 		  action {
-			  $result = DBInsert("blocks", "name,value,conditions", $Name, $Value, $Conditions )
+			  $result = DBInsert("blocks", "name,value,params,conditions", $Name, $Value, $Params, $Conditions )
 		  }
 	  }', 'ContractConditions("MainCondition")'),
 	  ('14','contract EditBlock {
 		  data {
 			  Id         int
 			  Value      string
+			  Params     string "optional"
 			  Conditions string
 		  }
 		  conditions {
@@ -463,7 +467,7 @@ var (
 			  ValidateCondition($Conditions, $ecosystem_id)
 		  }
 		  action {
-			  DBUpdate("blocks", $Id, "value,conditions", $Value, $Conditions)
+			  DBUpdate("blocks", $Id, "value,params,conditions", $Value, $Params, $Conditions)
 		  }
 	  }', 'ContractConditions("MainCondition")'),
 	  ('15','contract NewTable {
@@ -726,6 +730,7 @@ var (
 			"id" bigint  NOT NULL DEFAULT '0',
 			"name" character varying(255) UNIQUE NOT NULL DEFAULT '',
 			"value" text NOT NULL DEFAULT '',
+			"params" text NOT NULL DEFAULT '',
 			"conditions" text NOT NULL DEFAULT ''
 		);
 		ALTER TABLE ONLY "%[1]d_blocks" ADD CONSTRAINT "%[1]d_blocks_pkey" PRIMARY KEY (id);
@@ -843,6 +848,7 @@ var (
 				  "new_column": "ContractConditions(\"MainCondition\")"}',
 				'{"name": "ContractConditions(\"MainCondition\")",
 			"value": "ContractConditions(\"MainCondition\")",
+			"params": "ContractConditions(\"MainCondition\")",
 			"conditions": "ContractConditions(\"MainCondition\")"
 				}', 'ContractAccess("@1EditTable")'),
 				('8', 'signatures', 
@@ -1442,6 +1448,7 @@ var (
 		data {
 			Name       string
 			Value      string
+			Params     string "optional"
 			Conditions string
 		}
 		conditions {
@@ -1455,13 +1462,14 @@ var (
 			}
 		}
 		action {
-			DBInsert("blocks", "name,value,conditions", $Name, $Value, $Conditions )
+			DBInsert("blocks", "name,value,params,conditions", $Name, $Value, $Params, $Conditions )
 		}
 	}', '%[1]d','ContractConditions("MainCondition")'),
 	('21','contract EditBlock {
 		data {
 			Id         int
 			Value      string
+			Params     string "optional"
 			Conditions string
 		}
 		conditions {
@@ -1469,7 +1477,7 @@ var (
 			ValidateCondition($Conditions, $ecosystem_id)
 		}
 		action {
-			DBUpdate("blocks", $Id, "value,conditions", $Value, $Conditions)
+			DBUpdate("blocks", $Id, "value,params,conditions", $Value, $Params, $Conditions)
 		}
 	}', '%[1]d','ContractConditions("MainCondition")'),
 	('22','contract NewTable {
@@ -35,6 +35,9 @@ var migrations = []*migration{
 
 	// Initial schema
 	&migration{"0.1.6b9", migrationInitialSchema},
+
+	// Declared parameters of blocks
+	&migration{"0.1.6b12", migrationBlockParams},
 }
 
 type migration struct {
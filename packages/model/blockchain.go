@@ -50,6 +50,11 @@ func (b *Block) Get(blockID int64) (bool, error) {
 	return isFound(DBConn.Where("id = ?", blockID).First(b))
 }
 
+// GetTx is retrieving model from database in the transaction
+func (b *Block) GetTx(transaction *DbTransaction, blockID int64) (bool, error) {
+	return isFound(GetDB(transaction).Where("id = ?", blockID).First(b))
+}
+
 // GetMaxBlock returns last block existence
 func (b *Block) GetMaxBlock() (bool, error) {
 	return isFound(DBConn.Last(b))
@@ -94,3 +99,56 @@ func (b *Block) GetBlocksFrom(startFromID int64, ordering string) ([]Block, erro
 func (b *Block) DeleteById(transaction *DbTransaction, id int64) error {
 	return GetDB(transaction).Where("id = ?", id).Delete(Block{}).Error
 }
+
+// IsPruned returns true if the body of the block has been removed by the pruning, the header is kept in other columns
+func (b *Block) IsPruned() bool {
+	return len(b.Data) == 0
+}
+
+// PruneBlocks is removing the bodies of blocks after fromBlockID till toBlockID, the first block is always kept
+func PruneBlocks(transaction *DbTransaction, fromBlockID, toBlockID int64) (int64, error) {
+	if fromBlockID < 1 {
+		fromBlockID = 1
+	}
+	query := GetDB(transaction).Exec(`UPDATE block_chain SET data = '' WHERE id > ? AND id <= ? AND data <> ''`,
+		fromBlockID, toBlockID)
+	return query.RowsAffected, query.Error
+}
+
+// GetSnapshotBlockID returns the block of the restored snapshot of the state, zero means the node has
+// all blocks. Only the first block is kept before the block of the snapshot, and there isn't rollback data for them.
+func GetSnapshotBlockID() (int64, error) {
+	block := &Block{}
+	found, err := isFound(DBConn.Select("id").Where("id > 1").Order("id asc").First(block))
+	if err != nil || !found || block.ID == 2 {
+		return 0, err
+	}
+	return block.ID, nil
+}
+
+// IsChainComplete returns true if the node has the bodies of all blocks, so it can send any block to other nodes.
+// The blocks before the restored snapshot are missing and the bodies of old blocks are removed by the pruning.
+func IsChainComplete() (bool, error) {
+	var first struct {
+		ID   int64
+		Size int64
+	}
+	found, err := isFound(DBConn.Raw(`SELECT id, length(data) AS size FROM block_chain WHERE id > 1 ORDER BY id LIMIT 1`).Scan(&first))
+	if err != nil || !found {
+		return true, err
+	}
+	return first.ID == 2 && first.Size > 0, nil
+}
+
+// GetPrunedBlockID returns the last block whose body has been pruned, zero means there are no pruned blocks.
+// Blocks are pruned in the order of ids, so the block after the first one shows if there are pruned blocks.
+func GetPrunedBlockID() (int64, error) {
+	first := &Block{}
+	found, err := isFound(DBConn.Where("id > 1").Order("id asc").First(first))
+	if err != nil || !found || !first.IsPruned() {
+		return 0, err
+	}
+	last := &Block{}
+	_, err = isFound(DBConn.Select("id").Where("data = ''").Order("id desc").First(last))
+	return last.ID, err
+}
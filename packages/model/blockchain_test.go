@@ -0,0 +1,108 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+// initBlocks creates the blocks with the ids in the memory database
+func initBlocks(t *testing.T, ids ...int64) {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	DBConn = db
+	if err = DBConn.CreateTable(&Block{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range ids {
+		block := &Block{ID: id, Hash: []byte{byte(id)}, RollbacksHash: []byte{}, Data: []byte{1, byte(id)}}
+		if err = block.Create(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPruneBlocks(t *testing.T) {
+	initBlocks(t, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+	defer DBConn.Close()
+
+	check := func(pruned int64, complete bool) {
+		t.Helper()
+		prunedBlockID, err := GetPrunedBlockID()
+		if err != nil || prunedBlockID != pruned {
+			t.Errorf(`wrong pruned block %d %v`, prunedBlockID, err)
+		}
+		isComplete, err := IsChainComplete()
+		if err != nil || isComplete != complete {
+			t.Errorf(`wrong complete chain %v %v`, isComplete, err)
+		}
+	}
+	check(0, true)
+
+	for _, item := range []struct {
+		from, to, rows, pruned int64
+	}{{0, 5, 4, 5}, {5, 8, 3, 8}, {0, 8, 0, 8}} {
+		rows, err := PruneBlocks(nil, item.from, item.to)
+		if err != nil || rows != item.rows {
+			t.Errorf(`wrong pruned rows %d %v`, rows, err)
+		}
+		check(item.pruned, false)
+	}
+	for _, id := range []int64{1, 9, 10} {
+		block := &Block{}
+		if found, err := block.Get(id); err != nil || !found || block.IsPruned() {
+			t.Errorf(`block %d has been pruned`, id)
+		}
+	}
+	if snapshotBlockID, err := GetSnapshotBlockID(); err != nil || snapshotBlockID != 0 {
+		t.Errorf(`wrong snapshot block %d %v`, snapshotBlockID, err)
+	}
+}
+
+func TestSnapshotBlock(t *testing.T) {
+	initBlocks(t, 1)
+	defer DBConn.Close()
+
+	if snapshotBlockID, err := GetSnapshotBlockID(); err != nil || snapshotBlockID != 0 {
+		t.Errorf(`wrong snapshot block %d %v`, snapshotBlockID, err)
+	}
+	if complete, err := IsChainComplete(); err != nil || !complete {
+		t.Errorf(`wrong complete chain %v %v`, complete, err)
+	}
+	for _, id := range []int64{7, 8} {
+		if err := (&Block{ID: id, Hash: []byte{}, RollbacksHash: []byte{}, Data: []byte{1}}).Create(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if snapshotBlockID, err := GetSnapshotBlockID(); err != nil || snapshotBlockID != 7 {
+		t.Errorf(`wrong snapshot block %d %v`, snapshotBlockID, err)
+	}
+	if complete, err := IsChainComplete(); err != nil || complete {
+		t.Errorf(`wrong complete chain %v %v`, complete, err)
+	}
+}
@@ -23,6 +23,8 @@
 package model
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"strings"
@@ -104,6 +106,59 @@ func StartTransaction() (*DbTransaction, error) {
 	}, nil
 }
 
+// contextDB runs the queries with the context, so the running query is cancelled when the context is done
+type contextDB struct {
+	ctx context.Context
+	db  *sql.DB
+}
+
+func (c *contextDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.db.ExecContext(c.ctx, query, args...)
+}
+
+func (c *contextDB) Prepare(query string) (*sql.Stmt, error) {
+	return c.db.PrepareContext(c.ctx, query)
+}
+
+func (c *contextDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.db.QueryContext(c.ctx, query, args...)
+}
+
+func (c *contextDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return c.db.QueryRowContext(c.ctx, query, args...)
+}
+
+// ContextTransaction returns the connection whose queries are cancelled when the context is done.
+// It is passed to the functions instead of the transaction, it can't be committed or rolled back.
+func ContextTransaction(ctx context.Context) *DbTransaction {
+	if ctx == nil || DBConn == nil || DBConn.DB() == nil {
+		return nil
+	}
+	conn, err := gorm.Open("postgres", &contextDB{ctx: ctx, db: DBConn.DB()})
+	if err != nil {
+		return nil
+	}
+	if *conf.LogSQL {
+		conn.LogMode(true)
+		conn.SetLogger(log.New())
+	}
+	return &DbTransaction{conn: conn}
+}
+
+// StartReadOnlyTransaction is beginning read-only transaction with the snapshot of the database
+func StartReadOnlyTransaction() (*DbTransaction, error) {
+	tr, err := StartTransaction()
+	if err != nil {
+		return nil, err
+	}
+	if err = tr.conn.Exec(`SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY`).Error; err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("setting read only transaction")
+		tr.Rollback()
+		return nil, err
+	}
+	return tr, nil
+}
+
 // Rollback is transaction rollback
 func (tr *DbTransaction) Rollback() {
 	tr.conn.Rollback()
@@ -233,6 +288,33 @@ func CreateIndex(transaction *DbTransaction, indexName, tableName, onColumn stri
 	return GetDB(transaction).Exec(`CREATE INDEX "` + indexName + `_index" ON "` + tableName + `" (` + onColumn + `)`).Error
 }
 
+// searchVector returns the text search vector of the column
+func searchVector(column string) string {
+	return `to_tsvector('simple', coalesce("` + column + `"::text, ''))`
+}
+
+// CreateSearchIndex is creating index for the full-text search by the table column
+func CreateSearchIndex(transaction *DbTransaction, tableName, column string) error {
+	return GetDB(transaction).Exec(`CREATE INDEX IF NOT EXISTS "` + tableName + `_` + column + `_search_index" ON "` +
+		tableName + `" USING GIN (` + searchVector(column) + `)`).Error
+}
+
+// DropSearchIndex is dropping index for the full-text search by the table column
+func DropSearchIndex(transaction *DbTransaction, tableName, column string) error {
+	return GetDB(transaction).Exec(`DROP INDEX IF EXISTS "` + tableName + `_` + column + `_search_index"`).Error
+}
+
+// SearchWhere returns the condition and its arguments for the full-text search by the columns
+func SearchWhere(columns []string, query string) (string, []interface{}) {
+	list := make([]string, len(columns))
+	args := make([]interface{}, len(columns))
+	for i, column := range columns {
+		list[i] = searchVector(column) + ` @@ plainto_tsquery('simple', ?)`
+		args[i] = query
+	}
+	return `(` + strings.Join(list, ` OR `) + `)`, args
+}
+
 // GetColumnDataTypeCharMaxLength is returns max length of table column
 func GetColumnDataTypeCharMaxLength(tableName, columnName string) (map[string]string, error) {
 	return GetOneRow(`select data_type,character_maximum_length from
@@ -240,6 +322,12 @@ func GetColumnDataTypeCharMaxLength(tableName, columnName string) (map[string]st
 		tableName, columnName).String()
 }
 
+// GetColumnNames returns the names of the table columns in the order of their positions
+func GetColumnNames(tableName string) ([]string, error) {
+	return GetList(`SELECT column_name FROM information_schema.columns WHERE table_name=? ORDER BY ordinal_position`,
+		tableName).String()
+}
+
 // GetColumnType is returns type of column
 func GetColumnType(tblname, column string) (itype string, err error) {
 	coltype, err := GetColumnDataTypeCharMaxLength(tblname, column)
@@ -44,6 +44,11 @@ func (ib *InfoBlock) Get() (bool, error) {
 	return isFound(DBConn.Last(ib))
 }
 
+// GetTx is retrieving model from database in the transaction
+func (ib *InfoBlock) GetTx(transaction *DbTransaction) (bool, error) {
+	return isFound(GetDB(transaction).Last(ib))
+}
+
 // Update is update model
 func (ib *InfoBlock) Update(transaction *DbTransaction) error {
 	return GetDB(transaction).Model(&InfoBlock{}).Updates(ib).Error
@@ -38,9 +38,10 @@ const (
 	Update = "update"
 	Delete = "delete"
 
-	Set  = "set"
-	From = "from"
-	Into = "into"
+	Set     = "set"
+	From    = "from"
+	Into    = "into"
+	GroupBy = " group by "
 
 	Quote  = `"`
 	Lparen = "("
@@ -52,10 +53,11 @@ const (
 	InsertCost = 1
 	DeleteCost = 1
 
-	SelectRowCoeff = 0.0001
-	InsertRowCoeff = 0.0001
-	DeleteRowCoeff = 0.0001
-	UpdateRowCoeff = 0.0001
+	SelectRowCoeff  = 0.0001
+	GroupByRowCoeff = 0.0002
+	InsertRowCoeff  = 0.0001
+	DeleteRowCoeff  = 0.0001
+	UpdateRowCoeff  = 0.0001
 )
 
 var FromStatementMissingError = errors.New("FROM statement missing")
@@ -110,6 +112,10 @@ func (s SelectQueryType) GetTableName() (string, error) {
 }
 
 func (s SelectQueryType) CalculateCost(rowCount int64) int64 {
+	// grouping has to read and sort all rows of the table
+	if strings.Contains(string(s), GroupBy) {
+		return SelectCost + int64(GroupByRowCoeff*float64(rowCount))
+	}
 	return SelectCost + int64(SelectRowCoeff*float64(rowCount))
 }
 
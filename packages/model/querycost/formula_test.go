@@ -26,6 +26,7 @@ import (
 	"github.com/GACHAIN/go-gachain/packages/model"
 
 	"errors"
+	"strings"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"testing"
@@ -130,6 +131,14 @@ func (s *QueryCostByFormulaTestSuite) TestQueryCostSelect() {
 	assert.Equal(s.T(), cost, SelectQueryType("").CalculateCost(tableRowCount))
 }
 
+func (s *QueryCostByFormulaTestSuite) TestQueryCostSelectGroupBy() {
+	query := `SELECT "a", sum("b") AS "sum_b" FROM small GROUP BY "a"`
+	cost, err := s.queryCoster.QueryCost(nil, query)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), cost, SelectQueryType(strings.ToLower(query)).CalculateCost(tableRowCount))
+	assert.True(s.T(), cost > SelectQueryType("").CalculateCost(tableRowCount))
+}
+
 func (s *QueryCostByFormulaTestSuite) TestQueryCostUpdate() {
 	cost, err := s.queryCoster.QueryCost(nil, "UPDATE small SET a = ?", 3)
 	assert.Nil(s.T(), err)
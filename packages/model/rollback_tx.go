@@ -48,12 +48,22 @@ func (rt *RollbackTx) GetBlockRollbackTransactions(dbTransaction *DbTransaction,
 	return rollbackTransactions, err
 }
 
-func (rt *RollbackTx) GetRollbackTxsByTableIDAndTableName(tableID, tableName string, limit int) (*[]RollbackTx, error) {
-	rollbackTx := new([]RollbackTx)
-	if err := DBConn.Where("table_id = ? AND table_name = ?", tableID, tableName).Limit(limit).Find(rollbackTx).Error; err != nil {
-		return nil, err
+// DeleteRollbacksTill is deleting rollback records of blocks till the block inclusive
+func DeleteRollbacksTill(transaction *DbTransaction, blockID int64) (int64, error) {
+	query := GetDB(transaction).Exec(`DELETE FROM rollback_tx WHERE block_id <= ?`, blockID)
+	return query.RowsAffected, query.Error
+}
+
+// GetRollbacksAfterBlock returns rollback records of the table (or of the row if tableID is not empty)
+// which have been created after the block, the latest records go first
+func (rt *RollbackTx) GetRollbacksAfterBlock(transaction *DbTransaction, tableName, tableID string, blockID int64) ([]RollbackTx, error) {
+	var rollbackTxs []RollbackTx
+	query := GetDB(transaction).Where("table_name = ? AND block_id > ?", tableName, blockID)
+	if len(tableID) > 0 {
+		query = query.Where("table_id = ?", tableID)
 	}
-	return rollbackTx, nil
+	err := query.Order("id desc").Find(&rollbackTxs).Error
+	return rollbackTxs, err
 }
 
 // DeleteByHash is deleting rollbackTx by hash
@@ -75,3 +85,34 @@ func (rt *RollbackTx) Create(transaction *DbTransaction) error {
 func (rt *RollbackTx) Get(dbTransaction *DbTransaction, transactionHash []byte, tableName string) (bool, error) {
 	return isFound(GetDB(dbTransaction).Where("tx_hash = ? AND table_name = ?", transactionHash, tableName).First(rt))
 }
+
+// RowHistory is the change of the table row with the block and transaction info
+type RowHistory struct {
+	ID        int64
+	BlockID   int64
+	BlockTime int64
+	TxHash    []byte
+	KeyID     int64
+	Data      string
+}
+
+// GetRowHistory returns changes of the row within the block range, the latest changes go first.
+// Zero toBlock means the last block.
+func GetRowHistory(tableName, tableID string, fromBlock, toBlock int64, offset, limit int) ([]RowHistory, error) {
+	var history []RowHistory
+	query := `SELECT r.id, r.block_id, coalesce(b.time, 0) as block_time, r.tx_hash,
+		coalesce(ts.wallet_id, 0) as key_id, r.data
+		FROM rollback_tx r
+		LEFT JOIN block_chain b ON b.id = r.block_id
+		LEFT JOIN transactions_status ts ON ts.hash = r.tx_hash
+		WHERE r.table_name = ? AND r.table_id = ? AND r.block_id >= ?`
+	args := []interface{}{tableName, tableID, fromBlock}
+	if toBlock > 0 {
+		query += ` AND r.block_id <= ?`
+		args = append(args, toBlock)
+	}
+	query += ` ORDER BY r.id desc LIMIT ? OFFSET ?`
+	args = append(args, limit, offset)
+	err := DBConn.Raw(query, args...).Scan(&history).Error
+	return history, err
+}
@@ -0,0 +1,171 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TableColumn is the structure of the column of the table
+type TableColumn struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	NotNull bool   `json:"not_null,omitempty"`
+	Default string `json:"default,omitempty"`
+}
+
+// Definition returns the type of the column with its constraints for CREATE and ALTER TABLE
+func (c *TableColumn) Definition() string {
+	def := c.Type
+	if c.NotNull {
+		def += ` NOT NULL`
+	}
+	if len(c.Default) > 0 {
+		def += ` DEFAULT ` + c.Default
+	}
+	return def
+}
+
+func regclass(tableName string) string {
+	return `"` + tableName + `"`
+}
+
+func getStrings(transaction *DbTransaction, query string, args ...interface{}) ([]string, error) {
+	rows, err := GetDB(transaction).Raw(query, args...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []string
+	for rows.Next() {
+		var value string
+		if err = rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+	}
+	return list, rows.Err()
+}
+
+// GetSchemaTables returns the names of all tables of the database in the alphabetical order
+func GetSchemaTables(transaction *DbTransaction) ([]string, error) {
+	return getStrings(transaction, `SELECT tablename FROM pg_tables WHERE schemaname = current_schema() ORDER BY tablename`)
+}
+
+// GetTableColumns returns the structure of the table columns in the order of their positions
+func GetTableColumns(transaction *DbTransaction, tableName string) ([]TableColumn, error) {
+	rows, err := GetDB(transaction).Raw(`SELECT a.attname, format_type(a.atttypid, a.atttypmod), a.attnotnull,
+		coalesce(pg_get_expr(d.adbin, d.adrelid), '')
+		FROM pg_attribute a LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE a.attrelid = ?::regclass AND a.attnum > 0 AND NOT a.attisdropped ORDER BY a.attnum`,
+		regclass(tableName)).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var columns []TableColumn
+	for rows.Next() {
+		var column TableColumn
+		if err = rows.Scan(&column.Name, &column.Type, &column.NotNull, &column.Default); err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+// GetPrimaryKey returns the columns of the primary key of the table
+func GetPrimaryKey(transaction *DbTransaction, tableName string) ([]string, error) {
+	return getStrings(transaction, `SELECT a.attname FROM pg_index i
+		JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+		WHERE i.indrelid = ?::regclass AND i.indisprimary ORDER BY array_position(i.indkey::int2[], a.attnum)`,
+		regclass(tableName))
+}
+
+// GetTableIndexes returns the definitions of the table indexes except the primary key
+func GetTableIndexes(transaction *DbTransaction, tableName string) ([]string, error) {
+	return getStrings(transaction, `SELECT pg_get_indexdef(indexrelid) FROM pg_index
+		WHERE indrelid = ?::regclass AND NOT indisprimary ORDER BY indexrelid::regclass::text`, regclass(tableName))
+}
+
+// CreateTableWithColumns is creating the table with the columns and the primary key
+func CreateTableWithColumns(transaction *DbTransaction, tableName string, columns []TableColumn, primary []string) error {
+	defs := make([]string, 0, len(columns)+1)
+	for _, column := range columns {
+		defs = append(defs, `"`+column.Name+`" `+column.Definition())
+	}
+	if len(primary) > 0 {
+		defs = append(defs, `PRIMARY KEY ("`+strings.Join(primary, `", "`)+`")`)
+	}
+	return GetDB(transaction).Exec(`CREATE TABLE "` + tableName + `" (` + strings.Join(defs, `, `) + `)`).Error
+}
+
+// CreateTableIndex is creating the index on the columns of the table
+func CreateTableIndex(transaction *DbTransaction, indexName, tableName string, unique bool, columns []string) error {
+	query := `CREATE INDEX "`
+	if unique {
+		query = `CREATE UNIQUE INDEX "`
+	}
+	return GetDB(transaction).Exec(query + indexName + `" ON "` + tableName + `" ("` + strings.Join(columns, `", "`) + `")`).Error
+}
+
+// ClearTable is deleting all rows of the table
+func ClearTable(transaction *DbTransaction, tableName string) error {
+	return GetDB(transaction).Exec(`DELETE FROM "` + tableName + `"`).Error
+}
+
+// ForEachRowJSON calls the function for the rows of the table which are encoded as json,
+// the rows are ordered by the columns or by their json if the columns are empty
+func ForEachRowJSON(transaction *DbTransaction, tableName string, order []string, where string,
+	fn func([]byte) error, args ...interface{}) error {
+	orderBy := `row_to_json(t)::text`
+	if len(order) > 0 {
+		orderBy = `"` + strings.Join(order, `", "`) + `"`
+	}
+	if len(where) > 0 {
+		where = ` WHERE ` + where
+	}
+	rows, err := GetDB(transaction).Raw(fmt.Sprintf(`SELECT row_to_json(t) FROM "%s" t%s ORDER BY %s`,
+		tableName, where, orderBy), args...).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var row []byte
+		if err = rows.Scan(&row); err != nil {
+			return err
+		}
+		if err = fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// InsertRowJSON is inserting the row which is encoded as json by ForEachRowJSON
+func InsertRowJSON(transaction *DbTransaction, tableName string, row []byte) error {
+	return GetDB(transaction).Exec(`INSERT INTO "`+tableName+`" SELECT * FROM json_populate_record(NULL::"`+
+		tableName+`", ?)`, string(row)).Error
+}
@@ -0,0 +1,279 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package network
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/GACHAIN/go-gachain/packages/conf"
+	"github.com/GACHAIN/go-gachain/packages/config/syspar"
+	"github.com/GACHAIN/go-gachain/packages/consts"
+	"github.com/GACHAIN/go-gachain/packages/converter"
+	"github.com/GACHAIN/go-gachain/packages/crypto"
+	"github.com/GACHAIN/go-gachain/packages/model"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/vmihailenco/msgpack.v2"
+)
+
+// maxHandshakeSize is the max size of the handshake message
+const maxHandshakeSize = 65536
+
+var (
+	// ErrVersion is returned if the remote node uses the old protocol
+	ErrVersion = errors.New("incompatible protocol version")
+	// ErrNetwork is returned if the remote node belongs to another network
+	ErrNetwork = errors.New("foreign network")
+	// ErrSignature is returned if the handshake has the wrong signature
+	ErrSignature = errors.New("wrong handshake signature")
+	// ErrUnsupported is returned if the remote node doesn't handle the request type
+	ErrUnsupported = errors.New("request type is not supported by the node")
+	// ErrUnknownNode is returned if the key of the remote node is not listed in full_nodes
+	ErrUnknownNode = errors.New("unknown node")
+	// ErrPlaintext is returned if the remote node doesn't support encryption
+	ErrPlaintext = errors.New("plaintext connections are not allowed")
+)
+
+// SupportedTypes are the request types which are handled by this node
+var SupportedTypes = []uint16{1, 2, 4, 7, 10, 11, 13, 14, 15}
+
+// legacyTypes are the request types of nodes which don't send the handshake
+var legacyTypes = []uint16{1, 2, 4, 7, 10}
+
+// Handshake is the first message of the connection between nodes
+type Handshake struct {
+	Version   uint16
+	NetworkID []byte
+	KeyID     int64
+	PublicKey []byte
+	Types     []uint16
+	Nonce     []byte
+	// EphemeralKey is the public key for the key exchange of the encrypted connection
+	EphemeralKey []byte
+	Sign         []byte
+
+	ephemeral []byte // the private part of EphemeralKey
+}
+
+var (
+	networkID []byte
+	mutex     sync.Mutex
+	hostTypes = make(map[string][]uint16)
+)
+
+// NetworkID returns the hash of the first block which identifies the network
+func NetworkID() []byte {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if len(networkID) == 0 && model.DBConn != nil {
+		block := &model.Block{}
+		found, err := block.Get(1)
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting first block")
+		} else if found {
+			networkID = block.Hash
+		}
+	}
+	return networkID
+}
+
+// forSign returns the signed data, the answer also signs the nonce of the request
+func (h *Handshake) forSign(peerNonce []byte) string {
+	return fmt.Sprintf("%d,%x,%d,%x,%v,%x,%x,%x", h.Version, h.NetworkID, h.KeyID, h.PublicKey,
+		h.Types, h.Nonce, h.EphemeralKey, peerNonce)
+}
+
+// NewHandshake returns the signed handshake of this node. peerNonce is the nonce
+// of the received handshake if the new one is the answer.
+func NewHandshake(privateKey string, publicKey, peerNonce []byte) (*Handshake, error) {
+	h := &Handshake{
+		Version:   consts.PROTOCOL_VERSION,
+		NetworkID: NetworkID(),
+		PublicKey: publicKey,
+		Types:     SupportedTypes,
+		Nonce:     make([]byte, 16),
+	}
+	// only the node which is listed in full_nodes sends its KeyID, other nodes are anonymous
+	if node := syspar.GetNode(conf.Config.KeyID); node != nil && bytes.Equal(node.Public, publicKey) {
+		h.KeyID = conf.Config.KeyID
+	}
+	if _, err := crand.Read(h.Nonce); err != nil {
+		log.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("generating handshake nonce")
+		return nil, err
+	}
+	var err error
+	if h.ephemeral, h.EphemeralKey, err = ephemeralKeys(); err != nil {
+		log.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("generating ephemeral key")
+		return nil, err
+	}
+	h.Sign, err = crypto.Sign(privateKey, h.forSign(peerNonce))
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("signing handshake")
+		return nil, err
+	}
+	return h, nil
+}
+
+// Check verifies the received handshake. peerNonce is the nonce of our handshake
+// if the received one is the answer. The non-zero KeyID must be listed in full_nodes with
+// the public key of the handshake. The keys of the encrypted connection are derived from
+// the nonces of both handshakes, so the replayed handshake can't be used for the connection.
+func (h *Handshake) Check(peerNonce []byte) error {
+	if h.Version < consts.MIN_PROTOCOL_VERSION || h.Version == 0 {
+		return ErrVersion
+	}
+	if id := NetworkID(); len(id) > 0 && !bytes.Equal(id, h.NetworkID) {
+		return ErrNetwork
+	}
+	if h.KeyID != 0 {
+		if err := h.CheckNode(); err != nil {
+			return err
+		}
+	}
+	if ok, err := crypto.CheckSign(h.PublicKey, h.forSign(peerNonce), h.Sign); !ok || err != nil {
+		return ErrSignature
+	}
+	return nil
+}
+
+// CheckNode verifies that the remote node is listed in full_nodes with the same public key
+func (h *Handshake) CheckNode() error {
+	node := syspar.GetNode(h.KeyID)
+	if node == nil || !bytes.Equal(node.Public, h.PublicKey) {
+		return ErrUnknownNode
+	}
+	return nil
+}
+
+// CheckHost verifies that the answer of the host which is listed in full_nodes is signed
+// by the key of this full node. The hosts which are not full nodes are not authenticated.
+func (h *Handshake) CheckHost(host string) error {
+	keyID, node := syspar.GetNodeByHost(host)
+	if node != nil && (h.KeyID != keyID || !bytes.Equal(node.Public, h.PublicKey)) {
+		return ErrUnknownNode
+	}
+	return nil
+}
+
+// Encrypted returns true if the remote node supports the encrypted connection
+func (h *Handshake) Encrypted() bool {
+	return len(h.EphemeralKey) > 0
+}
+
+// AllowPlaintext returns true if plaintext connections with old nodes are allowed.
+// It doesn't affect the authentication of nodes.
+func AllowPlaintext() bool {
+	return conf.Config.LegacyPlaintext
+}
+
+// Supports returns true if the remote node handles the request type
+func (h *Handshake) Supports(reqType uint16) bool {
+	return hasType(h.Types, reqType)
+}
+
+func hasType(types []uint16, reqType uint16) bool {
+	for _, t := range types {
+		if t == reqType {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSupported returns true if this node handles the request type
+func IsSupported(reqType uint16) bool {
+	return hasType(SupportedTypes, reqType)
+}
+
+// WriteHandshake writes the handshake message prefixed with its type and size
+func WriteHandshake(w io.Writer, h *Handshake) error {
+	data, err := msgpack.Marshal(h)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.MarshallingError, "error": err}).Error("marshalling handshake")
+		return err
+	}
+	data = append(converter.DecToBin(len(data), 4), data...)
+	_, err = w.Write(append(converter.DecToBin(consts.DATA_TYPE_HANDSHAKE, 2), data...))
+	return err
+}
+
+// ReadHandshake reads the handshake message after its type
+func ReadHandshake(r io.Reader) (*Handshake, error) {
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	size := converter.BinToDec(buf)
+	if size <= 0 || size > maxHandshakeSize {
+		log.WithFields(log.Fields{"type": consts.ProtocolError, "size": size}).Error("wrong handshake size")
+		return nil, fmt.Errorf("wrong handshake size %d", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	h := &Handshake{}
+	if err := msgpack.Unmarshal(data, h); err != nil {
+		log.WithFields(log.Fields{"type": consts.UnmarshallingError, "error": err}).Error("unmarshalling handshake")
+		return nil, err
+	}
+	return h, nil
+}
+
+// ReadAnswer reads the type and the handshake which the remote node has answered
+func ReadAnswer(r io.Reader) (*Handshake, error) {
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	if reqType := converter.BinToDec(buf); reqType != consts.DATA_TYPE_HANDSHAKE {
+		log.WithFields(log.Fields{"type": consts.ProtocolError, "request_type": reqType}).Error("wrong handshake answer")
+		return nil, fmt.Errorf("wrong handshake answer %d", reqType)
+	}
+	return ReadHandshake(r)
+}
+
+// SetHostTypes remembers the request types which are supported by the host,
+// nil types mean the host doesn't send the handshake
+func SetHostTypes(host string, types []uint16) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if types == nil {
+		types = legacyTypes
+	}
+	hostTypes[host] = types
+}
+
+// HostSupports returns true if the host handles the request type or the host is unknown yet
+func HostSupports(host string, reqType uint16) bool {
+	mutex.Lock()
+	defer mutex.Unlock()
+	types, ok := hostTypes[host]
+	return !ok || hasType(types, reqType)
+}
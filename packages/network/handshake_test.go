@@ -0,0 +1,116 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package network
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/GACHAIN/go-gachain/packages/consts"
+	"github.com/GACHAIN/go-gachain/packages/converter"
+	"github.com/GACHAIN/go-gachain/packages/crypto"
+)
+
+func TestHandshake(t *testing.T) {
+	networkID = []byte(`first block hash`)
+	defer func() { networkID = nil }()
+
+	priv, pub, err := crypto.GenHexKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, _ := hex.DecodeString(pub)
+	request, err := NewHandshake(priv, public, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream := &bytes.Buffer{}
+	if err = WriteHandshake(stream, request); err != nil {
+		t.Fatal(err)
+	}
+	if reqType := converter.BinToDec(stream.Next(2)); reqType != consts.DATA_TYPE_HANDSHAKE {
+		t.Fatalf(`wrong request type %d`, reqType)
+	}
+	received, err := ReadHandshake(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = received.Check(nil); err != nil {
+		t.Errorf(`wrong check of request: %s`, err)
+	}
+	if received.KeyID != 0 {
+		t.Errorf(`the node which is not listed in full_nodes must be anonymous`)
+	}
+	if !received.Supports(consts.DATA_TYPE_BLOCK_RANGE) || received.Supports(consts.DATA_TYPE_HANDSHAKE) {
+		t.Errorf(`wrong types %v`, received.Types)
+	}
+
+	answer, err := NewHandshake(priv, public, request.Nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Reset()
+	WriteHandshake(stream, answer)
+	if received, err = ReadAnswer(stream); err != nil {
+		t.Fatal(err)
+	}
+	if err = received.Check(request.Nonce); err != nil {
+		t.Errorf(`wrong check of answer: %s`, err)
+	}
+	// the host which is not listed in full_nodes is not authenticated
+	if err = received.CheckHost(`10.0.0.1:7078`); err != nil {
+		t.Errorf(`wrong check of host: %s`, err)
+	}
+	// the answer is not valid for another request
+	if err = received.Check(answer.Nonce); err != ErrSignature {
+		t.Errorf(`replayed answer must be rejected: %v`, err)
+	}
+
+	// KeyID of the node which is not listed in full_nodes
+	received.KeyID = 5
+	if err = received.Check(request.Nonce); err != ErrUnknownNode {
+		t.Errorf(`unknown KeyID must be rejected: %v`, err)
+	}
+	received.KeyID = 0
+
+	received.Version = 0
+	if err = received.Check(request.Nonce); err != ErrVersion {
+		t.Errorf(`old version must be rejected: %v`, err)
+	}
+	received.Version = consts.PROTOCOL_VERSION
+	networkID = []byte(`another network`)
+	if err = received.Check(request.Nonce); err != ErrNetwork {
+		t.Errorf(`foreign network must be rejected: %v`, err)
+	}
+
+	if _, err = ReadHandshake(bytes.NewReader(converter.DecToBin(maxHandshakeSize+1, 4))); err == nil {
+		t.Error(`large handshake must be rejected`)
+	}
+
+	SetHostTypes(`10.0.0.1:7078`, nil)
+	if HostSupports(`10.0.0.1:7078`, consts.DATA_TYPE_BLOCK_RANGE) || !HostSupports(`10.0.0.2:7078`, consts.DATA_TYPE_BLOCK_RANGE) {
+		t.Error(`wrong types of hosts`)
+	}
+}
@@ -0,0 +1,189 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package network
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/elliptic"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+
+	"github.com/GACHAIN/go-gachain/packages/converter"
+)
+
+// maxRecordSize is the max size of the plaintext in one encrypted record
+const maxRecordSize = 65536
+
+var errRecord = errors.New("wrong encrypted record")
+
+// ephemeralKeys generates the key pair for the key exchange
+func ephemeralKeys() (private, public []byte, err error) {
+	private, x, y, err := elliptic.GenerateKey(elliptic.P256(), crand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	return private, elliptic.Marshal(elliptic.P256(), x, y), nil
+}
+
+// sessionKeys returns the keys for the data which are sent by the client and by the server
+func sessionKeys(local, remote *Handshake, client bool) (clientKey, serverKey []byte, err error) {
+	curve := elliptic.P256()
+	x, y := elliptic.Unmarshal(curve, remote.EphemeralKey)
+	if x == nil || len(local.ephemeral) == 0 {
+		return nil, nil, errRecord
+	}
+	shared, _ := curve.ScalarMult(x, y, local.ephemeral)
+	clientNonce, serverNonce := local.Nonce, remote.Nonce
+	if !client {
+		clientNonce, serverNonce = serverNonce, clientNonce
+	}
+	derive := func(label string) []byte {
+		h := sha256.New()
+		h.Write(converter.FillLeft(shared.Bytes()))
+		h.Write(clientNonce)
+		h.Write(serverNonce)
+		h.Write([]byte(label))
+		return h.Sum(nil)
+	}
+	return derive(`client`), derive(`server`), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// secureStream encrypts the data by AES-GCM records which are prefixed with their sizes.
+// The nonce of the record is its sequence number, so records can't be replayed or reordered.
+type secureStream struct {
+	rw         io.ReadWriter
+	reader     cipher.AEAD
+	writer     cipher.AEAD
+	readCount  uint64
+	writeCount uint64
+	buf        []byte
+}
+
+func newSecureStream(rw io.ReadWriter, local, remote *Handshake, client bool) (*secureStream, error) {
+	clientKey, serverKey, err := sessionKeys(local, remote, client)
+	if err != nil {
+		return nil, err
+	}
+	if !client {
+		clientKey, serverKey = serverKey, clientKey
+	}
+	s := &secureStream{rw: rw}
+	if s.writer, err = newAEAD(clientKey); err != nil {
+		return nil, err
+	}
+	if s.reader, err = newAEAD(serverKey); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func recordNonce(aead cipher.AEAD, count uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], count)
+	return nonce
+}
+
+func (s *secureStream) Write(data []byte) (int, error) {
+	var written int
+	for len(data) > 0 {
+		size := len(data)
+		if size > maxRecordSize {
+			size = maxRecordSize
+		}
+		record := s.writer.Seal(nil, recordNonce(s.writer, s.writeCount), data[:size], nil)
+		s.writeCount++
+		if _, err := s.rw.Write(append(converter.DecToBin(len(record), 4), record...)); err != nil {
+			return written, err
+		}
+		written += size
+		data = data[size:]
+	}
+	return written, nil
+}
+
+func (s *secureStream) Read(data []byte) (int, error) {
+	if len(s.buf) == 0 {
+		size := make([]byte, 4)
+		if _, err := io.ReadFull(s.rw, size); err != nil {
+			return 0, err
+		}
+		recordSize := converter.BinToDec(size)
+		if recordSize <= int64(s.reader.Overhead()) || recordSize > int64(maxRecordSize+s.reader.Overhead()) {
+			return 0, errRecord
+		}
+		record := make([]byte, recordSize)
+		if _, err := io.ReadFull(s.rw, record); err != nil {
+			return 0, err
+		}
+		plain, err := s.reader.Open(record[:0], recordNonce(s.reader, s.readCount), record, nil)
+		if err != nil {
+			return 0, errRecord
+		}
+		s.readCount++
+		s.buf = plain
+	}
+	n := copy(data, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+// secureConn is the encrypted connection which keeps deadlines and addresses of net.Conn
+type secureConn struct {
+	net.Conn
+	stream *secureStream
+}
+
+func (c *secureConn) Read(data []byte) (int, error) {
+	return c.stream.Read(data)
+}
+
+func (c *secureConn) Write(data []byte) (int, error) {
+	return c.stream.Write(data)
+}
+
+// SecureConn returns the encrypted client connection after the exchange of handshakes
+func SecureConn(conn net.Conn, local, remote *Handshake) (net.Conn, error) {
+	stream, err := newSecureStream(conn, local, remote, true)
+	if err != nil {
+		return nil, err
+	}
+	return &secureConn{Conn: conn, stream: stream}, nil
+}
+
+// SecureStream returns the encrypted server stream after the exchange of handshakes
+func SecureStream(rw io.ReadWriter, local, remote *Handshake) (io.ReadWriter, error) {
+	return newSecureStream(rw, local, remote, false)
+}
@@ -0,0 +1,91 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package network
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/GACHAIN/go-gachain/packages/crypto"
+)
+
+func TestSecureConn(t *testing.T) {
+	priv, pub, err := crypto.GenHexKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, _ := hex.DecodeString(pub)
+	request, err := NewHandshake(priv, public, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	answer, err := NewHandshake(priv, public, request.Nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the received handshakes don't have private ephemeral keys
+	remoteRequest, remoteAnswer := *request, *answer
+	remoteRequest.ephemeral, remoteAnswer.ephemeral = nil, nil
+
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	client, err := SecureConn(clientConn, request, &remoteAnswer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := SecureStream(serverConn, answer, &remoteRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := bytes.Repeat([]byte(`block data `), maxRecordSize/5)
+	go func() {
+		client.Write(message)
+		client.Write([]byte(`end`))
+	}()
+	received := make([]byte, len(message)+3)
+	if _, err = io.ReadFull(server, received); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(received, append(message, `end`...)) {
+		t.Error(`wrong decrypted data`)
+	}
+
+	go server.Write([]byte(`answer`))
+	received = make([]byte, 6)
+	if _, err = io.ReadFull(client, received); err != nil || string(received) != `answer` {
+		t.Errorf(`wrong answer %q %v`, received, err)
+	}
+
+	// the record which is sealed for another session must be rejected
+	stream := &bytes.Buffer{}
+	other, _ := newSecureStream(stream, request, &remoteAnswer, false)
+	other.Write([]byte(`data`))
+	go serverConn.Write(stream.Bytes())
+	if _, err = client.Read(received); err != errRecord {
+		t.Errorf(`wrong record must be rejected: %v`, err)
+	}
+}
@@ -179,6 +179,7 @@ type Parser struct {
 	txParser         ParserInterface
 	DbTransaction    *model.DbTransaction
 	SysUpdate        bool
+	TxTables         map[string]bool // tables which have been modified by the transaction
 
 	SmartContract smart.SmartContract
 }
@@ -508,5 +509,6 @@ func (p *Parser) CallContract(flags int) (resultContract string, err error) {
 	}
 	resultContract, err = sc.CallContract(flags)
 	p.SysUpdate = sc.SysUpdate
+	p.TxTables = sc.TxTables
 	return
 }
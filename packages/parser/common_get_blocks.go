@@ -31,6 +31,8 @@ import (
 	"github.com/GACHAIN/go-gachain/packages/converter"
 	"github.com/GACHAIN/go-gachain/packages/crypto"
 	"github.com/GACHAIN/go-gachain/packages/model"
+	"github.com/GACHAIN/go-gachain/packages/peers"
+	"github.com/GACHAIN/go-gachain/packages/template"
 	"github.com/GACHAIN/go-gachain/packages/utils"
 
 	log "github.com/sirupsen/logrus"
@@ -40,6 +42,13 @@ import (
 func GetBlocks(blockID int64, host string) error {
 	rollback := syspar.GetRbBlocks1()
 
+	// the blocks before the restored snapshot can't be rolled back
+	snapshotBlockID, err := model.GetSnapshotBlockID()
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting snapshot block")
+		return utils.ErrInfo(err)
+	}
+
 	badBlocks := make(map[int64]string)
 
 	blocks := make([]*Block, 0)
@@ -50,30 +59,42 @@ func GetBlocks(blockID int64, host string) error {
 			log.WithFields(log.Fields{"type": consts.BlockIsFirst}).Error("block id is smaller than 2")
 			return utils.ErrInfo(errors.New("block_id < 2"))
 		}
+		if blockID <= snapshotBlockID {
+			log.WithFields(log.Fields{"type": consts.BlockError, "block_id": blockID, "snapshot_block_id": snapshotBlockID}).Error("fork before snapshot block")
+			return utils.ErrInfo(fmt.Errorf("block %d is before the restored snapshot", blockID))
+		}
 		// if the limit of blocks received from the node was exaggerated
 		if count > int64(rollback) {
 			log.WithFields(log.Fields{"count": count, "max_count": int64(rollback)}).Error("limit of received from the node was exaggerated")
-			return utils.ErrInfo(errors.New("count > variables[rollback_blocks]"))
+			err := errors.New("count > variables[rollback_blocks]")
+			peers.Penalize(host, peers.BadHash, err)
+			return utils.ErrInfo(err)
 		}
 
 		// load the block body from the host
 		binaryBlock, err := utils.GetBlockBody(host, blockID, consts.DATA_TYPE_BLOCK_BODY)
 		if err != nil {
+			peers.PenalizeNet(host, err)
 			return utils.ErrInfo(err)
 		}
 
 		block, err := ProcessBlockWherePrevFromBlockchainTable(binaryBlock)
 		if err != nil {
+			peers.Penalize(host, peers.InvalidBlock, err)
 			return utils.ErrInfo(err)
 		}
 
 		if badBlocks[block.Header.BlockID] == string(converter.BinToHex(block.Header.Sign)) {
 			log.WithFields(log.Fields{"block_id": block.Header.BlockID, "type": consts.InvalidObject}).Error("block is bad")
-			return utils.ErrInfo(errors.New("bad block"))
+			err = errors.New("bad block")
+			peers.Penalize(host, peers.BadHash, err)
+			return utils.ErrInfo(err)
 		}
 		if block.Header.BlockID != blockID {
 			log.WithFields(log.Fields{"header_block_id": block.Header.BlockID, "block_id": blockID, "type": consts.InvalidObject}).Error("block ids does not match")
-			return utils.ErrInfo(errors.New("bad block_data['block_id']"))
+			err = errors.New("bad block_data['block_id']")
+			peers.Penalize(host, peers.ProtocolError, err)
+			return utils.ErrInfo(err)
 		}
 
 		// TODO: add checking for MAX_BLOCK_SIZE
@@ -105,7 +126,7 @@ func GetBlocks(blockID int64, host string) error {
 	}
 
 	// mark all transaction as unverified
-	_, err := model.MarkVerifiedAndNotUsedTransactionsUnverified()
+	_, err = model.MarkVerifiedAndNotUsedTransactionsUnverified()
 	if err != nil {
 		log.WithFields(log.Fields{
 			"error": err,
@@ -159,9 +180,11 @@ func GetBlocks(blockID int64, host string) error {
 
 		if err := block.CheckBlock(); err != nil {
 			dbTransaction.Rollback()
+			peers.Penalize(host, peers.BadHash, err)
 			return utils.ErrInfo(err)
 		}
 
+		// the bad transactions are skipped by playBlock, so its errors are the local errors of the database
 		if err := block.playBlock(dbTransaction); err != nil {
 			dbTransaction.Rollback()
 			return utils.ErrInfo(err)
@@ -201,6 +224,11 @@ func GetBlocks(blockID int64, host string) error {
 		}
 	}
 
-	err = dbTransaction.Commit()
-	return err
+	if err = dbTransaction.Commit(); err != nil {
+		return err
+	}
+	for _, block := range blocks {
+		template.InvalidateTables(block.Tables)
+	}
+	return nil
 }
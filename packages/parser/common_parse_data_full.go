@@ -36,6 +36,8 @@ import (
 	"github.com/GACHAIN/go-gachain/packages/model"
 	"github.com/GACHAIN/go-gachain/packages/script"
 	"github.com/GACHAIN/go-gachain/packages/smart"
+	"github.com/GACHAIN/go-gachain/packages/snapshot"
+	"github.com/GACHAIN/go-gachain/packages/template"
 	"github.com/GACHAIN/go-gachain/packages/utils"
 	"github.com/GACHAIN/go-gachain/packages/utils/tx"
 
@@ -52,6 +54,7 @@ type Block struct {
 	BinData    []byte
 	Parsers    []*Parser
 	SysUpdate  bool
+	Tables     map[string]bool // tables which have been modified by the transactions of the block
 }
 
 // GetLogger is returns logger
@@ -106,6 +109,7 @@ func (b *Block) PlayBlockSafe() error {
 	}
 
 	dbTransaction.Commit()
+	template.InvalidateTables(b.Tables)
 	if b.SysUpdate {
 		b.SysUpdate = false
 		if err = syspar.SysUpdate(nil); err != nil {
@@ -113,6 +117,9 @@ func (b *Block) PlayBlockSafe() error {
 			return err
 		}
 	}
+	if snapshot.IsHeight(b.Header.BlockID) {
+		snapshot.Create(b.Header.BlockID)
+	}
 	return nil
 }
 
@@ -233,6 +240,11 @@ func parseBlock(blockBuffer *bytes.Buffer) (*Block, error) {
 
 // ParseBlockHeader is parses block header
 func ParseBlockHeader(binaryBlock *bytes.Buffer) (utils.BlockData, error) {
+	return parseBlockHeader(binaryBlock, true)
+}
+
+// parseBlockHeader parses the header, the size of the block is checked if checkSize is true
+func parseBlockHeader(binaryBlock *bytes.Buffer, checkSize bool) (utils.BlockData, error) {
 	var block utils.BlockData
 	var err error
 
@@ -243,7 +255,7 @@ func ParseBlockHeader(binaryBlock *bytes.Buffer) (utils.BlockData, error) {
 
 	blockVersion := int(converter.BinToDec(binaryBlock.Next(2)))
 
-	if int64(binaryBlock.Len()) > syspar.GetMaxBlockSize() {
+	if checkSize && int64(binaryBlock.Len()) > syspar.GetMaxBlockSize() {
 		log.WithFields(log.Fields{"size": binaryBlock.Len(), "max_size": syspar.GetMaxBlockSize(), "type": consts.ParameterExceeded}).Error("binary block size exceeds max block size")
 		err = fmt.Errorf(`len(binaryBlock) > variables.Int64["max_block_size"]  %v > %v`,
 			binaryBlock.Len(), syspar.GetMaxBlockSize())
@@ -599,6 +611,7 @@ func (b *Block) playBlock(dbTransaction *model.DbTransaction) error {
 		p.DbTransaction = dbTransaction
 
 		msg, err := playTransaction(p)
+		b.addTables(p.TxTables)
 		if err != nil {
 			// skip this transaction
 			model.MarkTransactionUsed(nil, p.TxHash)
@@ -634,6 +647,18 @@ func (b *Block) playBlock(dbTransaction *model.DbTransaction) error {
 	return nil
 }
 
+func (b *Block) addTables(tables map[string]bool) {
+	if len(tables) == 0 {
+		return
+	}
+	if b.Tables == nil {
+		b.Tables = make(map[string]bool)
+	}
+	for table := range tables {
+		b.Tables[table] = true
+	}
+}
+
 // CheckBlock is checking block
 func (b *Block) CheckBlock() error {
 	logger := b.GetLogger()
@@ -29,6 +29,7 @@ import (
 	"github.com/GACHAIN/go-gachain/packages/consts"
 	"github.com/GACHAIN/go-gachain/packages/model"
 	"github.com/GACHAIN/go-gachain/packages/smart"
+	"github.com/GACHAIN/go-gachain/packages/template"
 	"github.com/GACHAIN/go-gachain/packages/utils"
 
 	log "github.com/sirupsen/logrus"
@@ -69,6 +70,8 @@ func BlockRollback(data []byte) error {
 	}
 
 	err = dbTransaction.Commit()
+	// the rolled back tables are not tracked, so all rendered pages are dropped
+	template.ClearCache()
 	return err
 }
 
@@ -99,6 +102,8 @@ func RollbackTxFromBlock(data []byte) error {
 	}
 
 	err = dbTransaction.Commit()
+	// the rolled back tables are not tracked, so all rendered pages are dropped
+	template.ClearCache()
 	return err
 }
 
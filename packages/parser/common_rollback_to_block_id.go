@@ -24,6 +24,7 @@ package parser
 
 import (
 	"database/sql"
+	"fmt"
 
 	"github.com/GACHAIN/go-gachain/packages/consts"
 	"github.com/GACHAIN/go-gachain/packages/converter"
@@ -35,7 +36,25 @@ import (
 // RollbackToBlockID rollbacks blocks till blockID
 func (p *Parser) RollbackToBlockID(blockID int64) error {
 	logger := p.GetLogger()
-	_, err := model.MarkVerifiedAndNotUsedTransactionsUnverified()
+	prunedBlockID, err := model.GetPrunedBlockID()
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting pruned block")
+		return p.ErrInfo(err)
+	}
+	if blockID <= prunedBlockID {
+		logger.WithFields(log.Fields{"type": consts.BlockError, "block_id": blockID, "pruned_block_id": prunedBlockID}).Error("rolling back to pruned block")
+		return p.ErrInfo(fmt.Errorf("block %d has been pruned", blockID))
+	}
+	snapshotBlockID, err := model.GetSnapshotBlockID()
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting snapshot block")
+		return p.ErrInfo(err)
+	}
+	if blockID < snapshotBlockID {
+		logger.WithFields(log.Fields{"type": consts.BlockError, "block_id": blockID, "snapshot_block_id": snapshotBlockID}).Error("rolling back before snapshot block")
+		return p.ErrInfo(fmt.Errorf("block %d is before the restored snapshot", blockID))
+	}
+	_, err = model.MarkVerifiedAndNotUsedTransactionsUnverified()
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("marking verified and not used transactions unverified")
 		return p.ErrInfo(err)
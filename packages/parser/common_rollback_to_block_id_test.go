@@ -0,0 +1,64 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GACHAIN/go-gachain/packages/model"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+func TestRollbackToBlockIDGuard(t *testing.T) {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	model.DBConn = db
+	if err = db.CreateTable(&model.Block{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	// the state has been restored from the snapshot at block 3 and blocks till 5 have been pruned
+	for _, id := range []int64{1, 3, 4, 5, 6, 7} {
+		block := &model.Block{ID: id, Hash: []byte{}, RollbacksHash: []byte{}, Data: []byte{1}}
+		if err = block.Create(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := &Parser{}
+	if err = p.RollbackToBlockID(2); err == nil || !strings.Contains(err.Error(), `before the restored snapshot`) {
+		t.Errorf(`rollback before snapshot: %v`, err)
+	}
+	if _, err = model.PruneBlocks(nil, 0, 5); err != nil {
+		t.Fatal(err)
+	}
+	for _, blockID := range []int64{2, 5} {
+		if err = p.RollbackToBlockID(blockID); err == nil || !strings.Contains(err.Error(), `has been pruned`) {
+			t.Errorf(`rollback to pruned block %d: %v`, blockID, err)
+		}
+	}
+}
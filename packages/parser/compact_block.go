@@ -0,0 +1,163 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package parser
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/GACHAIN/go-gachain/packages/consts"
+	"github.com/GACHAIN/go-gachain/packages/converter"
+	"github.com/GACHAIN/go-gachain/packages/crypto"
+	"github.com/GACHAIN/go-gachain/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CompactBlock splits the binary block into the binary header, the hashes of its transactions
+// and the transactions themselves in the same order. The size of the block isn't checked,
+// because the stored block has been checked when it was played.
+//
+// The compact blocks are pulled, not pushed: the disseminator announces the hash of the new block,
+// queue_parser_blocks requests the next block as the compact block and downloads only
+// the transactions which are missing in the local transactions and queue_tx tables.
+// If the compact block can't be restored, the full block body is downloaded.
+func CompactBlock(data []byte) ([]byte, [][]byte, [][]byte, error) {
+	buf := bytes.NewBuffer(data)
+	if _, err := parseBlockHeader(buf, false); err != nil {
+		return nil, nil, nil, err
+	}
+	header := data[:len(data)-buf.Len()]
+
+	hashes := make([][]byte, 0)
+	txs := make([][]byte, 0)
+	for buf.Len() > 0 {
+		size, err := converter.DecodeLengthBuf(buf)
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.UnmarshallingError, "error": err}).Error("decoding transaction size")
+			return nil, nil, nil, fmt.Errorf("bad block format (%s)", err)
+		}
+		if size == 0 || buf.Len() < size {
+			log.WithFields(log.Fields{"size": buf.Len(), "match_size": size, "type": consts.SizeDoesNotMatch}).Error("transaction size does not matches encoded length")
+			return nil, nil, nil, fmt.Errorf("bad block format (transaction len is %d)", size)
+		}
+		tx := buf.Next(size)
+		hash, err := crypto.Hash(tx)
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("hashing transaction")
+			return nil, nil, nil, err
+		}
+		hashes = append(hashes, hash)
+		txs = append(txs, tx)
+	}
+	return header, hashes, txs, nil
+}
+
+// RestoreBlock builds the binary block from the binary header and the transactions in the order of hashes
+func RestoreBlock(header []byte, hashes [][]byte, txs map[string][]byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write(header)
+	for _, hash := range hashes {
+		tx, ok := txs[string(hash)]
+		if !ok {
+			return nil, fmt.Errorf("transaction %x is missing", hash)
+		}
+		buf.Write(converter.EncodeLengthPlusData(tx))
+	}
+	return buf.Bytes(), nil
+}
+
+// SplitHashes splits the joined hashes of transactions
+func SplitHashes(data []byte) ([][]byte, error) {
+	if len(data)%consts.HashSize != 0 {
+		return nil, fmt.Errorf("bad size of hashes %d", len(data))
+	}
+	hashes := make([][]byte, 0, len(data)/consts.HashSize)
+	for i := 0; i < len(data); i += consts.HashSize {
+		hashes = append(hashes, data[i:i+consts.HashSize])
+	}
+	return hashes, nil
+}
+
+// AddMissingTransactions adds the received transactions to txs, every transaction must be requested
+// in missing hashes
+func AddMissingTransactions(txs map[string][]byte, missing [][]byte, data []byte) error {
+	requested := make(map[string]bool, len(missing))
+	for _, hash := range missing {
+		requested[string(hash)] = true
+	}
+	buf := bytes.NewBuffer(data)
+	for buf.Len() > 0 {
+		size, err := converter.DecodeLengthBuf(buf)
+		if err != nil || size == 0 || buf.Len() < size {
+			return fmt.Errorf("bad format of missing transactions")
+		}
+		tx := buf.Next(size)
+		hash, err := crypto.Hash(tx)
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("hashing transaction")
+			return err
+		}
+		if !requested[string(hash)] {
+			return fmt.Errorf("transaction %x hasn't been requested", hash)
+		}
+		txs[string(hash)] = tx
+	}
+	return nil
+}
+
+// LocalTransactions returns the transactions which are known to the node from
+// transactions and queue_tx tables and the hashes of the transactions which aren't known
+func LocalTransactions(hashes [][]byte) (map[string][]byte, [][]byte, error) {
+	txs := make(map[string][]byte)
+	missing := make([][]byte, 0)
+	checked := make(map[string]bool)
+	for _, hash := range hashes {
+		if checked[string(hash)] {
+			continue
+		}
+		checked[string(hash)] = true
+		tx := &model.Transaction{}
+		found, err := tx.Read(hash)
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err, "tx_hash": hash}).Error("reading transaction by hash")
+			return nil, nil, err
+		}
+		if found {
+			txs[string(hash)] = tx.Data
+			continue
+		}
+		queueTx := &model.QueueTx{}
+		found, err = queueTx.GetByHash(hash)
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err, "tx_hash": hash}).Error("reading queue tx by hash")
+			return nil, nil, err
+		}
+		if found {
+			txs[string(hash)] = queueTx.Data
+			continue
+		}
+		missing = append(missing, hash)
+	}
+	return txs, missing, nil
+}
@@ -0,0 +1,114 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package parser
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/GACHAIN/go-gachain/packages/converter"
+	"github.com/GACHAIN/go-gachain/packages/crypto"
+)
+
+func testBlock(txs [][]byte) []byte {
+	var buf bytes.Buffer
+	buf.Write(converter.DecToBin(1, 2))
+	buf.Write(converter.DecToBin(2, 4))
+	buf.Write(converter.DecToBin(1525000000, 4))
+	buf.Write(converter.DecToBin(1, 4))
+	buf.Write(converter.EncodeLenInt64InPlace(-1234567890))
+	buf.Write(converter.DecToBin(0, 1))
+	buf.Write(converter.EncodeLengthPlusData([]byte(`signature`)))
+	for _, tx := range txs {
+		buf.Write(converter.EncodeLengthPlusData(tx))
+	}
+	return buf.Bytes()
+}
+
+func TestCompactBlock(t *testing.T) {
+	txs := [][]byte{[]byte(`first transaction`), []byte(`second transaction`), []byte(`third transaction`)}
+	data := testBlock(txs)
+
+	header, hashes, blockTxs, err := CompactBlock(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(blockTxs, txs) || !bytes.Equal(header, testBlock(nil)) {
+		t.Fatalf(`wrong compact block %q %q`, header, blockTxs)
+	}
+	received, err := SplitHashes(bytes.Join(hashes, nil))
+	if err != nil || !reflect.DeepEqual(received, hashes) {
+		t.Fatalf(`wrong hashes %x %v`, received, err)
+	}
+	if _, err = SplitHashes(bytes.Join(hashes, nil)[1:]); err == nil {
+		t.Error(`hashes of the wrong size must be rejected`)
+	}
+
+	// the first transaction is known to the node, others are received
+	local := map[string][]byte{string(hashes[0]): txs[0]}
+	missing := hashes[1:]
+	var missingData []byte
+	for _, tx := range txs[1:] {
+		missingData = append(missingData, converter.EncodeLengthPlusData(tx)...)
+	}
+	if err = AddMissingTransactions(local, missing, missingData); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := RestoreBlock(header, hashes, local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(restored, data) {
+		t.Errorf(`restored block differs`)
+	}
+
+	// the block can't be restored without the transaction, so the full block body is downloaded
+	delete(local, string(hashes[2]))
+	if _, err = RestoreBlock(header, hashes, local); err == nil {
+		t.Error(`missing transaction must fail the restoring`)
+	}
+}
+
+func TestMissingTransactions(t *testing.T) {
+	tx := []byte(`transaction`)
+	hash, err := crypto.Hash(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := crypto.Hash([]byte(`other transaction`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	txs := make(map[string][]byte)
+	// the hash of the received transaction doesn't match the requested one
+	if err = AddMissingTransactions(txs, [][]byte{other}, converter.EncodeLengthPlusData(tx)); err == nil {
+		t.Error(`transaction with the wrong hash must be rejected`)
+	}
+	if err = AddMissingTransactions(txs, [][]byte{hash}, converter.EncodeLengthPlusData(tx)[:5]); err == nil {
+		t.Error(`truncated transaction must be rejected`)
+	}
+	if len(txs) != 0 {
+		t.Errorf(`wrong transactions are added %q`, txs)
+	}
+}
@@ -0,0 +1,320 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package peers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/GACHAIN/go-gachain/packages/consts"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Offence is the kind of misbehaviour of the remote node
+type Offence int
+
+const (
+	// InvalidBlock is a block which can't be parsed, checked or played
+	InvalidBlock Offence = iota
+	// BadHash is a block or a chain with the wrong hash or signature
+	BadHash
+	// Timeout is a request which has not been answered in time
+	Timeout
+	// ProtocolError is a malformed or unexpected response
+	ProtocolError
+)
+
+const (
+	// BanScore is the score when the node is banned
+	BanScore = 100
+	// ScoreHalfLife is the time during which the score is halved
+	ScoreHalfLife = 10 * time.Minute
+	// BanTime is the duration of the first ban, every next ban is twice longer
+	BanTime = 10 * time.Minute
+	// MaxBanTime is the longest duration of the ban
+	MaxBanTime = 24 * time.Hour
+)
+
+var offences = map[Offence]struct {
+	name    string
+	penalty float64
+}{
+	InvalidBlock:  {`invalid block`, 50},
+	BadHash:       {`bad hash`, 35},
+	Timeout:       {`timeout`, 5},
+	ProtocolError: {`protocol error`, 20},
+}
+
+func (o Offence) String() string {
+	return offences[o].name
+}
+
+// Peer is the reputation of the remote node
+type Peer struct {
+	Host        string    `json:"host"`
+	Score       float64   `json:"score"`
+	Updated     time.Time `json:"updated"`
+	Bans        int       `json:"bans"`
+	BannedUntil time.Time `json:"banned_until,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+}
+
+// Banned returns true if the peer is banned at the specified time
+func (p *Peer) Banned(now time.Time) bool {
+	return now.Before(p.BannedUntil)
+}
+
+// decay decreases the score according to the elapsed time
+func (p *Peer) decay(now time.Time) {
+	if elapsed := now.Sub(p.Updated); elapsed > 0 {
+		p.Score *= math.Pow(0.5, float64(elapsed)/float64(ScoreHalfLife))
+	}
+	p.Updated = now
+}
+
+// List is the list of the remote nodes with their reputation
+type List struct {
+	mutex sync.Mutex
+	peers map[string]*Peer
+	path  string
+	now   func() time.Time
+}
+
+var peerList = NewList(``)
+
+// NewList returns the new list of peers which is saved in the file with the specified path.
+// The list isn't saved if the path is empty.
+func NewList(path string) *List {
+	return &List{peers: make(map[string]*Peer), path: path, now: time.Now}
+}
+
+// key returns the host without the port so the ban covers all ports of the node
+func key(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// Load reads the saved list of peers
+func (l *List) Load() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	data, err := ioutil.ReadFile(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		log.WithFields(log.Fields{"type": consts.IOError, "error": err, "path": l.path}).Error("reading peers file")
+		return err
+	}
+	var peers []*Peer
+	if err = json.Unmarshal(data, &peers); err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err, "path": l.path}).Error("unmarshalling peers")
+		return err
+	}
+	for _, p := range peers {
+		l.peers[p.Host] = p
+	}
+	return nil
+}
+
+// save writes the list of peers into the file, the mutex must be locked
+func (l *List) save() {
+	if len(l.path) == 0 {
+		return
+	}
+	data, err := json.Marshal(l.list())
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling peers")
+		return
+	}
+	if err = ioutil.WriteFile(l.path, data, 0644); err != nil {
+		log.WithFields(log.Fields{"type": consts.WritingFile, "error": err, "path": l.path}).Error("writing peers file")
+	}
+}
+
+func (l *List) list() []Peer {
+	ret := make([]Peer, 0, len(l.peers))
+	for _, p := range l.peers {
+		ret = append(ret, *p)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Host < ret[j].Host })
+	return ret
+}
+
+// Penalize increases the score of the host and bans it if the score has reached BanScore
+func (l *List) Penalize(host string, offence Offence, err error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	name := key(host)
+	p, ok := l.peers[name]
+	if !ok {
+		p = &Peer{Host: name, Updated: now}
+		l.peers[name] = p
+	}
+	p.decay(now)
+	p.Score += offences[offence].penalty
+	logger := log.WithFields(log.Fields{"host": name, "offence": offence.String(), "score": p.Score, "error": err})
+	if p.Score < BanScore {
+		logger.Debug("peer has been penalized")
+		return
+	}
+	duration := BanTime << uint(p.Bans)
+	if duration > MaxBanTime || duration <= 0 {
+		duration = MaxBanTime
+	}
+	p.Bans++
+	p.Score = 0
+	p.BannedUntil = now.Add(duration)
+	p.Reason = offence.String()
+	logger.WithFields(log.Fields{"type": consts.PeerBanned, "until": p.BannedUntil}).Warning("peer has been banned")
+	l.save()
+}
+
+// IsBanned returns true if the host is banned now
+func (l *List) IsBanned(host string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	p, ok := l.peers[key(host)]
+	return ok && p.Banned(l.now())
+}
+
+// Filter returns hosts which are not banned
+func (l *List) Filter(hosts []string) []string {
+	ret := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		if !l.IsBanned(host) {
+			ret = append(ret, host)
+		}
+	}
+	return ret
+}
+
+// Peers returns all known peers with the current scores
+func (l *List) Peers() []Peer {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	for _, p := range l.peers {
+		p.decay(now)
+	}
+	return l.list()
+}
+
+// Unban removes the ban and the penalties of the host. It returns false if the host is unknown.
+func (l *List) Unban(host string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	p, ok := l.peers[key(host)]
+	if !ok {
+		return false
+	}
+	p.Score = 0
+	p.BannedUntil = time.Time{}
+	p.Reason = ``
+	p.Updated = l.now()
+	l.save()
+	return true
+}
+
+// Init loads the list of peers from the file
+func Init(path string) error {
+	peerList = NewList(path)
+	return peerList.Load()
+}
+
+// Penalize increases the score of the host in the global list
+func Penalize(host string, offence Offence, err error) {
+	peerList.Penalize(host, offence, err)
+}
+
+// IsBanned returns true if the host is banned in the global list
+func IsBanned(host string) bool {
+	return peerList.IsBanned(host)
+}
+
+// Filter returns hosts which are not banned in the global list
+func Filter(hosts []string) []string {
+	return peerList.Filter(hosts)
+}
+
+// Peers returns all peers of the global list
+func Peers() []Peer {
+	return peerList.Peers()
+}
+
+// Unban removes the ban of the host in the global list
+func Unban(host string) bool {
+	return peerList.Unban(host)
+}
+
+// cause returns the original error of the error which is wrapped by utils.ErrInfo
+func cause(err error) error {
+	for {
+		wrapped, ok := err.(interface {
+			Cause() error
+		})
+		if !ok {
+			return err
+		}
+		err = wrapped.Cause()
+	}
+}
+
+// NetOffence returns Timeout for network timeouts and ProtocolError for other errors.
+// The error can be wrapped.
+func NetOffence(err error) Offence {
+	if netErr, ok := cause(err).(net.Error); ok && netErr.Timeout() {
+		return Timeout
+	}
+	return ProtocolError
+}
+
+// IsDialError returns true if the connection to the node hasn't been established
+func IsDialError(err error) bool {
+	opErr, ok := cause(err).(*net.OpError)
+	return ok && opErr.Op == `dial`
+}
+
+// PenalizeNet penalizes the host for the failed network request. The failed dialing isn't an offence,
+// because the honest node can be offline.
+func PenalizeNet(host string, err error) {
+	if IsDialError(err) {
+		return
+	}
+	Penalize(host, NetOffence(err), err)
+}
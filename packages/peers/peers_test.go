@@ -0,0 +1,201 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package peers
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestPenalize(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `peers`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2018, 5, 1, 12, 0, 0, 0, time.UTC)
+	list := NewList(filepath.Join(dir, `peers.json`))
+	list.now = func() time.Time { return now }
+	errBlock := errors.New(`bad block`)
+
+	list.Penalize(`10.0.0.1:7078`, InvalidBlock, errBlock)
+	if list.IsBanned(`10.0.0.1`) {
+		t.Error(`peer must not be banned after one invalid block`)
+	}
+	// the score is halved so the second penalty doesn't reach the ban score
+	now = now.Add(ScoreHalfLife)
+	list.Penalize(`10.0.0.1:7078`, InvalidBlock, errBlock)
+	if list.IsBanned(`10.0.0.1:7078`) {
+		t.Error(`peer must not be banned after the decay`)
+	}
+	list.Penalize(`10.0.0.1:7079`, InvalidBlock, errBlock)
+	if !list.IsBanned(`10.0.0.1:7078`) {
+		t.Error(`peer must be banned`)
+	}
+	if hosts := list.Filter([]string{`10.0.0.1:7078`, `10.0.0.2:7078`}); len(hosts) != 1 || hosts[0] != `10.0.0.2:7078` {
+		t.Errorf(`wrong filtered hosts %v`, hosts)
+	}
+
+	loaded := NewList(list.path)
+	loaded.now = list.now
+	if err = loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.IsBanned(`10.0.0.1`) {
+		t.Error(`ban must be restored from the file`)
+	}
+
+	now = now.Add(BanTime)
+	if list.IsBanned(`10.0.0.1`) {
+		t.Error(`ban must be over`)
+	}
+	for i := 0; i < 2; i++ {
+		list.Penalize(`10.0.0.1`, InvalidBlock, errBlock)
+	}
+	peers := list.Peers()
+	if len(peers) != 1 || peers[0].Bans != 2 || !peers[0].BannedUntil.Equal(now.Add(2*BanTime)) ||
+		peers[0].Reason != InvalidBlock.String() {
+		t.Errorf(`wrong second ban %+v`, peers)
+	}
+	if !list.Unban(`10.0.0.1`) || list.IsBanned(`10.0.0.1`) {
+		t.Error(`peer must be unbanned`)
+	}
+	if list.Unban(`10.0.0.3`) {
+		t.Error(`unknown peer must not be unbanned`)
+	}
+}
+
+func TestTable(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `nodes`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2018, 5, 1, 12, 0, 0, 0, time.UTC)
+	table := NewTable(filepath.Join(dir, `nodes.json`))
+	table.now = func() time.Time { return now }
+
+	table.Seen(`10.0.0.1:7078`, 100)
+	table.Merge([]Node{
+		{Addr: `10.0.0.1:7078`, LastSeen: now.Add(time.Hour), Height: 500},
+		{Addr: `10.0.0.2:7078`, LastSeen: now.Add(time.Hour), Height: 90},
+		{Addr: `10.0.0.3:7078`, LastSeen: now.Add(-time.Minute), Height: 95},
+		{Addr: `10.0.0.4`, LastSeen: now, Height: 10},
+	})
+	// the received nodes are not added until they are verified
+	if nodes := table.Nodes(0); len(nodes) != 1 || nodes[0].Height != 100 {
+		t.Fatalf(`wrong nodes %+v`, nodes)
+	}
+	candidates := table.Candidates(1)
+	if len(candidates) != 1 {
+		t.Fatalf(`wrong candidates %v`, candidates)
+	}
+	candidates = append(candidates, table.Candidates(MaxCandidates)...)
+	sort.Strings(candidates)
+	if len(candidates) != 2 || candidates[0] != `10.0.0.2:7078` || candidates[1] != `10.0.0.3:7078` {
+		t.Fatalf(`wrong candidates %v`, candidates)
+	}
+	if rest := table.Candidates(MaxCandidates); len(rest) != 0 {
+		t.Errorf(`candidates must be removed %v`, rest)
+	}
+
+	now = now.Add(time.Minute)
+	table.Seen(`10.0.0.3:7078`, 95)
+	table.Seen(`10.0.0.4`, 10)
+	nodes := table.Nodes(0)
+	if len(nodes) != 2 || nodes[0].Addr != `10.0.0.3:7078` || !nodes[0].LastSeen.Equal(now) ||
+		nodes[1].Addr != `10.0.0.1:7078` {
+		t.Errorf(`wrong nodes %+v`, nodes)
+	}
+	if nodes = table.Nodes(1); len(nodes) != 1 {
+		t.Errorf(`wrong limited nodes %+v`, nodes)
+	}
+	if addrs := table.Sample(1); len(addrs) != 1 {
+		t.Errorf(`wrong sample %v`, addrs)
+	}
+
+	loaded := NewTable(table.path)
+	loaded.now = table.now
+	if err = loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if nodes := loaded.Nodes(0); len(nodes) != 0 {
+		t.Errorf(`the table must be written by Save %+v`, nodes)
+	}
+	table.Save()
+	if err = loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if nodes := loaded.Nodes(0); len(nodes) != 2 || nodes[0].Addr != `10.0.0.3:7078` {
+		t.Errorf(`wrong loaded nodes %+v`, nodes)
+	}
+
+	now = now.Add(NodeExpiration + time.Second)
+	table.Seen(`10.0.0.2:7079`, 120)
+	table.Save()
+	if addrs := table.Sample(0); len(addrs) != 1 || addrs[0] != `10.0.0.2:7079` {
+		t.Errorf(`expired nodes must be removed %v`, addrs)
+	}
+}
+
+type wrappedError struct {
+	err error
+}
+
+func (e *wrappedError) Error() string { return e.err.Error() + ` (utils.go:100)` }
+func (e *wrappedError) Cause() error  { return e.err }
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return `i/o timeout` }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestNetOffence(t *testing.T) {
+	wrap := func(err error) error {
+		return &wrappedError{err}
+	}
+	dialErr := &net.OpError{Op: `dial`, Net: `tcp`, Err: errors.New(`connection refused`)}
+	readErr := &net.OpError{Op: `read`, Net: `tcp`, Err: errors.New(`connection reset by peer`)}
+
+	if off := NetOffence(wrap(timeoutError{})); off != Timeout {
+		t.Errorf("wrapped timeout: %v", off)
+	}
+	if off := NetOffence(wrap(readErr)); off != ProtocolError {
+		t.Errorf("wrapped read error: %v", off)
+	}
+	if !IsDialError(wrap(dialErr)) {
+		t.Error("wrapped dial error isn't recognized")
+	}
+	if IsDialError(wrap(readErr)) {
+		t.Error("read error is recognized as dial error")
+	}
+}
@@ -0,0 +1,266 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package peers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/GACHAIN/go-gachain/packages/consts"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// MaxNodes is the max number of nodes in the table
+	MaxNodes = 1000
+	// MaxCandidates is the max number of received addresses which are waiting for the verification
+	MaxCandidates = 100
+	// NodeExpiration is the time after which the node is removed if it has not been seen
+	NodeExpiration = 24 * time.Hour
+)
+
+// Node is the address of the synced node which is learnt through the peer exchange
+type Node struct {
+	Addr     string    `json:"addr"`
+	LastSeen time.Time `json:"last_seen"`
+	Height   int64     `json:"height"`
+}
+
+// Table is the table of known nodes including nodes which are not listed in full_nodes.
+// The received addresses are candidates until this node connects to them.
+type Table struct {
+	mutex      sync.Mutex
+	nodes      map[string]*Node
+	candidates map[string]bool
+	changed    bool
+	path       string
+	now        func() time.Time
+}
+
+var nodeTable = NewTable(``)
+
+// NewTable returns the new table of nodes which is saved in the file with the specified path.
+// The table isn't saved if the path is empty.
+func NewTable(path string) *Table {
+	return &Table{nodes: make(map[string]*Node), candidates: make(map[string]bool), path: path, now: time.Now}
+}
+
+// ValidAddr returns true if the address has the form host:port
+func ValidAddr(addr string) bool {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || len(host) == 0 {
+		return false
+	}
+	num, err := strconv.Atoi(port)
+	return err == nil && num > 0 && num < 65536
+}
+
+// Load reads the saved table of nodes
+func (t *Table) Load() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	data, err := ioutil.ReadFile(t.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		log.WithFields(log.Fields{"type": consts.IOError, "error": err, "path": t.path}).Error("reading node table file")
+		return err
+	}
+	var nodes []*Node
+	if err = json.Unmarshal(data, &nodes); err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err, "path": t.path}).Error("unmarshalling node table")
+		return err
+	}
+	for _, node := range nodes {
+		if ValidAddr(node.Addr) && len(t.nodes) < MaxNodes {
+			t.nodes[node.Addr] = node
+		}
+	}
+	return nil
+}
+
+// Save removes expired nodes and writes the table into the file if it has been changed
+func (t *Table) Save() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	expired := t.now().Add(-NodeExpiration)
+	for addr, node := range t.nodes {
+		if node.LastSeen.Before(expired) {
+			delete(t.nodes, addr)
+			t.changed = true
+		}
+	}
+	if !t.changed || len(t.path) == 0 {
+		return
+	}
+	data, err := json.Marshal(t.list(0))
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling node table")
+		return
+	}
+	if err = ioutil.WriteFile(t.path, data, 0644); err != nil {
+		log.WithFields(log.Fields{"type": consts.WritingFile, "error": err, "path": t.path}).Error("writing node table file")
+		return
+	}
+	t.changed = false
+}
+
+// list returns nodes which have been seen recently first, the mutex must be locked
+func (t *Table) list(limit int) []Node {
+	ret := make([]Node, 0, len(t.nodes))
+	for _, node := range t.nodes {
+		ret = append(ret, *node)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].LastSeen.Equal(ret[j].LastSeen) {
+			return ret[i].Addr < ret[j].Addr
+		}
+		return ret[i].LastSeen.After(ret[j].LastSeen)
+	})
+	if limit > 0 && len(ret) > limit {
+		ret = ret[:limit]
+	}
+	return ret
+}
+
+// Seen adds or updates the node which has answered this node now. The table is written by Save.
+func (t *Table) Seen(addr string, height int64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if !ValidAddr(addr) || height < 0 {
+		return
+	}
+	delete(t.candidates, addr)
+	if _, ok := t.nodes[addr]; !ok && len(t.nodes) >= MaxNodes {
+		return
+	}
+	t.nodes[addr] = &Node{Addr: addr, LastSeen: t.now(), Height: height}
+	t.changed = true
+}
+
+// Merge adds the addresses of nodes which have been received from another node to candidates.
+// The received time and height are not trusted, the candidates are added to the table by Seen.
+func (t *Table) Merge(nodes []Node) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, node := range nodes {
+		if len(t.candidates) >= MaxCandidates {
+			return
+		}
+		if _, ok := t.nodes[node.Addr]; !ok && ValidAddr(node.Addr) {
+			t.candidates[node.Addr] = true
+		}
+	}
+}
+
+// Candidates removes and returns up to limit random candidates which should be verified
+func (t *Table) Candidates(limit int) []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	ret := make([]string, 0, limit)
+	for addr := range t.candidates {
+		if len(ret) >= limit {
+			break
+		}
+		ret = append(ret, addr)
+		delete(t.candidates, addr)
+	}
+	return ret
+}
+
+// Nodes returns up to limit nodes which have been seen recently, zero limit returns all nodes
+func (t *Table) Nodes(limit int) []Node {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.list(limit)
+}
+
+// Sample returns addresses of up to limit random nodes, zero limit returns all nodes
+func (t *Table) Sample(limit int) []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	ret := make([]string, 0, len(t.nodes))
+	for addr := range t.nodes {
+		ret = append(ret, addr)
+	}
+	for i := range ret {
+		j := i + rand.Intn(len(ret)-i)
+		ret[i], ret[j] = ret[j], ret[i]
+	}
+	if limit > 0 && len(ret) > limit {
+		ret = ret[:limit]
+	}
+	return ret
+}
+
+// InitTable loads the table of nodes from the file
+func InitTable(path string) error {
+	nodeTable = NewTable(path)
+	return nodeTable.Load()
+}
+
+// Seen updates the node in the global table
+func Seen(addr string, height int64) {
+	nodeTable.Seen(addr, height)
+}
+
+// Merge adds candidates to the global table
+func Merge(nodes []Node) {
+	nodeTable.Merge(nodes)
+}
+
+// Candidates returns candidates of the global table
+func Candidates(limit int) []string {
+	return nodeTable.Candidates(limit)
+}
+
+// Save writes the global table
+func Save() {
+	nodeTable.Save()
+}
+
+// Nodes returns nodes of the global table
+func Nodes(limit int) []Node {
+	return nodeTable.Nodes(limit)
+}
+
+// Sample returns addresses of random nodes of the global table
+func Sample(limit int) []string {
+	return nodeTable.Sample(limit)
+}
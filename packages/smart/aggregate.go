@@ -0,0 +1,209 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package smart
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/GACHAIN/go-gachain/packages/conf"
+	"github.com/GACHAIN/go-gachain/packages/converter"
+	"github.com/GACHAIN/go-gachain/packages/model"
+	"github.com/GACHAIN/go-gachain/packages/model/querycost"
+)
+
+var (
+	reAggregate  = regexp.MustCompile(`^(sum|count|avg|min|max)\s*\(\s*(\*|[a-z_][a-z0-9_]*)\s*\)$`)
+	reColumnName = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+)
+
+// Aggregation is the query with grouping and aggregate columns
+type Aggregation struct {
+	Fields  []string // SQL expressions of the result columns
+	Names   []string // names of the result columns
+	Group   []string // columns of GROUP BY
+	Columns []string // columns of the table which are read by the query
+}
+
+// ParseAggregation parses the columns like "category, sum(amount), count(*)" and the columns
+// of grouping. The aggregate columns are named like sum_amount, count(*) is named count.
+func ParseAggregation(columns, groupBy string) (*Aggregation, error) {
+	agg := &Aggregation{}
+	grouped := make(map[string]bool)
+	for _, col := range strings.Split(groupBy, `,`) {
+		col = strings.ToLower(strings.TrimSpace(col))
+		if len(col) == 0 {
+			continue
+		}
+		if !reColumnName.MatchString(col) {
+			return nil, fmt.Errorf(eAggColumn, col)
+		}
+		grouped[col] = true
+		agg.Group = append(agg.Group, col)
+		agg.Columns = append(agg.Columns, col)
+	}
+	for _, col := range strings.Split(columns, `,`) {
+		col = strings.ToLower(strings.TrimSpace(col))
+		if len(col) == 0 {
+			continue
+		}
+		if match := reAggregate.FindStringSubmatch(col); match != nil {
+			name, expr := match[1], `*`
+			if match[2] == `*` {
+				if match[1] != `count` {
+					return nil, fmt.Errorf(eAggColumn, col)
+				}
+			} else {
+				name += `_` + match[2]
+				expr = `"` + match[2] + `"`
+				agg.Columns = append(agg.Columns, match[2])
+			}
+			agg.Fields = append(agg.Fields, fmt.Sprintf(`%s(%s) as "%s"`, match[1], expr, name))
+			agg.Names = append(agg.Names, name)
+			continue
+		}
+		if !reColumnName.MatchString(col) {
+			return nil, fmt.Errorf(eAggColumn, col)
+		}
+		if !grouped[col] {
+			return nil, fmt.Errorf(eNotGrouped, col)
+		}
+		agg.Fields = append(agg.Fields, `"`+col+`"`)
+		agg.Names = append(agg.Names, col)
+	}
+	if len(agg.Fields) == 0 {
+		return nil, errAggEmpty
+	}
+	return agg, nil
+}
+
+// IsAggregation returns true if the columns contain aggregate functions
+func IsAggregation(columns string) bool {
+	for _, col := range strings.Split(columns, `,`) {
+		if reAggregate.MatchString(strings.ToLower(strings.TrimSpace(col))) {
+			return true
+		}
+	}
+	return false
+}
+
+// Order returns ORDER BY of the aggregation. The order can contain only the result columns.
+func (agg *Aggregation) Order(order string) (string, error) {
+	if len(order) == 0 {
+		return ``, nil
+	}
+	var list []string
+	for _, item := range strings.Split(order, `,`) {
+		fields := strings.Fields(strings.ToLower(item))
+		if len(fields) == 0 || len(fields) > 2 || len(fields) == 2 && fields[1] != `asc` && fields[1] != `desc` {
+			return ``, fmt.Errorf(eAggColumn, item)
+		}
+		found := false
+		for _, name := range agg.Names {
+			found = found || name == fields[0]
+		}
+		if !found {
+			return ``, fmt.Errorf(eAggColumn, fields[0])
+		}
+		fields[0] = `"` + fields[0] + `"`
+		list = append(list, strings.Join(fields, ` `))
+	}
+	return ` order by ` + strings.Join(list, `, `), nil
+}
+
+// Query returns the SQL query of the aggregation
+func (agg *Aggregation) Query(table, where, order string) string {
+	query := `select ` + strings.Join(agg.Fields, `, `) + ` from "` + table + `"`
+	if len(where) > 0 {
+		query += ` where ` + where
+	}
+	return query + agg.GroupBy() + order
+}
+
+// GroupBy returns GROUP BY of the aggregation
+func (agg *Aggregation) GroupBy() string {
+	if len(agg.Group) == 0 {
+		return ``
+	}
+	return ` group by "` + strings.Join(agg.Group, `", "`) + `"`
+}
+
+// DBAggregate returns the rows of the table grouped by groupBy columns with the aggregate columns
+func DBAggregate(sc *SmartContract, tblname string, columns string, groupBy string, order string, limit, ecosystem int64,
+	where string, params []interface{}) (int64, []interface{}, error) {
+
+	agg, err := ParseAggregation(columns, groupBy)
+	if err != nil {
+		return 0, nil, err
+	}
+	orderBy, err := agg.Order(order)
+	if err != nil {
+		return 0, nil, err
+	}
+	if limit <= 0 || limit > 250 {
+		limit = 250
+	}
+	if ecosystem == 0 {
+		ecosystem = sc.TxSmart.EcosystemID
+	}
+	tblname = GetTableName(sc, tblname, ecosystem)
+	var perm map[string]string
+	if sc.VDE && *conf.CheckReadAccess {
+		perm, err = sc.AccessTablePerm(tblname, `read`)
+		if err != nil {
+			return 0, nil, err
+		}
+		cols := append([]string{}, agg.Columns...)
+		if err = sc.AccessColumns(tblname, &cols, false); err != nil {
+			return 0, nil, err
+		}
+		if len(cols) != len(agg.Columns) {
+			return 0, nil, errAccessDenied
+		}
+	}
+	query := agg.Query(tblname, strings.Replace(converter.Escape(where), `$`, `?`, -1), orderBy) +
+		fmt.Sprintf(` limit %d`, limit)
+	cost, err := querycost.GetQueryCoster(querycost.FormulaQueryCosterType).QueryCost(sc.DbTransaction, query, params...)
+	if err != nil {
+		return 0, nil, err
+	}
+	list, err := model.GetAllTransaction(sc.DbTransaction, query, int(limit), params...)
+	if err != nil {
+		return 0, nil, err
+	}
+	result := make([]interface{}, len(list))
+	for i, row := range list {
+		for key, val := range row {
+			if val == `NULL` {
+				row[key] = ``
+			}
+		}
+		result[i] = reflect.ValueOf(row).Interface()
+	}
+	if err = sc.filterRows(perm, result); err != nil {
+		return 0, nil, err
+	}
+	return cost, result, nil
+}
@@ -26,9 +26,14 @@ import "errors"
 
 const (
 	eTableNotFound = `Table %s has not been found`
+	eNoSearch      = `Table %s doesn't have searchable columns`
+	eAggColumn     = `Column %s is not valid for aggregation`
+	eNotGrouped    = `Column %s must be in GroupBy or used in an aggregate function`
 )
 
 var (
 	errAccessDenied   = errors.New(`Access denied`)
 	errConditionEmpty = errors.New(`Conditions is empty`)
+	errSearchType     = errors.New(`Only varchar, text and character columns can be searchable`)
+	errAggEmpty       = errors.New(`Aggregation doesn't have columns`)
 )
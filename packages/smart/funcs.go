@@ -66,6 +66,7 @@ type permTable struct {
 type permColumn struct {
 	Update string `json:"update"`
 	Read   string `json:"read,omitempty"`
+	Search bool   `json:"search,omitempty"`
 }
 
 // SmartContract is storing smart contract data
@@ -83,12 +84,15 @@ type SmartContract struct {
 	TxHash        []byte
 	PublicKeys    [][]byte
 	DbTransaction *model.DbTransaction
+	TxTables      map[string]bool // tables which have been modified by the transaction
 }
 
 var (
 	funcCallsDB = map[string]struct{}{
+		"DBAggregate": {},
 		"DBInsert":    {},
 		"DBSelect":    {},
+		"DBSearch":    {},
 		"DBUpdate":    {},
 		"DBUpdateExt": {},
 	}
@@ -205,6 +209,8 @@ func EmbedFuncs(vm *script.VM, vt script.VMType) {
 	switch vt {
 	case script.VMTypeVDE:
 		f["HTTPRequest"] = HTTPRequest
+		f["DBSearch"] = DBSearch
+		f["DBAggregate"] = DBAggregate
 		f["GetMapKeys"] = GetMapKeys
 		f["SortedKeys"] = SortedKeys
 		f["Date"] = Date
@@ -327,6 +333,7 @@ func CreateTable(sc *SmartContract, name string, columns, permissions string) er
 		return fmt.Errorf(`CreateTable can be only called from NewTable`)
 	}
 	tableName := getDefTableName(sc, name)
+	sc.writeTable(tableName)
 
 	var cols []map[string]string
 	err = json.Unmarshal([]byte(columns), &cols)
@@ -338,6 +345,7 @@ func CreateTable(sc *SmartContract, name string, columns, permissions string) er
 	colsSQL := ""
 	colperm := make(map[string]string)
 	colList := make(map[string]bool)
+	searchCols := make([]string, 0)
 	for _, data := range cols {
 		colname := strings.ToLower(data[`name`])
 		if colList[colname] {
@@ -367,6 +375,9 @@ func CreateTable(sc *SmartContract, name string, columns, permissions string) er
 		}
 		colsSQL += `"` + colname + `" ` + colType + " " + colDef + " ,\n"
 		colperm[colname] = data[`conditions`]
+		if perm, err := getPermColumns(data[`conditions`]); err == nil && perm.Search {
+			searchCols = append(searchCols, colname)
+		}
 	}
 	colout, err := json.Marshal(colperm)
 	if err != nil {
@@ -382,6 +393,12 @@ func CreateTable(sc *SmartContract, name string, columns, permissions string) er
 		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("creating VDE tables")
 		return err
 	}
+	for _, colname := range searchCols {
+		if err = model.CreateSearchIndex(sc.DbTransaction, tableName, colname); err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("creating search index")
+			return err
+		}
+	}
 
 	var perm permTable
 	err = json.Unmarshal([]byte(permissions), &perm)
@@ -468,11 +485,6 @@ func DBInsert(sc *SmartContract, tblname string, params string, val ...interface
 func DBSelect(sc *SmartContract, tblname string, columns string, id int64, order string, offset, limit, ecosystem int64,
 	where string, params []interface{}) (int64, []interface{}, error) {
 
-	var (
-		err  error
-		rows *sql.Rows
-		perm map[string]string
-	)
 	if len(columns) == 0 {
 		columns = `*`
 	}
@@ -493,7 +505,79 @@ func DBSelect(sc *SmartContract, tblname string, columns string, id int64, order
 	if ecosystem == 0 {
 		ecosystem = sc.TxSmart.EcosystemID
 	}
+	return selectRows(sc, GetTableName(sc, tblname, ecosystem), columns, order, offset, limit, where, params)
+}
+
+// DBSearch returns the rows of the table which match the full-text search query by the searchable columns
+func DBSearch(sc *SmartContract, tblname string, query string, columns string, offset, limit, ecosystem int64) (int64, []interface{}, error) {
+	if len(columns) == 0 {
+		columns = `*`
+	}
+	if limit <= 0 || limit > 250 {
+		limit = 250
+	}
+	if ecosystem == 0 {
+		ecosystem = sc.TxSmart.EcosystemID
+	}
 	tblname = GetTableName(sc, tblname, ecosystem)
+	searchCols, err := SearchColumns(sc, tblname, sc.VDE && *conf.CheckReadAccess)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(searchCols) == 0 {
+		return 0, nil, fmt.Errorf(eNoSearch, tblname)
+	}
+	where, params := model.SearchWhere(searchCols, query)
+	return selectRows(sc, tblname, columns, `id`, offset, limit, where, params)
+}
+
+// SearchColumns returns the sorted list of searchable columns of the table. If checkRead is true,
+// only the columns which can be read by the user are returned, because the found rows show
+// the contents of the searched columns.
+func SearchColumns(sc *SmartContract, table string, checkRead bool) ([]string, error) {
+	prefix, name := PrefixName(table)
+	tables := &model.Table{}
+	tables.SetTablePrefix(prefix)
+	found, err := tables.Get(sc.DbTransaction, name)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting table columns")
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf(eTableNotFound, table)
+	}
+	var cols map[string]string
+	if err = json.Unmarshal([]byte(tables.Columns), &cols); err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("getting table columns")
+		return nil, err
+	}
+	result := make([]string, 0)
+	for column, cond := range cols {
+		if perm, err := getPermColumns(cond); err == nil && perm.Search {
+			result = append(result, column)
+		}
+	}
+	if checkRead && len(result) > 0 {
+		if err = sc.AccessColumns(table, &result, false); err != nil {
+			if err == errAccessDenied {
+				return []string{}, nil
+			}
+			return nil, err
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// selectRows returns the rows of the table checking the read access in VDE
+func selectRows(sc *SmartContract, tblname string, columns string, order string, offset, limit int64,
+	where string, params []interface{}) (int64, []interface{}, error) {
+
+	var (
+		err  error
+		rows *sql.Rows
+		perm map[string]string
+	)
 	if sc.VDE && *conf.CheckReadAccess {
 		perm, err = sc.AccessTablePerm(tblname, `read`)
 		if err != nil {
@@ -540,23 +624,32 @@ func DBSelect(sc *SmartContract, tblname string, columns string, id int64, order
 		}
 		result = append(result, reflect.ValueOf(row).Interface())
 	}
-	if sc.VDE && perm != nil && len(perm[`filter`]) > 0 {
-		fltResult, err := VMEvalIf(sc.VM, perm[`filter`], uint32(sc.TxSmart.EcosystemID),
-			&map[string]interface{}{
-				`data`:         result,
-				`ecosystem_id`: sc.TxSmart.EcosystemID,
-				`key_id`:       sc.TxSmart.KeyID, `sc`: sc,
-				`block_time`: 0, `time`: sc.TxSmart.Time})
-		if err != nil {
-			return 0, nil, err
-		}
-		if !fltResult {
-			return 0, nil, errAccessDenied
-		}
+	if err = sc.filterRows(perm, result); err != nil {
+		return 0, nil, err
 	}
 	return 0, result, nil
 }
 
+// filterRows checks the read filter of the table in VDE for the selected rows
+func (sc *SmartContract) filterRows(perm map[string]string, result []interface{}) error {
+	if !sc.VDE || perm == nil || len(perm[`filter`]) == 0 {
+		return nil
+	}
+	fltResult, err := VMEvalIf(sc.VM, perm[`filter`], uint32(sc.TxSmart.EcosystemID),
+		&map[string]interface{}{
+			`data`:         result,
+			`ecosystem_id`: sc.TxSmart.EcosystemID,
+			`key_id`:       sc.TxSmart.KeyID, `sc`: sc,
+			`block_time`: 0, `time`: sc.TxSmart.Time})
+	if err != nil {
+		return err
+	}
+	if !fltResult {
+		return errAccessDenied
+	}
+	return nil
+}
+
 // DBUpdate updates the item with the specified id in the table
 func DBUpdate(sc *SmartContract, tblname string, id int64, params string, val ...interface{}) (qcost int64, err error) {
 	tblname = getDefTableName(sc, tblname)
@@ -760,7 +853,10 @@ func TableConditions(sc *SmartContract, name, columns, permissions string) (err
 				return err
 			}
 		}
-
+		if perm.Search && !isSearchType(itype) {
+			log.WithFields(log.Fields{"type": consts.InvalidObject, "column_type": itype}).Error("column cannot be searchable")
+			return errSearchType
+		}
 	}
 	if err := sc.AccessRights("new_table", false); err != nil {
 		return err
@@ -822,8 +918,22 @@ func ColumnCondition(sc *SmartContract, tableName, name, coltype, permissions st
 	}
 	tblName := getDefTableName(sc, tableName)
 	if isExist {
+		if perm.Search {
+			if coltype, err = model.GetColumnType(tblName, name); err != nil {
+				log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting column type")
+				return err
+			}
+			if !isSearchType(coltype) {
+				log.WithFields(log.Fields{"type": consts.InvalidObject, "column_type": coltype}).Error("column cannot be searchable")
+				return errSearchType
+			}
+		}
 		return sc.AccessTable(tblName, `update`)
 	}
+	if perm.Search && !isSearchType(coltype) {
+		log.WithFields(log.Fields{"type": consts.InvalidObject, "column_type": coltype}).Error("column cannot be searchable")
+		return errSearchType
+	}
 	count, err := model.GetColumnCount(tblName)
 	if err != nil {
 		log.WithFields(log.Fields{"table": tblName, "type": consts.DBError}).Error("counting table columns")
@@ -842,6 +952,11 @@ func ColumnCondition(sc *SmartContract, tableName, name, coltype, permissions st
 	return sc.AccessTable(tblName, "new_column")
 }
 
+// isSearchType returns true if the full-text search can be used for the column type
+func isSearchType(coltype string) bool {
+	return coltype == `varchar` || coltype == `text` || coltype == `character`
+}
+
 // RowConditions checks conditions for table row by id
 func RowConditions(sc *SmartContract, tblname string, id int64) error {
 	escapedTableName := converter.EscapeName(getDefTableName(sc, tblname))
@@ -878,6 +993,7 @@ func CreateColumn(sc *SmartContract, tableName, name, coltype, permissions strin
 	}
 	name = strings.ToLower(name)
 	tblname := getDefTableName(sc, tableName)
+	sc.writeTable(tblname)
 
 	var colType string
 	switch coltype {
@@ -901,6 +1017,12 @@ func CreateColumn(sc *SmartContract, tableName, name, coltype, permissions strin
 		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("adding column to the table")
 		return err
 	}
+	if perm, err := getPermColumns(permissions); err == nil && perm.Search {
+		if err = model.CreateSearchIndex(sc.DbTransaction, tblname, name); err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("creating search index")
+			return err
+		}
+	}
 
 	tables := getDefTableName(sc, `tables`)
 	type cols struct {
@@ -955,6 +1077,20 @@ func PermColumn(sc *SmartContract, tableName, name, permissions string) error {
 		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling columns permissions from json")
 		return err
 	}
+	oldPerm, _ := getPermColumns(perm[name])
+	newPerm, _ := getPermColumns(permissions)
+	if newPerm.Search != oldPerm.Search {
+		tblname := getDefTableName(sc, tableName)
+		if newPerm.Search {
+			err = model.CreateSearchIndex(sc.DbTransaction, tblname, name)
+		} else {
+			err = model.DropSearchIndex(sc.DbTransaction, tblname, name)
+		}
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("changing search index")
+			return err
+		}
+	}
 	perm[name] = permissions
 	permout, err := json.Marshal(perm)
 	if err != nil {
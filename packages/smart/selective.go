@@ -51,6 +51,7 @@ func (sc *SmartContract) selectiveLoggingAndUpd(fields []string, ivalues []inter
 		rollbackInfoStr string
 	)
 	logger := sc.GetLogger()
+	sc.writeTable(table)
 
 	if generalRollback && sc.BlockData == nil {
 		logger.WithFields(log.Fields{"type": consts.EmptyObject}).Error("Block is undefined")
@@ -256,3 +257,11 @@ func (sc *SmartContract) selectiveLoggingAndUpd(fields []string, ivalues []inter
 	}
 	return cost, tableID, nil
 }
+
+// writeTable adds the table to the write set of the transaction
+func (sc *SmartContract) writeTable(table string) {
+	if sc.TxTables == nil {
+		sc.TxTables = make(map[string]bool)
+	}
+	sc.TxTables[table] = true
+}
@@ -338,6 +338,13 @@ func LoadContracts(transaction *model.DbTransaction) (err error) {
 	return
 }
 
+// ReloadContracts compiles all contracts in the new virtual machine,
+// it's used when the tables of contracts are replaced at once
+func ReloadContracts() error {
+	smartVM = newVM()
+	return LoadContracts(nil)
+}
+
 // LoadContract reads and compiles contract of new state
 func LoadContract(transaction *model.DbTransaction, prefix string) (err error) {
 	var contracts []map[string]string
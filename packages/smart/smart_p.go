@@ -331,9 +331,25 @@ func HexToBytes(hexdata string) ([]byte, error) {
 	return hex.DecodeString(hexdata)
 }
 
-// LangRes returns the language resource
-func LangRes(sc *SmartContract, idRes, lang string) string {
-	ret, _ := language.LangText(idRes, int(sc.TxSmart.EcosystemID), lang, sc.VDE)
+// LangRes returns the language resource. The language can be followed by the arguments of the message
+// as a map or as pairs of names and values, e.g. LangRes("items", "en", "count", 5)
+func LangRes(sc *SmartContract, idRes string, params ...interface{}) string {
+	lang := fmt.Sprint(params[0])
+	if len(params) == 1 {
+		ret, _ := language.LangText(idRes, int(sc.TxSmart.EcosystemID), lang, sc.VDE)
+		return ret
+	}
+	args := make(map[string]string)
+	if imap, ok := params[1].(map[string]interface{}); ok && len(params) == 2 {
+		for key, val := range imap {
+			args[key] = fmt.Sprint(val)
+		}
+	} else {
+		for i := 1; i+1 < len(params); i += 2 {
+			args[fmt.Sprint(params[i])] = fmt.Sprint(params[i+1])
+		}
+	}
+	ret, _ := language.LangFormat(idRes, int(sc.TxSmart.EcosystemID), lang, sc.VDE, args)
 	return ret
 }
 
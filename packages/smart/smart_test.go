@@ -23,9 +23,15 @@
 package smart
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/GACHAIN/go-gachain/packages/model"
 	"github.com/GACHAIN/go-gachain/packages/script"
+	"github.com/GACHAIN/go-gachain/packages/utils/tx"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
 )
 
 type TestSmart struct {
@@ -71,3 +77,72 @@ func TestNewContract(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestParseAggregation(t *testing.T) {
+	agg, err := ParseAggregation(`category, SUM(amount), count(*), max(date)`, `category`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order, err := agg.Order(`sum_amount desc, category`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `select "category", sum("amount") as "sum_amount", count(*) as "count", max("date") as "max_date" ` +
+		`from "1_sales" where amount > 0 group by "category" order by "sum_amount" desc, "category"`
+	if query := agg.Query(`1_sales`, `amount > 0`, order); query != want {
+		t.Errorf("wrong query %s", query)
+	}
+	if len(agg.Columns) != 3 {
+		t.Errorf("wrong columns %v", agg.Columns)
+	}
+	for _, item := range []struct {
+		columns, groupBy string
+	}{
+		{`category, sum(amount)`, ``},
+		{`sum(*)`, ``},
+		{`sum(amount); drop table`, ``},
+		{`avg(amount)`, `category desc`},
+		{``, ``},
+	} {
+		if _, err := ParseAggregation(item.columns, item.groupBy); err == nil {
+			t.Errorf("%s must be rejected", item.columns)
+		}
+	}
+	if _, err = agg.Order(`amount`); err == nil {
+		t.Error("order by not aggregated column must be rejected")
+	}
+}
+
+func TestSearchColumns(t *testing.T) {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	model.DBConn = db
+	if err = db.Exec(`CREATE TABLE "1_tables" (id integer, name text, permissions text, columns text, conditions text)`).Error; err != nil {
+		t.Fatal(err)
+	}
+	columns := `{"name": "{\"update\":\"true\",\"search\":true}",
+		"secret": "{\"update\":\"true\",\"read\":\"$key_id == 1\",\"search\":true}",
+		"amount": "true"}`
+	if err = db.Exec(`INSERT INTO "1_tables" VALUES (1, 'notes', '{}', ?, 'true')`, columns).Error; err != nil {
+		t.Fatal(err)
+	}
+	sc := &SmartContract{VM: GetVM(false, 0), TxSmart: tx.SmartContract{Header: tx.Header{EcosystemID: 1, KeyID: 2}}}
+	for _, item := range []struct {
+		keyID     int64
+		checkRead bool
+		want      string
+	}{
+		{2, false, `name,secret`},
+		{2, true, `name`},
+		{1, true, `name,secret`},
+	} {
+		sc.TxSmart.KeyID = item.keyID
+		cols, err := SearchColumns(sc, `1_notes`, item.checkRead)
+		if err != nil || strings.Join(cols, `,`) != item.want {
+			t.Errorf(`wrong search columns %v %v for key %d`, cols, err, item.keyID)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/GACHAIN/go-gachain/packages/consts"
+	"github.com/GACHAIN/go-gachain/packages/converter"
+	"github.com/GACHAIN/go-gachain/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Reader reads the snapshot file
+type Reader struct {
+	Header Header
+
+	file *os.File
+	gz   *gzip.Reader
+	buf  *bufio.Reader
+	hash hash.Hash
+}
+
+// Open opens the snapshot and reads its header
+func Open(path string) (*Reader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.IOError, "error": err, "path": path}).Error("opening snapshot")
+		return nil, err
+	}
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.IOError, "error": err, "path": path}).Error("decompressing snapshot")
+		file.Close()
+		return nil, err
+	}
+	r := &Reader{file: file, gz: gz, buf: bufio.NewReader(gz), hash: sha256.New()}
+	line, err := r.buf.ReadBytes('\n')
+	if err == nil {
+		err = json.Unmarshal(line, &r.Header)
+	}
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err, "path": path}).Error("reading snapshot header")
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Close closes the snapshot file
+func (r *Reader) Close() error {
+	r.gz.Close()
+	return r.file.Close()
+}
+
+// next returns the next record of the body, it returns io.EOF after the last record
+func (r *Reader) next() (*record, error) {
+	line, err := r.buf.ReadBytes('\n')
+	if err != nil {
+		if err == io.EOF && len(line) > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	r.hash.Write(line)
+	rec := &record{}
+	if err = json.Unmarshal(line, rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// sum returns the hash of the records which have been read
+func (r *Reader) sum() []byte {
+	return r.hash.Sum(nil)
+}
+
+// Restore replaces the state tables with the tables of the snapshot in one transaction,
+// nothing is changed if the tables don't match the header of the snapshot
+func Restore(r *Reader) error {
+	logger := log.WithFields(log.Fields{"block_id": r.Header.BlockID, "key_id": r.Header.KeyID})
+	tr, err := model.StartTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tr.Rollback()
+		}
+	}()
+
+	var (
+		table    *Table
+		hasBlock bool
+	)
+	for {
+		var rec *record
+		if rec, err = r.next(); err == io.EOF {
+			break
+		}
+		if err != nil {
+			logger.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("reading snapshot record")
+			return err
+		}
+		if rec.Table != nil {
+			table = rec.Table
+			if err = prepareTable(tr, table); err != nil {
+				logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table.Name}).Error("preparing table")
+				return err
+			}
+			continue
+		}
+		if table == nil {
+			err = fmt.Errorf("row without table")
+			logger.WithFields(log.Fields{"type": consts.SnapshotError, "error": err}).Error("reading snapshot record")
+			return err
+		}
+		if table.Name == chainTable {
+			var block struct {
+				ID int64 `json:"id"`
+			}
+			if err = json.Unmarshal(rec.Row, &block); err != nil || block.ID != r.Header.BlockID || hasBlock {
+				err = fmt.Errorf("wrong block of snapshot")
+				logger.WithFields(log.Fields{"type": consts.SnapshotError, "error": err}).Error("checking block")
+				return err
+			}
+			hasBlock = true
+		}
+		if err = model.InsertRowJSON(tr, table.Name, rec.Row); err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table.Name}).Error("inserting row")
+			return err
+		}
+	}
+
+	if !bytes.Equal(r.sum(), r.Header.StateHash) {
+		err = ErrStateHash
+		logger.WithFields(log.Fields{"type": consts.SnapshotError, "error": err}).Error("checking state hash")
+		return err
+	}
+	if !hasBlock {
+		err = fmt.Errorf("block %d isn't in snapshot", r.Header.BlockID)
+		logger.WithFields(log.Fields{"type": consts.SnapshotError, "error": err}).Error("checking block")
+		return err
+	}
+	if err = restoreInfoBlock(tr, &r.Header); err != nil {
+		logger.WithFields(log.Fields{"type": consts.SnapshotError, "error": err}).Error("restoring info block")
+		return err
+	}
+	if err = tr.Commit(); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("committing snapshot")
+		return err
+	}
+	logger.Info("snapshot has been restored")
+	return nil
+}
+
+// restoreInfoBlock replaces info_block with the block of the snapshot, the block is marked as sent
+// because it has been sent to the network long ago
+func restoreInfoBlock(tr *model.DbTransaction, header *Header) error {
+	block := &model.Block{}
+	found, err := block.GetTx(tr, header.BlockID)
+	if err != nil {
+		return err
+	}
+	if !found || !bytes.Equal(block.Hash, header.BlockHash) || len(block.Data) < 2 {
+		return fmt.Errorf("block %d doesn't match the header of snapshot", header.BlockID)
+	}
+	if err = model.ClearTable(tr, (&model.InfoBlock{}).TableName()); err != nil {
+		return err
+	}
+	infoBlock := &model.InfoBlock{
+		Hash:           block.Hash,
+		BlockID:        block.ID,
+		Time:           block.Time,
+		EcosystemID:    block.EcosystemID,
+		KeyID:          block.KeyID,
+		NodePosition:   converter.Int64ToStr(block.NodePosition),
+		CurrentVersion: fmt.Sprintf("%d", converter.BinToDec(block.Data[:2])),
+		Sent:           1,
+	}
+	return infoBlock.Create(tr)
+}
+
+// prepareTable makes the empty table with the structure of the snapshot table,
+// the existing blocks are kept because the first block is the identifier of the network
+func prepareTable(tr *model.DbTransaction, table *Table) error {
+	if err := checkTable(table); err != nil {
+		return err
+	}
+	if !model.IsTable(table.Name) {
+		if err := model.CreateTableWithColumns(tr, table.Name, table.Columns, table.Primary); err != nil {
+			return err
+		}
+		for _, definition := range table.Indexes {
+			index, err := parseIndex(table.Name, definition)
+			if err != nil {
+				return err
+			}
+			if len(index.search) > 0 {
+				err = model.CreateSearchIndex(tr, table.Name, index.search)
+			} else {
+				err = model.CreateTableIndex(tr, index.name, table.Name, index.unique, index.columns)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	columns, err := model.GetTableColumns(tr, table.Name)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		existing[column.Name] = true
+	}
+	for _, column := range table.Columns {
+		if !existing[column.Name] {
+			if err = model.AlterTableAddColumn(tr, table.Name, `"`+column.Name+`"`, column.Definition()); err != nil {
+				return err
+			}
+		}
+	}
+	if table.Name == chainTable {
+		return nil
+	}
+	return model.ClearTable(tr, table.Name)
+}
+
+const ident = `("[^"]+"|[a-z_][a-z0-9_]*)`
+
+var (
+	// columnType is the type of the column which is returned by format_type, e.g. character varying(100)
+	columnType = regexp.MustCompile(`^[a-z][a-z ]*(\(\d+(,\s?\d+)?\))?(\[\])?$`)
+	// columnDefault is the constant default value which is returned by pg_get_expr, e.g. '0'::numeric
+	columnDefault = regexp.MustCompile(`^('([^']|'')*'|-?\d+(\.\d+)?|true|false|NULL)(::[a-z][a-z ]*(\(\d+(,\s?\d+)?\))?)?$`)
+	// btreeIndex is the index on the columns which is returned by pg_get_indexdef
+	btreeIndex = regexp.MustCompile(`^CREATE (UNIQUE )?INDEX ` + ident + ` ON (` + ident + `\.)?` + ident +
+		` USING btree \((` + ident + `(, ` + ident + `)*)\)$`)
+	// searchIndex is the index of the full-text search which is created by model.CreateSearchIndex
+	searchIndex = regexp.MustCompile(`^CREATE INDEX ` + ident + ` ON (` + ident + `\.)?` + ident +
+		` USING gin \(to_tsvector\('simple'::regconfig, COALESCE\(\(?` + ident + `\)?(::text)?, ''::text\)\)\)$`)
+)
+
+// index is the index which is restored from its definition
+type index struct {
+	name    string
+	unique  bool
+	columns []string
+	search  string // the column of the full-text search index
+}
+
+func unquote(name string) string {
+	return strings.Trim(name, `"`)
+}
+
+// parseIndex parses the definition of the index of the table, only indexes on the columns
+// and the indexes of the full-text search are allowed
+func parseIndex(tableName, definition string) (*index, error) {
+	if m := btreeIndex.FindStringSubmatch(definition); m != nil && unquote(m[5]) == tableName {
+		ret := &index{name: unquote(m[2]), unique: len(m[1]) > 0}
+		for _, column := range strings.Split(m[6], `, `) {
+			ret.columns = append(ret.columns, unquote(column))
+		}
+		return ret, nil
+	}
+	if m := searchIndex.FindStringSubmatch(definition); m != nil && unquote(m[4]) == tableName {
+		column := unquote(m[5])
+		if unquote(m[1]) == tableName+`_`+column+`_search_index` {
+			return &index{name: unquote(m[1]), search: column}, nil
+		}
+	}
+	return nil, fmt.Errorf("wrong index %s of table %s", definition, tableName)
+}
+
+// checkTable checks that the names and the definitions of the table can't change the query
+func checkTable(table *Table) error {
+	if !IsStateTable(table.Name) || strings.ContainsAny(table.Name, `";`) {
+		return fmt.Errorf("wrong table name %s", table.Name)
+	}
+	for _, column := range table.Columns {
+		if strings.ContainsAny(column.Name, `";`) || !columnType.MatchString(column.Type) ||
+			(len(column.Default) > 0 && !columnDefault.MatchString(column.Default)) {
+			return fmt.Errorf("wrong column %s of table %s", column.Name, table.Name)
+		}
+	}
+	for _, name := range table.Primary {
+		if strings.ContainsAny(name, `";`) {
+			return fmt.Errorf("wrong primary key %s of table %s", name, table.Name)
+		}
+	}
+	for _, definition := range table.Indexes {
+		if _, err := parseIndex(table.Name, definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
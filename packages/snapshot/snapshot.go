@@ -0,0 +1,369 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package snapshot
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/GACHAIN/go-gachain/packages/conf"
+	"github.com/GACHAIN/go-gachain/packages/consts"
+	"github.com/GACHAIN/go-gachain/packages/crypto"
+	"github.com/GACHAIN/go-gachain/packages/model"
+	"github.com/GACHAIN/go-gachain/packages/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	fileExt = ".snapshot"
+	// chainTable is the only table which contains only the block of the snapshot
+	chainTable = "block_chain"
+)
+
+var (
+	// ErrNotFound is returned if there aren't snapshots
+	ErrNotFound = errors.New("snapshot not found")
+	// ErrSignature is returned if the snapshot isn't signed by the full node
+	ErrSignature = errors.New("wrong signature of the snapshot")
+	// ErrStateHash is returned if the tables don't match the hash of the snapshot
+	ErrStateHash = errors.New("tables don't match the state hash of the snapshot")
+)
+
+// localTables are kept by every node itself and they aren't the part of the state,
+// info_block is rebuilt from the block of the snapshot because its sent flag is set by every node
+var localTables = map[string]bool{
+	"confirmations":       true,
+	"info_block":          true,
+	"install":             true,
+	"migration_history":   true,
+	"my_node_keys":        true,
+	"queue_blocks":        true,
+	"queue_tx":            true,
+	"rollback_tx":         true,
+	"stop_daemons":        true,
+	"transactions":        true,
+	"transactions_status": true,
+}
+
+var creating int32
+
+// Header is the first line of the snapshot, it's signed by the full node which has created the snapshot
+type Header struct {
+	BlockID   int64  `json:"block_id"`
+	BlockHash []byte `json:"block_hash"`
+	StateHash []byte `json:"state_hash"`
+	KeyID     int64  `json:"key_id"`
+	Time      int64  `json:"time"`
+	Sign      []byte `json:"sign"`
+}
+
+func (h *Header) forSign() string {
+	return fmt.Sprintf("%d,%x,%x,%d,%d", h.BlockID, h.BlockHash, h.StateHash, h.KeyID, h.Time)
+}
+
+// CheckSign checks that the header is signed by the public key
+func (h *Header) CheckSign(public []byte) error {
+	ok, err := crypto.CheckSign(public, h.forSign(), h.Sign)
+	if err != nil || !ok {
+		log.WithFields(log.Fields{"type": consts.CryptoError, "error": err, "block_id": h.BlockID, "key_id": h.KeyID}).Error("checking snapshot signature")
+		return ErrSignature
+	}
+	return nil
+}
+
+// Table is the structure of the table in the snapshot
+type Table struct {
+	Name    string              `json:"name"`
+	Columns []model.TableColumn `json:"columns"`
+	Primary []string            `json:"primary,omitempty"`
+	Indexes []string            `json:"indexes,omitempty"`
+}
+
+// record is the line of the snapshot after the header, the table is followed by its rows
+type record struct {
+	Table *Table          `json:"table,omitempty"`
+	Row   json.RawMessage `json:"row,omitempty"`
+}
+
+// bodyWriter writes the tables and calculates the state hash
+type bodyWriter struct {
+	enc  *json.Encoder
+	hash hash.Hash
+}
+
+func newBodyWriter(w io.Writer) *bodyWriter {
+	h := sha256.New()
+	return &bodyWriter{enc: json.NewEncoder(io.MultiWriter(w, h)), hash: h}
+}
+
+func (w *bodyWriter) table(t *Table) error {
+	return w.enc.Encode(record{Table: t})
+}
+
+func (w *bodyWriter) row(data []byte) error {
+	return w.enc.Encode(record{Row: data})
+}
+
+func (w *bodyWriter) sum() []byte {
+	return w.hash.Sum(nil)
+}
+
+// IsStateTable returns true if the table is the part of the state of the blockchain
+func IsStateTable(name string) bool {
+	return !localTables[name] && !strings.Contains(name, "_vde_")
+}
+
+// IsHeight returns true if the snapshot should be created at the block
+func IsHeight(blockID int64) bool {
+	return conf.Config.Snapshot.Interval > 0 && blockID%conf.Config.Snapshot.Interval == 0
+}
+
+// Create starts the creating of the snapshot of the state at the block in background.
+// The caller must hold the lock of the database, so the state isn't changed until
+// the database snapshot of the read-only transaction is taken.
+func Create(blockID int64) {
+	if !atomic.CompareAndSwapInt32(&creating, 0, 1) {
+		log.WithFields(log.Fields{"type": consts.SnapshotError, "block_id": blockID}).Warning("previous snapshot is being created")
+		return
+	}
+	tr, err := model.StartReadOnlyTransaction()
+	if err != nil {
+		atomic.StoreInt32(&creating, 0)
+		return
+	}
+	// the first query of the transaction fixes its database snapshot
+	infoBlock := &model.InfoBlock{}
+	if _, err = infoBlock.GetTx(tr); err != nil || infoBlock.BlockID != blockID {
+		log.WithFields(log.Fields{"type": consts.SnapshotError, "error": err, "block_id": blockID, "info_block_id": infoBlock.BlockID}).Error("getting info block of snapshot")
+		tr.Rollback()
+		atomic.StoreInt32(&creating, 0)
+		return
+	}
+
+	go func() {
+		defer atomic.StoreInt32(&creating, 0)
+		defer tr.Rollback()
+
+		start := time.Now()
+		path, err := create(tr, infoBlock)
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.SnapshotError, "error": err, "block_id": blockID}).Error("creating snapshot")
+			return
+		}
+		log.WithFields(log.Fields{"block_id": blockID, "path": path, "duration": time.Since(start)}).Info("snapshot has been created")
+		removeOld(conf.Config.Snapshot.Keep)
+	}()
+}
+
+func create(tr *model.DbTransaction, infoBlock *model.InfoBlock) (string, error) {
+	dir := conf.GetSnapshotsDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.WithFields(log.Fields{"type": consts.IOError, "error": err, "path": dir}).Error("creating snapshots directory")
+		return "", err
+	}
+	body, err := ioutil.TempFile(dir, "body")
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("creating temporary file of snapshot")
+		return "", err
+	}
+	defer os.Remove(body.Name())
+	defer body.Close()
+
+	gz := gzip.NewWriter(body)
+	w := newBodyWriter(gz)
+	if err = writeTables(tr, w, infoBlock.BlockID); err != nil {
+		return "", err
+	}
+	if err = gz.Close(); err != nil {
+		log.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("compressing snapshot")
+		return "", err
+	}
+
+	header := &Header{
+		BlockID:   infoBlock.BlockID,
+		BlockHash: infoBlock.Hash,
+		StateHash: w.sum(),
+		KeyID:     conf.Config.KeyID,
+		Time:      time.Now().Unix(),
+	}
+	nodePrivateKey, _, err := utils.GetNodeKeys()
+	if err != nil {
+		return "", err
+	}
+	if header.Sign, err = crypto.Sign(nodePrivateKey, header.forSign()); err != nil {
+		log.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("signing snapshot")
+		return "", err
+	}
+	if _, err = body.Seek(0, io.SeekStart); err != nil {
+		log.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("seeking temporary file of snapshot")
+		return "", err
+	}
+	path := Path(infoBlock.BlockID)
+	return path, writeFile(path, header, body)
+}
+
+func writeTables(tr *model.DbTransaction, w *bodyWriter, blockID int64) error {
+	names, err := model.GetSchemaTables(tr)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting tables")
+		return err
+	}
+	for _, name := range names {
+		if !IsStateTable(name) {
+			continue
+		}
+		t := &Table{Name: name}
+		if t.Columns, err = model.GetTableColumns(tr, name); err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": name}).Error("getting table columns")
+			return err
+		}
+		if t.Primary, err = model.GetPrimaryKey(tr, name); err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": name}).Error("getting table primary key")
+			return err
+		}
+		if t.Indexes, err = model.GetTableIndexes(tr, name); err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": name}).Error("getting table indexes")
+			return err
+		}
+		if err = w.table(t); err != nil {
+			return err
+		}
+		var where string
+		var args []interface{}
+		if name == chainTable {
+			where, args = "id = ?", []interface{}{blockID}
+		}
+		if err = model.ForEachRowJSON(tr, name, t.Primary, where, w.row, args...); err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": name}).Error("reading table rows")
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFile writes the header and the compressed body of the snapshot to the file
+func writeFile(path string, header *Header, body io.Reader) error {
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.IOError, "error": err, "path": tmpPath}).Error("creating snapshot file")
+		return err
+	}
+	// the header and the body are separate gzip members which are read as one stream
+	gz := gzip.NewWriter(file)
+	err = json.NewEncoder(gz).Encode(header)
+	if err == nil {
+		err = gz.Close()
+	}
+	if err == nil {
+		_, err = io.Copy(file, body)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.IOError, "error": err, "path": path}).Error("writing snapshot file")
+		os.Remove(tmpPath)
+	}
+	return err
+}
+
+// list returns the block ids of the snapshots in ascending order
+func list() ([]int64, error) {
+	files, err := ioutil.ReadDir(conf.GetSnapshotsDir())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var blocks []int64
+	for _, file := range files {
+		var blockID int64
+		name := file.Name()
+		if strings.HasSuffix(name, fileExt) {
+			if _, err := fmt.Sscanf(strings.TrimSuffix(name, fileExt), "%d", &blockID); err == nil {
+				blocks = append(blocks, blockID)
+			}
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i] < blocks[j] })
+	return blocks, nil
+}
+
+// Path returns the path to the snapshot at the block
+func Path(blockID int64) string {
+	return filepath.Join(conf.GetSnapshotsDir(), fmt.Sprintf("%d%s", blockID, fileExt))
+}
+
+// GetHeader returns the header of the snapshot at the block
+func GetHeader(blockID int64) (*Header, error) {
+	path := Path(blockID)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	r, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return &r.Header, nil
+}
+
+// Latest returns the path to the latest snapshot
+func Latest() (string, error) {
+	blocks, err := list()
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("reading snapshots directory")
+		return "", err
+	}
+	if len(blocks) == 0 {
+		return "", ErrNotFound
+	}
+	return Path(blocks[len(blocks)-1]), nil
+}
+
+func removeOld(keep int) {
+	blocks, err := list()
+	if err != nil || keep <= 0 || len(blocks) <= keep {
+		return
+	}
+	for _, blockID := range blocks[:len(blocks)-keep] {
+		if err := os.Remove(Path(blockID)); err != nil {
+			log.WithFields(log.Fields{"type": consts.IOError, "error": err, "block_id": blockID}).Error("removing old snapshot")
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package snapshot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/GACHAIN/go-gachain/packages/crypto"
+	"github.com/GACHAIN/go-gachain/packages/model"
+)
+
+func TestSnapshotFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	table := &Table{Name: "1_keys", Columns: []model.TableColumn{{Name: "id", Type: "bigint", NotNull: true},
+		{Name: "amount", Type: "numeric(30,0)", NotNull: true, Default: "'0'::numeric"}}, Primary: []string{"id"}}
+	rows := []string{`{"id":1,"amount":100}`, `{"id":2,"amount":5}`}
+
+	body := &bytes.Buffer{}
+	gz := gzip.NewWriter(body)
+	w := newBodyWriter(gz)
+	if err = w.table(table); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if err = w.row([]byte(row)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	priv, pub, err := crypto.GenHexKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, _ := hex.DecodeString(pub)
+	header := &Header{BlockID: 10, BlockHash: []byte(`block hash`), StateHash: w.sum(), KeyID: 1, Time: 1}
+	if header.Sign, err = crypto.Sign(priv, header.forSign()); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "10"+fileExt)
+	if err = writeFile(path, header, body); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err = r.Header.CheckSign(public); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for {
+		rec, err := r.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rec.Table != nil {
+			if rec.Table.Name != table.Name || len(rec.Table.Columns) != 2 || rec.Table.Columns[1].Definition() != `numeric(30,0) NOT NULL DEFAULT '0'::numeric` {
+				t.Errorf(`wrong table %+v`, rec.Table)
+			}
+			continue
+		}
+		got = append(got, string(rec.Row))
+	}
+	if len(got) != len(rows) || got[0] != rows[0] || got[1] != rows[1] {
+		t.Errorf(`wrong rows %v`, got)
+	}
+	if !bytes.Equal(r.sum(), header.StateHash) {
+		t.Error(`state hash doesn't match`)
+	}
+
+	r.Header.StateHash = []byte(`other state`)
+	if err = r.Header.CheckSign(public); err != ErrSignature {
+		t.Errorf(`wrong signature is accepted`)
+	}
+	if err = checkTable(&Table{Name: `1_keys"; DROP TABLE "1_pages`}); err == nil {
+		t.Errorf(`wrong table name is accepted`)
+	}
+	if err = checkTable(&Table{Name: `1_keys`, Indexes: []string{`DROP TABLE "1_pages"`}}); err == nil {
+		t.Errorf(`wrong index is accepted`)
+	}
+	valid := &Table{Name: `1_keys`, Columns: table.Columns, Indexes: []string{
+		`CREATE UNIQUE INDEX "1_keys_pkey" ON public."1_keys" USING btree (id)`,
+		`CREATE INDEX "1_keys_index_amount" ON "1_keys" USING btree (amount, id)`,
+		`CREATE INDEX "1_keys_name_search_index" ON public."1_keys" USING gin ` +
+			`(to_tsvector('simple'::regconfig, COALESCE((name)::text, ''::text)))`,
+	}}
+	if err = checkTable(valid); err != nil {
+		t.Error(err)
+	}
+	if index, err := parseIndex(`1_keys`, valid.Indexes[1]); err != nil || index.name != `1_keys_index_amount` ||
+		index.unique || len(index.columns) != 2 || index.columns[1] != `id` {
+		t.Errorf(`wrong index %v %v`, index, err)
+	}
+	if index, err := parseIndex(`1_keys`, valid.Indexes[2]); err != nil || index.search != `name` {
+		t.Errorf(`wrong search index %v %v`, index, err)
+	}
+	for _, index := range []string{
+		`CREATE INDEX "1_keys_id" ON "1_pages" USING btree (id)`,
+		`CREATE INDEX "1_keys_id" ON "1_keys" USING btree ((id + 1))`,
+		`CREATE INDEX "1_keys_id" ON "1_keys" USING btree (id) WHERE (id > 0)`,
+		`CREATE INDEX "1_keys_name" ON "1_keys" USING gin (to_tsvector('simple'::regconfig, COALESCE((name)::text, ''::text)))`,
+	} {
+		if _, err = parseIndex(`1_keys`, index); err == nil {
+			t.Errorf(`wrong index %s is accepted`, index)
+		}
+	}
+	for _, column := range []model.TableColumn{
+		{Name: `amount`, Type: `numeric(30,0)`, Default: `0); DROP TABLE "1_pages"; --`},
+		{Name: `amount`, Type: `numeric(30,0)`, Default: `nextval('1_keys_id_seq'::regclass)`},
+		{Name: `amount`, Type: `numeric(30,0) CHECK (amount > 0)`},
+	} {
+		if err = checkTable(&Table{Name: `1_keys`, Columns: []model.TableColumn{column}}); err == nil {
+			t.Errorf(`wrong column %v is accepted`, column)
+		}
+	}
+}
+
+// stateHash writes the tables like writeTables and returns the state hash
+func stateHash(t *testing.T, tables map[string][]string) []byte {
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	w := newBodyWriter(ioutil.Discard)
+	for _, name := range names {
+		if !IsStateTable(name) {
+			continue
+		}
+		if err := w.table(&Table{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+		for _, row := range tables[name] {
+			if err := w.row([]byte(row)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return w.sum()
+}
+
+func TestStateHash(t *testing.T) {
+	node := func(sent int) map[string][]string {
+		return map[string][]string{
+			"1_keys":      {`{"id":1,"amount":100}`},
+			"block_chain": {`{"id":10,"hash":"\\x0a"}`},
+			"info_block":  {fmt.Sprintf(`{"hash":"\\x0a","block_id":10,"sent":%d}`, sent)},
+		}
+	}
+	if !bytes.Equal(stateHash(t, node(0)), stateHash(t, node(1))) {
+		t.Error(`state hash depends on the sent flag of info block`)
+	}
+	other := node(1)
+	other["1_keys"] = []string{`{"id":1,"amount":99}`}
+	if bytes.Equal(stateHash(t, node(1)), stateHash(t, other)) {
+		t.Error(`state hash doesn't depend on the state`)
+	}
+	if err := checkTable(&Table{Name: "info_block"}); err == nil {
+		t.Error(`info block is accepted as the table of snapshot`)
+	}
+}
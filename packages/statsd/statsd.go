@@ -32,6 +32,11 @@ import (
 const (
 	Count = ".count"
 	Time  = ".time"
+
+	RateLimited = ".ratelimited"
+
+	TCPConnections = "tcpserver.connections"
+	TCPRejected    = "tcpserver.rejected"
 )
 
 var Client statsd.Statter
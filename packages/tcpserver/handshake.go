@@ -0,0 +1,76 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package tcpserver
+
+import (
+	"encoding/hex"
+	"io"
+
+	"github.com/GACHAIN/go-gachain/packages/consts"
+	"github.com/GACHAIN/go-gachain/packages/network"
+	"github.com/GACHAIN/go-gachain/packages/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Handshake checks the handshake of the remote node and answers with the handshake of this node.
+// It returns the encrypted stream if the remote node supports encryption. The connection is
+// closed without the answer if the remote node is incompatible or its KeyID doesn't match full_nodes.
+// The nodes which are not listed in full_nodes are served anonymously.
+func Handshake(rw io.ReadWriter) (io.ReadWriter, error) {
+	remote, err := network.ReadHandshake(rw)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.ProtocolError, "error": err}).Error("reading handshake")
+		return nil, err
+	}
+	err = remote.Check(nil)
+	if err == nil && !remote.Encrypted() && !network.AllowPlaintext() {
+		err = network.ErrPlaintext
+	}
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.ProtocolError, "error": err, "key_id": remote.KeyID,
+			"version": remote.Version}).Warning("rejecting node")
+		return nil, err
+	}
+	privateKey, publicKey, err := utils.GetNodeKeys()
+	if err != nil {
+		return nil, err
+	}
+	pub, err := hex.DecodeString(publicKey)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.ConversionError, "error": err}).Error("decoding node public key from hex")
+		return nil, err
+	}
+	local, err := network.NewHandshake(privateKey, pub, remote.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	if err = network.WriteHandshake(rw, local); err != nil {
+		log.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("writing handshake")
+		return nil, err
+	}
+	if !remote.Encrypted() {
+		return rw, nil
+	}
+	return network.SecureStream(rw, local, remote)
+}
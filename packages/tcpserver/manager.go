@@ -0,0 +1,305 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package tcpserver
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/GACHAIN/go-gachain/packages/conf"
+	"github.com/GACHAIN/go-gachain/packages/config/syspar"
+	"github.com/GACHAIN/go-gachain/packages/consts"
+	"github.com/GACHAIN/go-gachain/packages/statsd"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// requestOverhead is added to the size limits of transactions and blocks
+	requestOverhead = 4096
+	// smallRequestSize is the size limit of requests with fixed size fields
+	smallRequestSize = 64
+	// peersRequestSize is the size limit of the request with the announced address
+	peersRequestSize = 512
+)
+
+var errRequestSize = errors.New("request size exceeds the limit")
+
+// ConnStats is the statistics of the connection manager
+type ConnStats struct {
+	Active          int
+	Accepted        int64
+	RejectedTotal   int64 // rejected by the global limit
+	RejectedPerPeer int64 // rejected by the limit of one address
+}
+
+// ConnManager limits the number of incoming connections and closes them on shutdown
+type ConnManager struct {
+	mutex    sync.Mutex
+	limits   conf.TCPLimitsConfig
+	listener net.Listener
+	conns    map[net.Conn]string
+	perPeer  map[string]int
+	stats    ConnStats
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+var manager *ConnManager
+
+// NewConnManager returns the connection manager with the specified limits
+func NewConnManager(limits conf.TCPLimitsConfig) *ConnManager {
+	return &ConnManager{limits: limits, conns: make(map[net.Conn]string), perPeer: make(map[string]int)}
+}
+
+func peerAddr(conn net.Conn) string {
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		return conn.RemoteAddr().String()
+	}
+	return host
+}
+
+func countRejected(kind string) {
+	if statsd.Client != nil {
+		statsd.Client.Inc(statsd.TCPRejected+"."+kind, 1, 1.0)
+	}
+}
+
+// add registers the new connection, it returns false if the connection exceeds the limits
+func (m *ConnManager) add(conn net.Conn) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	peer := peerAddr(conn)
+	switch {
+	case m.closed:
+		return false
+	case m.limits.MaxConnections > 0 && len(m.conns) >= m.limits.MaxConnections:
+		m.stats.RejectedTotal++
+		countRejected(`total`)
+		log.WithFields(log.Fields{"type": consts.ParameterExceeded, "host": peer, "limit": m.limits.MaxConnections}).Warning("too many connections")
+		return false
+	case m.limits.MaxPerPeer > 0 && m.perPeer[peer] >= m.limits.MaxPerPeer:
+		m.stats.RejectedPerPeer++
+		countRejected(`peer`)
+		log.WithFields(log.Fields{"type": consts.ParameterExceeded, "host": peer, "limit": m.limits.MaxPerPeer}).Warning("too many connections from the peer")
+		return false
+	}
+	m.conns[conn] = peer
+	m.perPeer[peer]++
+	m.stats.Accepted++
+	m.wg.Add(1)
+	if statsd.Client != nil {
+		statsd.Client.Gauge(statsd.TCPConnections, int64(len(m.conns)), 1.0)
+	}
+	return true
+}
+
+func (m *ConnManager) remove(conn net.Conn) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	peer := m.conns[conn]
+	delete(m.conns, conn)
+	if m.perPeer[peer]--; m.perPeer[peer] <= 0 {
+		delete(m.perPeer, peer)
+	}
+	m.wg.Done()
+}
+
+// Serve accepts connections until the listener is closed
+func (m *ConnManager) Serve(l net.Listener) {
+	m.mutex.Lock()
+	m.listener = l
+	m.mutex.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if m.isClosed() {
+				return
+			}
+			log.WithFields(log.Fields{"type": consts.ConnectionError, "error": err, "host": l.Addr().String()}).Error("Error accepting")
+			time.Sleep(time.Second)
+			continue
+		}
+		if !m.add(conn) {
+			conn.Close()
+			continue
+		}
+		go func(conn net.Conn) {
+			defer m.remove(conn)
+			defer conn.Close()
+			HandleTCPRequest(newTimedConn(conn, m.limits))
+		}(conn)
+	}
+}
+
+func (m *ConnManager) isClosed() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.closed
+}
+
+// Shutdown stops accepting of new connections and waits for active requests during
+// the timeout, then the rest of connections are closed
+func (m *ConnManager) Shutdown(timeout time.Duration) {
+	m.mutex.Lock()
+	m.closed = true
+	if m.listener != nil {
+		m.listener.Close()
+	}
+	m.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return
+	case <-time.After(timeout):
+	}
+	m.mutex.Lock()
+	for conn := range m.conns {
+		conn.Close()
+	}
+	m.mutex.Unlock()
+	<-done
+}
+
+// Stats returns the statistics of connections
+func (m *ConnManager) Stats() ConnStats {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	stats := m.stats
+	stats.Active = len(m.conns)
+	return stats
+}
+
+// Shutdown stops the tcp server
+func Shutdown() {
+	if manager != nil {
+		manager.Shutdown(time.Duration(conf.Config.TCPLimits.WriteTimeout) * time.Second)
+		log.Debug("tcp server stopped")
+	}
+}
+
+// Stats returns the statistics of connections of the tcp server
+func Stats() ConnStats {
+	if manager == nil {
+		return ConnStats{}
+	}
+	return manager.Stats()
+}
+
+// timedConn sets the deadline before every read and write, so the connection is closed
+// if the remote node is silent too long. The first read waits for the request during
+// the idle timeout.
+type timedConn struct {
+	net.Conn
+	idle         bool
+	idleTimeout  time.Duration
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+}
+
+func newTimedConn(conn net.Conn, limits conf.TCPLimitsConfig) *timedConn {
+	return &timedConn{Conn: conn, idle: true,
+		idleTimeout:  time.Duration(limits.IdleTimeout) * time.Second,
+		readTimeout:  time.Duration(limits.ReadTimeout) * time.Second,
+		writeTimeout: time.Duration(limits.WriteTimeout) * time.Second,
+	}
+}
+
+func deadline(timeout time.Duration) time.Time {
+	if timeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(timeout)
+}
+
+func (c *timedConn) Read(data []byte) (int, error) {
+	timeout := c.readTimeout
+	if c.idle {
+		timeout, c.idle = c.idleTimeout, false
+	}
+	c.Conn.SetReadDeadline(deadline(timeout))
+	return c.Conn.Read(data)
+}
+
+func (c *timedConn) Write(data []byte) (int, error) {
+	c.Conn.SetWriteDeadline(deadline(c.writeTimeout))
+	return c.Conn.Write(data)
+}
+
+// limitedStream fails reading when the request exceeds the size limit of its type
+type limitedStream struct {
+	io.ReadWriter
+	remain int64
+}
+
+func (s *limitedStream) Read(data []byte) (int, error) {
+	if s.remain <= 0 {
+		return 0, errRequestSize
+	}
+	if int64(len(data)) > s.remain {
+		data = data[:s.remain]
+	}
+	n, err := s.ReadWriter.Read(data)
+	s.remain -= int64(n)
+	return n, err
+}
+
+// requestSize returns the max size of the request data of the type
+func requestSize(reqType uint16) int64 {
+	var size int64
+	switch reqType {
+	case 1:
+		// the hashes and then the transactions which fit in the block
+		size = 2 * syspar.GetMaxBlockSize()
+	case 2:
+		size = syspar.GetMaxTxSize()
+	case 13:
+		return peersRequestSize
+	case 14:
+		// the block id and then the hashes of the missing transactions
+		size = int64(syspar.GetMaxTxCount()) * consts.HashSize
+	default:
+		return smallRequestSize
+	}
+	if size <= 0 {
+		size = maxRequestSize
+	}
+	return size + requestOverhead
+}
+
+// limitRequest limits the reading of the request of the type
+func limitRequest(rw io.ReadWriter, reqType uint16) io.ReadWriter {
+	return &limitedStream{ReadWriter: rw, remain: requestSize(reqType)}
+}
@@ -0,0 +1,101 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package tcpserver
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/GACHAIN/go-gachain/packages/conf"
+	"github.com/GACHAIN/go-gachain/packages/converter"
+)
+
+func waitActive(t *testing.T, m *ConnManager, active int) {
+	for i := 0; i < 100 && m.Stats().Active != active; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if stats := m.Stats(); stats.Active != active {
+		t.Fatalf("wrong active connections, want %d, got %+v", active, stats)
+	}
+}
+
+func TestConnManager(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("can't listen: %s", err)
+	}
+	m := NewConnManager(conf.TCPLimitsConfig{MaxConnections: 2, MaxPerPeer: 1, IdleTimeout: 10})
+	go m.Serve(l)
+
+	first, err := net.Dial("tcp4", l.Addr().String())
+	if err != nil {
+		t.Fatalf("can't connect: %s", err)
+	}
+	defer first.Close()
+	waitActive(t, m, 1)
+
+	// the second connection from the same address is closed at once
+	second, err := net.Dial("tcp4", l.Addr().String())
+	if err != nil {
+		t.Fatalf("can't connect: %s", err)
+	}
+	defer second.Close()
+	second.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err = second.Read(make([]byte, 1)); err == nil {
+		t.Error("connection over the limit must be closed")
+	}
+	if stats := m.Stats(); stats.RejectedPerPeer != 1 || stats.Accepted != 1 {
+		t.Errorf("wrong stats %+v", stats)
+	}
+
+	// the idle connection is closed after the shutdown timeout
+	m.Shutdown(50 * time.Millisecond)
+	waitActive(t, m, 0)
+	first.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err = first.Read(make([]byte, 1)); err == nil {
+		t.Error("connection must be closed on shutdown")
+	}
+	if _, err = net.Dial("tcp4", l.Addr().String()); err == nil {
+		t.Error("listener must be closed on shutdown")
+	}
+}
+
+func TestRequestSize(t *testing.T) {
+	request := &bytes.Buffer{}
+	request.Write(converter.DecToBin(smallRequestSize, 4))
+	request.Write(make([]byte, smallRequestSize))
+
+	// the request for a block can't have a large data
+	if err := ReadRequest(&DisRequest{}, limitRequest(request, 7)); err == nil {
+		t.Error("request over the limit must fail")
+	}
+
+	request.Reset()
+	request.Write(converter.DecToBin(10, 4))
+	request.Write(make([]byte, 10))
+	if err := ReadRequest(&DisRequest{}, limitRequest(request, 7)); err != nil {
+		t.Errorf("small request return err: %s", err)
+	}
+}
@@ -36,6 +36,9 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// maxRequestSize is the max size of the data field of the request
+const maxRequestSize = 10485760
+
 // TransactionType is type of transaction
 type TransactionType struct {
 	Type uint16
@@ -59,6 +62,56 @@ type GetBodyResponse struct {
 	Data []byte
 }
 
+// GetBlocksRangeRequest contains the first BlockID and the count of blocks
+type GetBlocksRangeRequest struct {
+	BlockID uint32
+	Count   uint32
+}
+
+// PeersRequest contains the announced address and the last block id of the sender
+type PeersRequest struct {
+	BlockID uint32
+	Addr    []byte
+}
+
+// PeersResponse contains the json list of known nodes
+type PeersResponse struct {
+	Data []byte
+}
+
+// CompactBlockRequest contains BlockID
+type CompactBlockRequest struct {
+	BlockID uint32
+}
+
+// CompactBlockResponse contains the binary header of the block and the hashes of its transactions,
+// the header is empty if the block isn't found
+type CompactBlockResponse struct {
+	Header []byte
+	Hashes []byte
+}
+
+// MissingTxsRequest contains the hashes of the transactions which should be sent in full
+type MissingTxsRequest struct {
+	Hashes []byte
+}
+
+// MissingTxsResponse contains the requested transactions in the format of the block body
+type MissingTxsResponse struct {
+	Data []byte
+}
+
+// SnapshotHeaderRequest contains BlockID of the snapshot
+type SnapshotHeaderRequest struct {
+	BlockID uint32
+}
+
+// SnapshotHeaderResponse contains the json of the signed header of the snapshot,
+// it is empty if the node doesn't have the snapshot
+type SnapshotHeaderResponse struct {
+	Data []byte
+}
+
 // ConfirmRequest contains request data
 type ConfirmRequest struct {
 	BlockID uint32
@@ -198,8 +251,11 @@ func readUint(r io.Reader, byteCount int) (uint64, error) {
 }
 
 func readBytes(r io.Reader, size uint64) ([]byte, error) {
-	var maxSize uint64 = 10485760
-	if size > maxSize { // TODO
+	var maxSize uint64 = maxRequestSize
+	if limited, ok := r.(*limitedStream); ok && limited.remain < int64(maxSize) {
+		maxSize = uint64(limited.remain)
+	}
+	if size > maxSize {
 		log.WithFields(log.Fields{"size": size, "max_size": maxSize, "type": consts.ParameterExceeded}).Error("bytes size to read exceeds max allowed size")
 		return nil, errors.New("bad size")
 	}
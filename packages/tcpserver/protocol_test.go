@@ -30,6 +30,7 @@ import (
 	"reflect"
 
 	"github.com/GACHAIN/go-gachain/packages/converter"
+	"github.com/GACHAIN/go-gachain/packages/utils"
 )
 
 func TestReadRequest(t *testing.T) {
@@ -110,3 +111,46 @@ func TestSendRequest(t *testing.T) {
 		t.Errorf("different values: %+v and %+v", test, test2)
 	}
 }
+
+func TestBlocksRange(t *testing.T) {
+	blocks := [][]byte{[]byte("first"), []byte("second block"), []byte("3")}
+
+	stream := &bytes.Buffer{}
+	if err := utils.WriteBlocksRange(stream, blocks); err != nil {
+		t.Fatalf("write blocks range return err: %s", err)
+	}
+	data := stream.Bytes()
+
+	received, err := utils.ReadBlocksRange(bytes.NewReader(data), 3, nil)
+	if err != nil {
+		t.Errorf("read blocks range return err: %s", err)
+	}
+	if !reflect.DeepEqual(received, blocks) {
+		t.Errorf("bad blocks: %q", received)
+	}
+
+	if _, err = utils.ReadBlocksRange(bytes.NewReader(data), 2, nil); err == nil {
+		t.Errorf("read blocks range must fail if there are too many blocks")
+	}
+	if _, err = utils.ReadBlocksRange(bytes.NewReader(data[:len(data)-4]), 3, nil); err == nil {
+		t.Errorf("read blocks range must fail without the end of the stream")
+	}
+
+	// the total size of 7 blocks is larger than the limit of the range
+	large := make([]byte, 10485759)
+	stream.Reset()
+	for i := 0; i < 7; i++ {
+		stream.Write(converter.DecToBin(len(large), 4))
+		stream.Write(large)
+	}
+	stream.Write(converter.DecToBin(0, 4))
+	if _, err = utils.ReadBlocksRange(stream, 10, nil); err == nil {
+		t.Errorf("read blocks range must fail if the total size is too large")
+	}
+
+	stream.Reset()
+	utils.WriteBlocksRange(stream, nil)
+	if received, err = utils.ReadBlocksRange(stream, 3, nil); err != nil || len(received) != 0 {
+		t.Errorf("bad empty range: %q %v", received, err)
+	}
+}
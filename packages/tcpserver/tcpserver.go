@@ -26,29 +26,16 @@ import (
 	"io"
 	"net"
 	"strings"
-	"sync/atomic"
-	"time"
 
+	"github.com/GACHAIN/go-gachain/packages/conf"
 	"github.com/GACHAIN/go-gachain/packages/consts"
+	"github.com/GACHAIN/go-gachain/packages/network"
 
 	log "github.com/sirupsen/logrus"
 )
 
-var (
-	counter int64
-)
-
 // HandleTCPRequest proceed TCP requests
 func HandleTCPRequest(rw io.ReadWriter) {
-	defer func() {
-		atomic.AddInt64(&counter, -1)
-	}()
-
-	count := atomic.AddInt64(&counter, +1)
-	if count > 20 {
-		return
-	}
-
 	dType := &TransactionType{}
 	err := ReadRequest(dType, rw)
 	if err != nil {
@@ -56,6 +43,24 @@ func HandleTCPRequest(rw io.ReadWriter) {
 		return
 	}
 
+	if dType.Type == consts.DATA_TYPE_HANDSHAKE {
+		if rw, err = Handshake(rw); err != nil {
+			return
+		}
+		if err = ReadRequest(dType, rw); err != nil {
+			log.Errorf("read request type failed: %s", err)
+			return
+		}
+	} else if consts.MIN_PROTOCOL_VERSION > 0 || !network.AllowPlaintext() {
+		log.WithFields(log.Fields{"type": consts.ProtocolError, "request_type": dType.Type}).Warning("request without handshake")
+		return
+	}
+	if !network.IsSupported(dType.Type) {
+		log.WithFields(log.Fields{"type": consts.ProtocolError, "request_type": dType.Type}).Warning("unsupported request type")
+		return
+	}
+	rw = limitRequest(rw, dType.Type)
+
 	log.WithFields(log.Fields{"request_type": dType.Type}).Debug("tcpserver got request type")
 	var response interface{}
 
@@ -90,6 +95,34 @@ func HandleTCPRequest(rw io.ReadWriter) {
 
 	case 10:
 		response, err = Type10()
+
+	case 11:
+		req := &GetBlocksRangeRequest{}
+		err = ReadRequest(req, rw)
+		if err == nil {
+			err = Type11(req, rw)
+		}
+
+	case 13:
+		req := &PeersRequest{}
+		err = ReadRequest(req, rw)
+		if err == nil {
+			response, err = Type13(req)
+		}
+
+	case 14:
+		req := &CompactBlockRequest{}
+		err = ReadRequest(req, rw)
+		if err == nil {
+			err = Type14(req, rw)
+		}
+
+	case 15:
+		req := &SnapshotHeaderRequest{}
+		err = ReadRequest(req, rw)
+		if err == nil {
+			response, err = Type15(req)
+		}
 	}
 
 	if err != nil {
@@ -119,21 +152,8 @@ func TcpListener(laddr string) error {
 		return err
 	}
 
-	go func() {
-		defer l.Close()
-		for {
-			conn, err := l.Accept()
-			if err != nil {
-				log.WithFields(log.Fields{"type": consts.ConnectionError, "error": err, "host": laddr}).Error("Error accepting")
-				time.Sleep(time.Second)
-			} else {
-				go func(conn net.Conn) {
-					HandleTCPRequest(conn)
-					conn.Close()
-				}(conn)
-			}
-		}
-	}()
+	manager = NewConnManager(conf.Config.TCPLimits)
+	go manager.Serve(l)
 
 	return nil
 }
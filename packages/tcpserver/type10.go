@@ -32,9 +32,18 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-// Type10 sends the last block ID
+// Type10 sends the last block ID. The node without the bodies of all blocks sends zero,
+// so syncing nodes don't choose it for downloading of blocks
 // blocksCollection daemon sends this request
 func Type10() (*MaxBlockResponse, error) {
+	complete, err := model.IsChainComplete()
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("checking for complete chain")
+		return nil, utils.ErrInfo(err)
+	}
+	if !complete {
+		return &MaxBlockResponse{}, nil
+	}
 	infoBlock := &model.InfoBlock{}
 	found, err := infoBlock.Get()
 	if err != nil {
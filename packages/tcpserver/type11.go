@@ -0,0 +1,63 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package tcpserver
+
+import (
+	"io"
+
+	"github.com/GACHAIN/go-gachain/packages/consts"
+	"github.com/GACHAIN/go-gachain/packages/model"
+	"github.com/GACHAIN/go-gachain/packages/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Type11 writes the bodies of the contiguous range of blocks through one connection
+// blocksCollection and queue_parser_blocks daemons send the request through utils.GetBlocksRange()
+func Type11(request *GetBlocksRangeRequest, w io.Writer) error {
+	count := int64(request.Count)
+	if count > consts.MAX_BLOCK_RANGE {
+		count = consts.MAX_BLOCK_RANGE
+	}
+	first := int64(request.BlockID)
+	var data [][]byte
+	if first > 0 && count > 0 {
+		blocks, err := model.GetBlockchain(first-1, first+count-1)
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err, "block_id": first, "count": count}).Error("Error getting range of blocks")
+			return utils.ErrInfo(err)
+		}
+		for _, block := range blocks {
+			// the range must be contiguous, so we stop at the first missing or pruned block
+			if block.ID != first+int64(len(data)) || block.IsPruned() {
+				break
+			}
+			data = append(data, block.Data)
+		}
+	}
+	if err := utils.WriteBlocksRange(w, data); err != nil {
+		log.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("writing range of blocks")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package tcpserver
+
+import (
+	"encoding/json"
+
+	"github.com/GACHAIN/go-gachain/packages/conf"
+	"github.com/GACHAIN/go-gachain/packages/consts"
+	"github.com/GACHAIN/go-gachain/packages/peers"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Type13 remembers the announced address of the sender as the candidate which is verified later
+// and returns the nodes which have been seen recently
+// blocksCollection daemon sends the request through exchangePeers()
+func Type13(request *PeersRequest) (*PeersResponse, error) {
+	if addr := string(request.Addr); len(addr) > 0 && addr != conf.Config.PublicAddress {
+		if !peers.IsBanned(addr) {
+			peers.Merge([]peers.Node{{Addr: addr}})
+		}
+	}
+	data, err := json.Marshal(peers.Nodes(consts.MAX_PEERS_EXCHANGE))
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling nodes")
+		return nil, err
+	}
+	return &PeersResponse{Data: data}, nil
+}
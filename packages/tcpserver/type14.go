@@ -0,0 +1,86 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package tcpserver
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/GACHAIN/go-gachain/packages/consts"
+	"github.com/GACHAIN/go-gachain/packages/converter"
+	"github.com/GACHAIN/go-gachain/packages/model"
+	"github.com/GACHAIN/go-gachain/packages/parser"
+	"github.com/GACHAIN/go-gachain/packages/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Type14 sends the block as the header and the hashes of transactions and then
+// sends in full only the transactions which the remote node doesn't have
+// queue_parser_blocks daemon sends the request through getCompactBlock()
+func Type14(request *CompactBlockRequest, rw io.ReadWriter) error {
+	block := &model.Block{}
+	found, err := block.Get(int64(request.BlockID))
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "block_id": request.BlockID}).Error("Error getting block with id")
+		return utils.ErrInfo(err)
+	}
+	if !found || block.IsPruned() {
+		log.WithFields(log.Fields{"type": consts.NotFound, "block_id": request.BlockID}).Debug("block with id not found")
+		return SendRequest(&CompactBlockResponse{}, rw)
+	}
+
+	header, hashes, txs, err := parser.CompactBlock(block.Data)
+	if err != nil {
+		return utils.ErrInfo(err)
+	}
+	if err = SendRequest(&CompactBlockResponse{Header: header, Hashes: bytes.Join(hashes, nil)}, rw); err != nil {
+		return err
+	}
+
+	req := &MissingTxsRequest{}
+	if err = ReadRequest(req, rw); err != nil {
+		return err
+	}
+	if len(req.Hashes)%consts.HashSize != 0 {
+		log.WithFields(log.Fields{"type": consts.ProtocolError, "size": len(req.Hashes)}).Error("bad size of hashes of missing transactions")
+		return fmt.Errorf("bad size of hashes %d", len(req.Hashes))
+	}
+	blockTxs := make(map[string][]byte, len(txs))
+	for i, hash := range hashes {
+		blockTxs[string(hash)] = txs[i]
+	}
+	var data []byte
+	for len(req.Hashes) > 0 {
+		hash := req.Hashes[:consts.HashSize]
+		req.Hashes = req.Hashes[consts.HashSize:]
+		tx, ok := blockTxs[string(hash)]
+		if !ok {
+			log.WithFields(log.Fields{"type": consts.NotFound, "block_id": request.BlockID, "tx_hash": hash}).Error("transaction isn't in the block")
+			return fmt.Errorf("transaction %x isn't in the block %d", hash, request.BlockID)
+		}
+		data = append(data, converter.EncodeLengthPlusData(tx)...)
+	}
+	return SendRequest(&MissingTxsResponse{Data: data}, rw)
+}
@@ -45,5 +45,9 @@ func Type7(request *GetBodyRequest) (*GetBodyResponse, error) {
 		log.WithFields(log.Fields{"type": consts.NotFound, "block_id": request.BlockID}).Error("block with id not found")
 		return nil, errors.New("Block not found. ID: " + string(request.BlockID))
 	}
+	if block.IsPruned() {
+		log.WithFields(log.Fields{"type": consts.NotFound, "block_id": request.BlockID}).Warning("block body has been pruned")
+		return nil, errors.New("Block is pruned")
+	}
 	return &GetBodyResponse{Data: block.Data}, nil
 }
@@ -0,0 +1,189 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package template
+
+import (
+	"fmt"
+
+	"github.com/GACHAIN/go-gachain/packages/consts"
+	"github.com/GACHAIN/go-gachain/packages/converter"
+	"github.com/GACHAIN/go-gachain/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Names of the ecosystem parameters which limit page rendering
+const (
+	ParamMaxQueries = `max_page_queries`
+	ParamMaxRows    = `max_page_rows`
+	ParamMaxNodes   = `max_page_nodes`
+	ParamMaxInclude = `max_include_depth`
+	ParamMaxCost    = `max_page_query_cost`
+)
+
+// Default limits are used when the ecosystem doesn't have the parameter
+const (
+	defaultMaxQueries = 100
+	defaultMaxRows    = 10000
+	defaultMaxNodes   = 20000
+	defaultMaxInclude = 5
+	defaultMaxCost    = 1000
+)
+
+// Limits are the resources which a template is allowed to spend. Zero value means no limit.
+type Limits struct {
+	Queries int64
+	Rows    int64
+	Nodes   int64
+	Include int64
+	Cost    int64 // the total cost of queries which is calculated by querycost
+}
+
+// Budget contains the limits and counts the resources spent on rendering
+type Budget struct {
+	Limits  Limits `json:"-"`
+	Queries int64  `json:"queries"`
+	Rows    int64  `json:"rows"`
+	Nodes   int64  `json:"nodes"`
+	Include int64  `json:"include"`
+	Cost    int64  `json:"cost"`
+}
+
+// BudgetError is returned when rendering has exceeded one of the limits
+type BudgetError struct {
+	Resource string
+	Limit    int64
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf(`the limit of %s (%d) has been exceeded`, e.Resource, e.Limit)
+}
+
+// GetLimits returns the rendering limits of the ecosystem
+func GetLimits(ecosystemID int64, vde bool) Limits {
+	prefix := converter.Int64ToStr(ecosystemID)
+	if vde {
+		prefix += `_vde`
+	}
+	limit := func(name string, def int64) int64 {
+		sp := &model.StateParameter{}
+		sp.SetTablePrefix(prefix)
+		found, err := sp.Get(nil, name)
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err, "name": name}).Error("getting page limit")
+			return def
+		}
+		if !found || len(sp.Value) == 0 {
+			return def
+		}
+		return converter.StrToInt64(sp.Value)
+	}
+	return Limits{
+		Queries: limit(ParamMaxQueries, defaultMaxQueries),
+		Rows:    limit(ParamMaxRows, defaultMaxRows),
+		Nodes:   limit(ParamMaxNodes, defaultMaxNodes),
+		Include: limit(ParamMaxInclude, defaultMaxInclude),
+		Cost:    limit(ParamMaxCost, defaultMaxCost),
+	}
+}
+
+// NewBudget returns the budget with the specified limits
+func NewBudget(limits Limits) *Budget {
+	return &Budget{Limits: limits}
+}
+
+func spend(value *int64, count, limit int64, resource string) error {
+	*value += count
+	if limit > 0 && *value > limit {
+		return &BudgetError{Resource: resource, Limit: limit}
+	}
+	return nil
+}
+
+// aborted returns true if rendering has been cancelled or has exceeded the budget
+func (w *Workspace) aborted() bool {
+	if w.Err != nil {
+		return true
+	}
+	select {
+	case <-w.Ctx.Done():
+		w.Err = w.Ctx.Err()
+		return true
+	default:
+	}
+	return false
+}
+
+// fail stops rendering with the error
+func (w *Workspace) fail(err error) bool {
+	if err != nil && w.Err == nil {
+		w.Err = err
+	}
+	return w.Err != nil
+}
+
+// useQuery must be called before each DB query of the template
+func (w *Workspace) useQuery() bool {
+	if w.aborted() {
+		return false
+	}
+	return !w.fail(spend(&w.Budget.Queries, 1, w.Budget.Limits.Queries, `DB queries`))
+}
+
+// db returns the connection for the queries of the template, the running query is cancelled
+// when the rendering is aborted by the timeout
+func (w *Workspace) db() *model.DbTransaction {
+	if w.dbConn == nil {
+		w.dbConn = model.ContextTransaction(w.Ctx)
+	}
+	return w.dbConn
+}
+
+func (w *Workspace) useRows(count int) bool {
+	return !w.fail(spend(&w.Budget.Rows, int64(count), w.Budget.Limits.Rows, `rows`))
+}
+
+func (w *Workspace) useCost(cost int64) bool {
+	return !w.fail(spend(&w.Budget.Cost, cost, w.Budget.Limits.Cost, `query cost`))
+}
+
+func (w *Workspace) useNode() bool {
+	return !w.fail(spend(&w.Budget.Nodes, 1, w.Budget.Limits.Nodes, `nodes`))
+}
+
+// enterInclude puts the block into the stack of includes, it returns false if the depth is too large
+func (w *Workspace) enterInclude(name string) bool {
+	depth := int64(len(w.includes) + 1)
+	if limit := w.Budget.Limits.Include; limit > 0 && depth > limit {
+		return !w.fail(&BudgetError{Resource: `Include depth`, Limit: limit})
+	}
+	if depth > w.Budget.Include {
+		w.Budget.Include = depth
+	}
+	w.includes = append(w.includes, name)
+	return true
+}
+
+func (w *Workspace) leaveInclude() {
+	w.includes = w.includes[:len(w.includes)-1]
+}
@@ -0,0 +1,187 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package template
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/GACHAIN/go-gachain/packages/conf"
+)
+
+// Deps are the tables which have been read while rendering the template
+type Deps struct {
+	Tables   map[string]bool
+	Volatile bool // the result depends on the current time and can't be cached
+}
+
+// NewDeps returns the empty dependencies
+func NewDeps() *Deps {
+	return &Deps{Tables: make(map[string]bool)}
+}
+
+// Add appends the tables to the dependencies
+func (d *Deps) Add(tables ...string) {
+	for _, table := range tables {
+		d.Tables[table] = true
+	}
+}
+
+type cacheItem struct {
+	value  interface{}
+	tables []string
+}
+
+// renderCache keeps the rendered templates until one of the tables which they have read is modified
+type renderCache struct {
+	mutex   sync.Mutex
+	gen     uint64 // it is increased on each invalidation
+	items   map[string]*cacheItem
+	byTable map[string]map[string]bool
+}
+
+var cache = newRenderCache()
+
+func newRenderCache() *renderCache {
+	return &renderCache{items: make(map[string]*cacheItem), byTable: make(map[string]map[string]bool)}
+}
+
+func (c *renderCache) generation() uint64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.gen
+}
+
+func (c *renderCache) get(key string) (interface{}, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if item, ok := c.items[key]; ok {
+		return item.value, true
+	}
+	return nil, false
+}
+
+// set stores the value if there was no invalidation since gen had been taken
+func (c *renderCache) set(key string, value interface{}, deps *Deps, gen uint64, size int) {
+	if deps.Volatile || size <= 0 {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if gen != c.gen {
+		return
+	}
+	c.remove(key)
+	for len(c.items) >= size {
+		for old := range c.items {
+			c.remove(old)
+			break
+		}
+	}
+	item := &cacheItem{value: value, tables: make([]string, 0, len(deps.Tables))}
+	for table := range deps.Tables {
+		item.tables = append(item.tables, table)
+		if c.byTable[table] == nil {
+			c.byTable[table] = make(map[string]bool)
+		}
+		c.byTable[table][key] = true
+	}
+	c.items[key] = item
+}
+
+func (c *renderCache) remove(key string) {
+	item, ok := c.items[key]
+	if !ok {
+		return
+	}
+	for _, table := range item.tables {
+		delete(c.byTable[table], key)
+		if len(c.byTable[table]) == 0 {
+			delete(c.byTable, table)
+		}
+	}
+	delete(c.items, key)
+}
+
+func (c *renderCache) invalidate(tables map[string]bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.gen++
+	for table := range tables {
+		for key := range c.byTable[table] {
+			c.remove(key)
+		}
+	}
+}
+
+func (c *renderCache) clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.gen++
+	c.items = make(map[string]*cacheItem)
+	c.byTable = make(map[string]map[string]bool)
+}
+
+// CacheKey returns the key of the rendered template with the specified variables
+func CacheKey(name string, vars map[string]string) string {
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	list := make([]string, 0, len(keys)+1)
+	list = append(list, name)
+	for _, key := range keys {
+		list = append(list, key+`=`+vars[key])
+	}
+	return strings.Join(list, "\x00")
+}
+
+// CacheGeneration returns the current generation of the render cache. It must be taken
+// before rendering and passed to CacheSet.
+func CacheGeneration() uint64 {
+	return cache.generation()
+}
+
+// CacheGet returns the rendered value from the cache
+func CacheGet(key string) (interface{}, bool) {
+	return cache.get(key)
+}
+
+// CacheSet stores the rendered value with its dependencies
+func CacheSet(key string, value interface{}, deps *Deps, gen uint64) {
+	cache.set(key, value, deps, gen, conf.Config.RenderCacheSize)
+}
+
+// InvalidateTables removes the rendered values which depend on the modified tables
+func InvalidateTables(tables map[string]bool) {
+	if len(tables) > 0 {
+		cache.invalidate(tables)
+	}
+}
+
+// ClearCache removes all rendered values
+func ClearCache() {
+	cache.clear()
+}
@@ -39,8 +39,11 @@ import (
 	"github.com/GACHAIN/go-gachain/packages/converter"
 	"github.com/GACHAIN/go-gachain/packages/language"
 	"github.com/GACHAIN/go-gachain/packages/model"
+	"github.com/GACHAIN/go-gachain/packages/model/querycost"
+	"github.com/GACHAIN/go-gachain/packages/script"
 	"github.com/GACHAIN/go-gachain/packages/smart"
 
+	"github.com/shopspring/decimal"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -48,8 +51,42 @@ var (
 	funcs = make(map[string]tplFunc)
 	tails = make(map[string]forTails)
 	modes = [][]rune{{'(', ')'}, {'{', '}'}}
+	// timeNow returns the current time, it is replaced in tests
+	timeNow = time.Now
 )
 
+// maxMoneyDigit is the max number of digits after the point of money values
+const maxMoneyDigit = 18
+
+// timeUnits are the units of AddDate and TimeAgo
+var timeUnits = []struct {
+	name     string
+	duration time.Duration
+}{
+	{`year`, 365 * 24 * time.Hour},
+	{`month`, 30 * 24 * time.Hour},
+	{`week`, 7 * 24 * time.Hour},
+	{`day`, 24 * time.Hour},
+	{`hour`, time.Hour},
+	{`minute`, time.Minute},
+	{`second`, time.Second},
+}
+
+// timeAgoMessages are the default messages of TimeAgo. They can be redefined by the language
+// resources with the same names.
+var timeAgoMessages = map[string]string{
+	`timeago_now`:    `just now`,
+	`timeago_past`:   `{time} ago`,
+	`timeago_future`: `in {time}`,
+	`timeago_year`:   `{n, plural, one {# year} other {# years}}`,
+	`timeago_month`:  `{n, plural, one {# month} other {# months}}`,
+	`timeago_week`:   `{n, plural, one {# week} other {# weeks}}`,
+	`timeago_day`:    `{n, plural, one {# day} other {# days}}`,
+	`timeago_hour`:   `{n, plural, one {# hour} other {# hours}}`,
+	`timeago_minute`: `{n, plural, one {# minute} other {# minutes}}`,
+	`timeago_second`: `{n, plural, one {# second} other {# seconds}}`,
+}
+
 func init() {
 	funcs[`Lower`] = tplFunc{lowerTag, defaultTag, `lower`, `Text`}
 	funcs[`AddToolButton`] = tplFunc{defaultTag, defaultTag, `addtoolbutton`, `Title,Icon,Page,PageParams`}
@@ -57,27 +94,31 @@ func init() {
 	funcs[`Calculate`] = tplFunc{calculateTag, defaultTag, `calculate`, `Exp,Type,Prec`}
 	funcs[`CmpTime`] = tplFunc{cmpTimeTag, defaultTag, `cmptime`, `Time1,Time2`}
 	funcs[`Code`] = tplFunc{defaultTag, defaultTag, `code`, `Text`}
-	funcs[`DateTime`] = tplFunc{dateTimeTag, defaultTag, `datetime`, `DateTime,Format`}
+	funcs[`ContractForm`] = tplFunc{contractFormTag, defaultTag, `contractform`, `Contract,Class`}
+	funcs[`AddDate`] = tplFunc{addDateTag, defaultTag, `adddate`, `DateTime,Interval,Format`}
+	funcs[`DateTime`] = tplFunc{dateTimeTag, defaultTag, `datetime`, `DateTime,Format,Location,Locale`}
 	funcs[`EcosysParam`] = tplFunc{ecosysparTag, defaultTag, `ecosyspar`, `Name,Index,Source`}
 	funcs[`Em`] = tplFunc{defaultTag, defaultTag, `em`, `Body,Class`}
 	funcs[`GetVar`] = tplFunc{getvarTag, defaultTag, `getvar`, `Name`}
 	funcs[`ImageInput`] = tplFunc{defaultTag, defaultTag, `imageinput`, `Name,Width,Ratio,Format`}
 	funcs[`InputErr`] = tplFunc{defaultTag, defaultTag, `inputerr`, `*`}
-	funcs[`LangRes`] = tplFunc{langresTag, defaultTag, `langres`, `Name,Lang`}
+	funcs[`LangRes`] = tplFunc{langresTag, defaultTag, `langres`, `Name,Lang,Params`}
 	funcs[`MenuGroup`] = tplFunc{menugroupTag, defaultTag, `menugroup`, `Title,Body,Icon`}
 	funcs[`MenuItem`] = tplFunc{defaultTag, defaultTag, `menuitem`, `Title,Page,PageParams,Icon,Vde`}
+	funcs[`Money`] = tplFunc{moneyTag, defaultTag, `money`, `Exp,Digit,Locale`}
 	funcs[`Now`] = tplFunc{nowTag, defaultTag, `now`, `Format,Interval`}
 	funcs[`SetTitle`] = tplFunc{defaultTag, defaultTag, `settitle`, `Title`}
 	funcs[`SetVar`] = tplFunc{setvarTag, defaultTag, `setvar`, `Name,Value`}
 	funcs[`Strong`] = tplFunc{defaultTag, defaultTag, `strong`, `Body,Class`}
 	funcs[`SysParam`] = tplFunc{sysparTag, defaultTag, `syspar`, `Name`}
+	funcs[`TimeAgo`] = tplFunc{timeAgoTag, defaultTag, `timeago`, `DateTime,Locale`}
 	funcs[`Button`] = tplFunc{buttonTag, buttonTag, `button`, `Body,Page,Class,Contract,Params,PageParams`}
 	funcs[`Div`] = tplFunc{defaultTailTag, defaultTailTag, `div`, `Class,Body`}
 	funcs[`ForList`] = tplFunc{forlistTag, defaultTag, `forlist`, `Source,Body`}
 	funcs[`Form`] = tplFunc{defaultTailTag, defaultTailTag, `form`, `Class,Body`}
 	funcs[`If`] = tplFunc{ifTag, ifFull, `if`, `Condition,Body`}
 	funcs[`Image`] = tplFunc{defaultTailTag, defaultTailTag, `image`, `Src,Alt,Class`}
-	funcs[`Include`] = tplFunc{includeTag, defaultTag, `include`, `Name`}
+	funcs[`Include`] = tplFunc{includeTag, defaultTag, `include`, `Name,Params`}
 	funcs[`Input`] = tplFunc{defaultTailTag, defaultTailTag, `input`, `Name,Class,Placeholder,Type,@Value,Disabled`}
 	funcs[`Label`] = tplFunc{defaultTailTag, defaultTailTag, `label`, `Body,Class,For`}
 	funcs[`LinkPage`] = tplFunc{defaultTailTag, defaultTailTag, `linkpage`, `Body,Page,Class,PageParams`}
@@ -129,6 +170,9 @@ func init() {
 		`Order`:     {tplFunc{tailTag, defaultTailFull, `order`, `Order`}, false},
 		`Limit`:     {tplFunc{tailTag, defaultTailFull, `limit`, `Limit`}, false},
 		`Offset`:    {tplFunc{tailTag, defaultTailFull, `offset`, `Offset`}, false},
+		`Count`:     {tplFunc{countTag, defaultTailFull, `count`, `CountVar`}, false},
+		`GroupBy`:   {tplFunc{tailTag, defaultTailFull, `groupby`, `GroupBy`}, false},
+		`Search`:    {tplFunc{tailTag, defaultTailFull, `search`, `Search`}, false},
 		`Ecosystem`: {tplFunc{tailTag, defaultTailFull, `ecosystem`, `Ecosystem`}, false},
 		`Custom`:    {tplFunc{customTag, defaultTailFull, `custom`, `Column,Body`}, false},
 		`Vars`:      {tplFunc{tailTag, defaultTailFull, `vars`, `Prefix`}, false},
@@ -144,7 +188,8 @@ func init() {
 		`Style`: {tplFunc{tailTag, defaultTailFull, `style`, `Style`}, false},
 	}}
 	tails[`table`] = forTails{map[string]tailInfo{
-		`Style`: {tplFunc{tailTag, defaultTailFull, `style`, `Style`}, false},
+		`Style`:  {tplFunc{tailTag, defaultTailFull, `style`, `Style`}, false},
+		`Paging`: {tplFunc{pagingTag, defaultTailFull, `paging`, `Sort`}, false},
 	}}
 	tails[`select`] = forTails{map[string]tailInfo{
 		`Validate`: {tplFunc{validateTag, validateFull, `validate`, `*`}, false},
@@ -225,9 +270,13 @@ func ecosysparTag(par parFunc) string {
 	if par.Workspace.SmartContract.VDE {
 		prefix += `_vde`
 	}
+	if !par.Workspace.useQuery() {
+		return ``
+	}
+	par.Workspace.Deps.Add(prefix+`_parameters`, prefix+`_languages`)
 	sp := &model.StateParameter{}
 	sp.SetTablePrefix(prefix)
-	_, err := sp.Get(nil, (*par.Pars)[`Name`])
+	_, err := sp.Get(par.Workspace.db(), (*par.Pars)[`Name`])
 	if err != nil {
 		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting ecosystem param")
 		return err.Error()
@@ -264,13 +313,21 @@ func langresTag(par parFunc) string {
 	if len(lang) == 0 {
 		lang = (*par.Workspace.Vars)[`lang`]
 	}
-	ret, _ := language.LangText((*par.Pars)[`Name`], int(converter.StrToInt64((*par.Workspace.Vars)[`ecosystem_id`])),
-		lang, par.Workspace.SmartContract.VDE)
+	par.Workspace.Deps.Add(smart.GetTableName(par.Workspace.SmartContract, `languages`,
+		converter.StrToInt64((*par.Workspace.Vars)[`ecosystem_id`])))
+	state := int(converter.StrToInt64((*par.Workspace.Vars)[`ecosystem_id`]))
+	if params := (*par.Pars)[`Params`]; len(params) > 0 {
+		ret, _ := language.LangFormat((*par.Pars)[`Name`], state, lang, par.Workspace.SmartContract.VDE,
+			parseIncludeParams(params))
+		return ret
+	}
+	ret, _ := language.LangText((*par.Pars)[`Name`], state, lang, par.Workspace.SmartContract.VDE)
 	return ret
 }
 
 func sysparTag(par parFunc) (ret string) {
 	if len((*par.Pars)[`Name`]) > 0 {
+		par.Workspace.Deps.Add(`system_parameters`)
 		ret = syspar.SysString((*par.Pars)[`Name`])
 	}
 	return
@@ -305,7 +362,11 @@ func nowTag(par parFunc) string {
 			query = fmt.Sprintf(`select to_char(now()%s, '%s')`, interval, format)
 		}
 	}
-	ret, err := model.Single(query).String()
+	if !par.Workspace.useQuery() {
+		return ``
+	}
+	par.Workspace.Deps.Volatile = true
+	ret, err := model.SingleTransaction(par.Workspace.db(), query).String()
 	if err != nil {
 		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting single from DB")
 		return err.Error()
@@ -407,7 +468,8 @@ func dataTag(par parFunc) string {
 	par.Node.Attr[`columns`] = &cols
 	par.Node.Attr[`types`] = &types
 	par.Node.Attr[`data`] = &data
-	newSource(par)
+	par.Node.Attr[`count`] = converter.IntToStr(len(data))
+	newSource(par, nil)
 	par.Owner.Children = append(par.Owner.Children, par.Node)
 	return ``
 }
@@ -418,6 +480,7 @@ func dbfindTag(par parFunc) string {
 		state  int64
 		err    error
 		perm   map[string]string
+		agg    *smart.Aggregation
 	)
 	if len((*par.Pars)[`Name`]) == 0 {
 		return ``
@@ -427,12 +490,19 @@ func dbfindTag(par parFunc) string {
 	where := ``
 	order := ``
 	limit := 25
+	offset := 0
 	if par.Node.Attr[`columns`] != nil {
 		fields = converter.Escape(par.Node.Attr[`columns`].(string))
 	}
 	if len(fields) == 0 {
 		fields = `*`
 	}
+	if groupBy, ok := par.Node.Attr[`groupby`].(string); ok || smart.IsAggregation(fields) {
+		if agg, err = smart.ParseAggregation(fields, groupBy); err != nil {
+			return err.Error()
+		}
+		fields = strings.Join(agg.Fields, `, `)
+	}
 	if par.Node.Attr[`where`] != nil {
 		where = ` where ` + converter.Escape(par.Node.Attr[`where`].(string))
 	}
@@ -448,6 +518,38 @@ func dbfindTag(par parFunc) string {
 	if limit > 250 {
 		limit = 250
 	}
+	if par.Node.Attr[`offset`] != nil {
+		offset = converter.StrToInt(par.Node.Attr[`offset`].(string))
+	}
+	source, _ := par.Node.Attr[`source`].(string)
+	var orderColumn string
+	req := par.Workspace.Paging
+	if req != nil && len(source) > 0 && req.Source == source {
+		offset = int(req.Offset)
+		if len(req.Order) > 0 {
+			column, desc, err := parseOrder(req.Order)
+			if err != nil {
+				return err.Error()
+			}
+			orderColumn = column
+			order = ` order by ` + converter.EscapeName(column)
+			if desc {
+				order += ` desc`
+			}
+			par.Node.Attr[`order`] = req.Order
+		}
+		req.node = par.Node
+	}
+	if agg != nil && par.Node.Attr[`order`] != nil {
+		// aggregation can be sorted only by its result columns
+		if order, err = agg.Order(par.Node.Attr[`order`].(string)); err != nil {
+			return err.Error()
+		}
+		orderColumn = ``
+	}
+	if offset < 0 {
+		offset = 0
+	}
 	if par.Node.Attr[`prefix`] != nil {
 		prefix = par.Node.Attr[`prefix`].(string)
 		limit = 1
@@ -459,22 +561,99 @@ func dbfindTag(par parFunc) string {
 	}
 	sc := par.Workspace.SmartContract
 	tblname := smart.GetTableName(sc, strings.Trim(converter.EscapeName((*par.Pars)[`Name`]), `"`), state)
+	par.Workspace.Deps.Add(tblname, smart.GetTableName(sc, `tables`, state))
 	if sc.VDE && *conf.CheckReadAccess {
 		perm, err = sc.AccessTablePerm(tblname, `read`)
-		cols := strings.Split(fields, `,`)
-		if err != nil || sc.AccessColumns(tblname, &cols, false) != nil {
+		if agg != nil {
+			cols := append([]string{}, agg.Columns...)
+			if err != nil || sc.AccessColumns(tblname, &cols, false) != nil || len(cols) != len(agg.Columns) {
+				return `Access denied`
+			}
+		} else {
+			cols := strings.Split(fields, `,`)
+			if err != nil || sc.AccessColumns(tblname, &cols, false) != nil {
+				return `Access denied`
+			}
+			fields = strings.Join(cols, `,`)
+		}
+	}
+	if len(orderColumn) > 0 {
+		// the requested order must be checked like the columns of the query
+		if !par.Workspace.useQuery() {
+			return ``
+		}
+		if itype, err := model.GetColumnType(tblname, orderColumn); err != nil || len(itype) == 0 {
+			return fmt.Sprintf(`Column %s has not been found`, orderColumn)
+		}
+		if sc.VDE && *conf.CheckReadAccess && sc.AccessColumns(tblname, &[]string{orderColumn}, false) != nil {
 			return `Access denied`
 		}
-		fields = strings.Join(cols, `,`)
 	}
-	if fields != `*` && !strings.Contains(fields, `id`) {
+	var args []interface{}
+	if par.Node.Attr[`search`] != nil {
+		searchCols, err := smart.SearchColumns(sc, tblname, sc.VDE && *conf.CheckReadAccess)
+		if err != nil {
+			return err.Error()
+		}
+		if len(searchCols) == 0 {
+			return fmt.Sprintf(`Table %s doesn't have searchable columns`, tblname)
+		}
+		var cond string
+		cond, args = model.SearchWhere(searchCols, par.Node.Attr[`search`].(string))
+		if len(where) > 0 {
+			where += ` and ` + cond
+		} else {
+			where = ` where ` + cond
+		}
+	}
+	if agg == nil && fields != `*` && !strings.Contains(fields, `id`) {
 		fields += `, id`
 	}
-	list, err := model.GetAll(`select `+fields+` from "`+tblname+`"`+where+order, limit)
+	query := `select ` + fields + ` from "` + tblname + `"` + where
+	if agg != nil {
+		query += agg.GroupBy()
+		// aggregation reads all rows of the table so its cost is limited by the budget
+		if !par.Workspace.useQuery() {
+			return ``
+		}
+		cost, err := querycost.GetQueryCoster(querycost.FormulaQueryCosterType).QueryCost(par.Workspace.db(), query, args...)
+		if err != nil {
+			return err.Error()
+		}
+		if !par.Workspace.useCost(cost) {
+			return ``
+		}
+	}
+	if !par.Workspace.useQuery() {
+		return ``
+	}
+	list, err := model.GetAllTransaction(par.Workspace.db(), query+order+fmt.Sprintf(` offset %d limit %d`, offset, limit),
+		limit, args...)
 	if err != nil {
 		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting all from db")
 		return err.Error()
 	}
+	paging := &Paging{Source: source, Offset: offset, Limit: limit, Count: -1}
+	if par.Node.Attr[`order`] != nil {
+		paging.Order = par.Node.Attr[`order`].(string)
+	}
+	if par.Node.Attr[`countvar`] != nil || req != nil && req.node == par.Node {
+		if !par.Workspace.useQuery() {
+			return ``
+		}
+		paging.Count, err = model.SingleTransaction(par.Workspace.db(), `select count(*) from (`+query+`) as rows`, args...).Int64()
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting count from db")
+			return err.Error()
+		}
+		par.Node.Attr[`count`] = converter.Int64ToStr(paging.Count)
+		if countVar, _ := par.Node.Attr[`countvar`].(string); len(countVar) > 0 {
+			(*par.Workspace.Vars)[countVar] = par.Node.Attr[`count`].(string)
+		}
+	}
+	if !par.Workspace.useRows(len(list)) {
+		return ``
+	}
 	data := make([][]string, 0)
 	cols := make([]string, 0)
 	types := make([]string, 0)
@@ -482,8 +661,14 @@ func dbfindTag(par parFunc) string {
 	defcol := 0
 	for _, item := range list {
 		if lencol == 0 {
-			for key := range item {
-				cols = append(cols, key)
+			if agg != nil {
+				cols = append(cols, agg.Names...)
+			} else {
+				for key := range item {
+					cols = append(cols, key)
+				}
+			}
+			for range cols {
 				types = append(types, `text`)
 			}
 			defcol = len(cols)
@@ -560,7 +745,7 @@ func dbfindTag(par parFunc) string {
 	par.Node.Attr[`columns`] = &cols
 	par.Node.Attr[`types`] = &types
 	par.Node.Attr[`data`] = &data
-	newSource(par)
+	newSource(par, paging)
 	par.Owner.Children = append(par.Owner.Children, par.Node)
 	return ``
 }
@@ -585,22 +770,47 @@ func tailTag(par parFunc) string {
 }
 
 func includeTag(par parFunc) string {
-	if len((*par.Pars)[`Name`]) >= 0 && len((*par.Workspace.Vars)[`_include`]) < 5 {
-		pattern, err := model.Single(`select value from "`+(*par.Workspace.Vars)[`ecosystem_id`]+`_blocks" where name=?`, (*par.Pars)[`Name`]).String()
+	name := (*par.Pars)[`Name`]
+	if len(name) == 0 {
+		return ``
+	}
+	if err := checkRecursion(par.Workspace.includes, name); err != nil {
+		return err.Error()
+	}
+	if !par.Workspace.useQuery() {
+		return ``
+	}
+	par.Workspace.Deps.Add((*par.Workspace.Vars)[`ecosystem_id`] + `_blocks`)
+	block, err := model.GetOneRowTransaction(par.Workspace.db(), `select * from "`+(*par.Workspace.Vars)[`ecosystem_id`]+`_blocks" where name=?`,
+		name).String()
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting block by name")
+		return err.Error()
+	}
+	if len(block[`value`]) == 0 {
+		return ``
+	}
+	workspace := par.Workspace
+	// the blocks without parameters share the variables of the caller
+	if len(block[`params`]) > 0 || len((*par.Pars)[`Params`]) > 0 {
+		vars, err := blockVars(name, *workspace.Vars, parseBlockParams(block[`params`]),
+			parseIncludeParams((*par.Pars)[`Params`]))
 		if err != nil {
-			log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting block by name")
 			return err.Error()
 		}
-		if len(pattern) > 0 {
-			root := node{}
-			(*par.Workspace.Vars)[`_include`] += `1`
-			process(pattern, &root, par.Workspace)
-			(*par.Workspace.Vars)[`_include`] = (*par.Workspace.Vars)[`_include`][:len((*par.Workspace.Vars)[`_include`])-1]
-			for _, item := range root.Children {
-				par.Owner.Children = append(par.Owner.Children, item)
-			}
-		}
+		callerVars, callerSources := workspace.Vars, workspace.Sources
+		workspace.Vars, workspace.Sources = &vars, nil
+		defer func() {
+			workspace.Vars, workspace.Sources = callerVars, callerSources
+		}()
+	}
+	if !workspace.enterInclude(name) {
+		return ``
 	}
+	root := node{}
+	process(block[`value`], &root, workspace)
+	workspace.leaveInclude()
+	par.Owner.Children = append(par.Owner.Children, root.Children...)
 	return ``
 }
 
@@ -638,6 +848,15 @@ func tableTag(par parFunc) string {
 			par.Node.Attr[`columns`] = imap
 		}
 	}
+	if par.Node.Attr[`paging`] != nil {
+		// the client regenerates only the source with the new offset or order
+		delete(par.Node.Attr, `paging`)
+		if source, ok := par.Node.Attr[`source`].(string); ok && par.Workspace.Sources != nil {
+			if src := (*par.Workspace.Sources)[source]; src.Paging != nil {
+				par.Node.Attr[`paging`] = src.Paging
+			}
+		}
+	}
 	return ``
 }
 
@@ -676,6 +895,94 @@ func buttonTag(par parFunc) string {
 	return ``
 }
 
+// formInput returns the input of the form for the field of the contract
+func formInput(field *script.FieldInfo) *node {
+	name := field.Name
+	switch {
+	case strings.Contains(field.Tags, `hidden`):
+		return &node{Tag: `input`, Attr: map[string]interface{}{`name`: name, `type`: `hidden`}}
+	case strings.Contains(field.Tags, `image`):
+		return &node{Tag: `imageinput`, Attr: map[string]interface{}{`name`: name}}
+	}
+	inputType := `text`
+	switch {
+	case strings.Contains(field.Tags, `date`):
+		inputType = `date`
+	case strings.Contains(field.Tags, `text`):
+		inputType = `textarea`
+	case field.Type.String() == script.Decimal || field.Type.String() == `int64` ||
+		field.Type.String() == `float64`:
+		inputType = `number`
+	case field.Type.String() == `bool`:
+		inputType = `checkbox`
+	case field.Type.String() == `[]uint8`:
+		inputType = `file`
+	}
+	input := &node{Tag: `input`, Attr: map[string]interface{}{`name`: name, `type`: inputType}}
+	rules := make(map[string]interface{})
+	if !strings.Contains(field.Tags, `optional`) && inputType != `checkbox` {
+		rules[`required`] = `true`
+	}
+	switch field.Type.String() {
+	case `int64`:
+		rules[`pattern`] = `^-?[0-9]+$`
+	case script.Decimal:
+		rules[`min`] = `0`
+	}
+	if len(rules) > 0 {
+		input.Attr[`validate`] = rules
+	}
+	return input
+}
+
+// contractFormTag generates the form with the inputs for the data fields of the contract
+// and the button which calls the contract. The nodes are built directly, so the values
+// of the parameters are never parsed as the template.
+func contractFormTag(par parFunc) string {
+	name := (*par.Pars)[`Contract`]
+	if len(name) == 0 {
+		return ``
+	}
+	sc := par.Workspace.SmartContract
+	state := converter.StrToInt64((*par.Workspace.Vars)[`ecosystem_id`])
+	par.Workspace.Deps.Add(smart.GetTableName(sc, `contracts`, state))
+	contract := smart.VMGetContract(sc.VM, name, uint32(state))
+	if contract == nil {
+		return fmt.Sprintf(`Contract %s has not been found`, name)
+	}
+	info := contract.Block.Info.(*script.ContractInfo)
+	form := &node{Tag: `form`, Attr: make(map[string]interface{})}
+	if class := (*par.Pars)[`Class`]; len(class) > 0 {
+		form.Attr[`class`] = class
+	}
+	params := make(map[string]interface{})
+	if info.Tx != nil {
+		for _, field := range *info.Tx {
+			if strings.Contains(field.Tags, `signature`) {
+				continue
+			}
+			if strings.Contains(field.Tags, `hidden`) {
+				form.Children = append(form.Children, formInput(field))
+			} else {
+				label := &node{Tag: `label`, Attr: map[string]interface{}{`for`: field.Name},
+					Children: []*node{{Tag: `text`, Text: field.Name}}}
+				form.Children = append(form.Children, &node{Tag: `div`,
+					Attr:     map[string]interface{}{`class`: `form-group`},
+					Children: []*node{label, formInput(field)}})
+			}
+			params[field.Name] = map[string]interface{}{`type`: `text`, `text`: field.Name}
+		}
+	}
+	button := &node{Tag: `button`, Attr: map[string]interface{}{`class`: `btn btn-primary`, `contract`: name},
+		Children: []*node{{Tag: `text`, Text: `Submit`}}}
+	if len(params) > 0 {
+		button.Attr[`params`] = params
+	}
+	form.Children = append(form.Children, button)
+	par.Owner.Children = append(par.Owner.Children, form)
+	return ``
+}
+
 func ifTag(par parFunc) string {
 	cond := ifValue((*par.Pars)[`Condition`], par.Workspace)
 	if cond {
@@ -741,37 +1048,178 @@ func elseFull(par parFunc) string {
 	return ``
 }
 
-func dateTimeTag(par parFunc) string {
-	datetime := (*par.Pars)[`DateTime`]
-	if len(datetime) == 0 || datetime[0] < '0' || datetime[0] > '9' {
-		return ``
+// parseTime parses the time like YYYY-MM-DD HH:MI:SS in UTC, the unix time or now
+func parseTime(par parFunc, value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == `now` {
+		par.Workspace.Deps.Volatile = true
+		return timeNow().UTC(), nil
+	}
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil && len(value) > 8 {
+		return time.Unix(unix, 0).UTC(), nil
 	}
 	defTime := `1970-01-01T00:00:00`
-	lenTime := len(datetime)
-	if lenTime < len(defTime) {
-		datetime += defTime[lenTime:]
+	if len(value) < len(defTime) {
+		value += defTime[len(value):]
+	}
+	return time.Parse(`2006-01-02T15:04:05`, strings.Replace(value[:19], ` `, `T`, -1))
+}
+
+// locale returns Locale parameter or the language of the page
+func locale(par parFunc) string {
+	if lang := (*par.Pars)[`Locale`]; len(lang) > 0 {
+		return lang
+	}
+	return (*par.Workspace.Vars)[`lang`]
+}
+
+func dateTimeTag(par parFunc) string {
+	datetime := strings.TrimSpace((*par.Pars)[`DateTime`])
+	if len(datetime) == 0 || (datetime[0] < '0' || datetime[0] > '9') && datetime != `now` {
+		return ``
 	}
-	itime, err := time.Parse(`2006-01-02T15:04:05`, strings.Replace(datetime[:19], ` `, `T`, -1))
+	itime, err := parseTime(par, datetime)
 	if err != nil {
 		return err.Error()
 	}
+	if name := (*par.Pars)[`Location`]; len(name) > 0 {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return err.Error()
+		}
+		itime = itime.In(loc)
+	}
 	format := (*par.Pars)[`Format`]
-	if len(format) == 0 {
+	switch format {
+	case ``:
+		lang := locale(par)
+		par.Workspace.Deps.Add(smart.GetTableName(par.Workspace.SmartContract, `languages`,
+			converter.StrToInt64((*par.Workspace.Vars)[`ecosystem_id`])))
 		format, _ = language.LangText(`timeformat`, converter.StrToInt((*par.Workspace.Vars)[`ecosystem_id`]),
-			(*par.Workspace.Vars)[`lang`], par.Workspace.SmartContract.VDE)
+			lang, par.Workspace.SmartContract.VDE)
 		if format == `timeformat` {
-			format = `2006-01-02 15:04:05`
+			if len((*par.Pars)[`Locale`]) > 0 {
+				format = language.DateFormat(lang, `long`)
+			} else {
+				format = `2006-01-02 15:04:05`
+			}
+		}
+	case `short`, `medium`, `long`, `time`:
+		format = language.DateFormat(locale(par), format)
+	}
+	return itime.Format(language.TimeLayout(format))
+}
+
+// addDateTag adds the interval like "+1 month -2 days" to the date
+func addDateTag(par parFunc) string {
+	if len(strings.TrimSpace((*par.Pars)[`DateTime`])) == 0 {
+		return ``
+	}
+	itime, err := parseTime(par, (*par.Pars)[`DateTime`])
+	if err != nil {
+		return err.Error()
+	}
+	fields := strings.Fields(strings.ToLower((*par.Pars)[`Interval`]))
+	if len(fields)%2 != 0 {
+		return fmt.Sprintf(`Interval %s is not valid`, (*par.Pars)[`Interval`])
+	}
+	for i := 0; i < len(fields); i += 2 {
+		count, err := strconv.Atoi(strings.TrimPrefix(fields[i], `+`))
+		if err != nil {
+			return fmt.Sprintf(`Interval %s is not valid`, (*par.Pars)[`Interval`])
+		}
+		switch strings.TrimSuffix(fields[i+1], `s`) {
+		case `year`:
+			itime = itime.AddDate(count, 0, 0)
+		case `month`:
+			itime = itime.AddDate(0, count, 0)
+		case `week`:
+			itime = itime.AddDate(0, 0, 7*count)
+		case `day`:
+			itime = itime.AddDate(0, 0, count)
+		case `hour`:
+			itime = itime.Add(time.Duration(count) * time.Hour)
+		case `minute`:
+			itime = itime.Add(time.Duration(count) * time.Minute)
+		case `second`:
+			itime = itime.Add(time.Duration(count) * time.Second)
+		default:
+			return fmt.Sprintf(`Unknown unit %s`, fields[i+1])
+		}
+	}
+	format := (*par.Pars)[`Format`]
+	if len(format) == 0 {
+		format = `YYYY-MM-DD HH:MI:SS`
+	}
+	return itime.Format(language.TimeLayout(format))
+}
+
+// timeAgoTag returns the relative time like "5 minutes ago" or "in 2 days"
+func timeAgoTag(par parFunc) string {
+	if len(strings.TrimSpace((*par.Pars)[`DateTime`])) == 0 {
+		return ``
+	}
+	itime, err := parseTime(par, (*par.Pars)[`DateTime`])
+	if err != nil {
+		return err.Error()
+	}
+	par.Workspace.Deps.Volatile = true
+	lang := locale(par)
+	state := converter.StrToInt((*par.Workspace.Vars)[`ecosystem_id`])
+	par.Workspace.Deps.Add(smart.GetTableName(par.Workspace.SmartContract, `languages`, int64(state)))
+	message := func(name string, args map[string]string) string {
+		if ret, ok := language.LangFormat(name, state, lang, par.Workspace.SmartContract.VDE, args); ok {
+			return ret
+		}
+		ret, err := language.FormatMessage(timeAgoMessages[name], lang, args)
+		if err != nil {
+			return err.Error()
+		}
+		return ret
+	}
+	diff := timeNow().Sub(itime)
+	name := `timeago_past`
+	if diff < 0 {
+		diff, name = -diff, `timeago_future`
+	}
+	for _, unit := range timeUnits {
+		if count := int64(diff / unit.duration); count > 0 {
+			period := message(`timeago_`+unit.name, map[string]string{`n`: strconv.FormatInt(count, 10)})
+			return message(name, map[string]string{`time`: period})
 		}
 	}
-	format = strings.Replace(format, `YYYY`, `2006`, -1)
-	format = strings.Replace(format, `YY`, `06`, -1)
-	format = strings.Replace(format, `MM`, `01`, -1)
-	format = strings.Replace(format, `DD`, `02`, -1)
-	format = strings.Replace(format, `HH`, `15`, -1)
-	format = strings.Replace(format, `MI`, `04`, -1)
-	format = strings.Replace(format, `SS`, `05`, -1)
+	return message(`timeago_now`, nil)
+}
 
-	return itime.Format(format)
+// moneyTag returns Exp divided by 10^Digit and formatted by Locale
+func moneyTag(par parFunc) string {
+	value, err := decimal.NewFromString(strings.TrimSpace((*par.Pars)[`Exp`]))
+	if err != nil {
+		return err.Error()
+	}
+	var digit int
+	if len((*par.Pars)[`Digit`]) > 0 {
+		digit = converter.StrToInt((*par.Pars)[`Digit`])
+	} else {
+		if !par.Workspace.useQuery() {
+			return ``
+		}
+		par.Workspace.Deps.Add(smart.GetTableName(par.Workspace.SmartContract, `parameters`,
+			converter.StrToInt64((*par.Workspace.Vars)[`ecosystem_id`])))
+		digit = converter.StrToInt(smart.EcosysParam(par.Workspace.SmartContract, `money_digit`))
+	}
+	if digit < 0 || digit > maxMoneyDigit {
+		return fmt.Sprintf(`Digit %d is out of range 0..%d`, digit, maxMoneyDigit)
+	}
+	style := `integer`
+	if digit > 0 {
+		style = `.` + strings.Repeat(`0`, digit)
+	}
+	ret, err := language.FormatNumber(locale(par), value.Mul(decimal.New(1, int32(-digit))).String(), style)
+	if err != nil {
+		return err.Error()
+	}
+	return ret
 }
 
 func cmpTimeTag(par parFunc) string {
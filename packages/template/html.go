@@ -0,0 +1,486 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// HTMLOptions are the options of HTML rendering
+type HTMLOptions struct {
+	PagePath string // the prefix of the links to pages
+}
+
+// validateAttrs are the rules of Validate tail which are supported by HTML inputs
+var validateAttrs = []string{`max`, `maxlength`, `min`, `minlength`, `pattern`, `required`}
+
+// defaultColors are used by charts without Colors
+var defaultColors = []string{`#3366cc`, `#dc3912`, `#ff9900`, `#109618`, `#990099`, `#0099c6`}
+
+type htmlSource struct {
+	columns []string
+	types   []string
+	data    [][]string
+}
+
+type htmlWriter struct {
+	buf     bytes.Buffer
+	opts    HTMLOptions
+	sources map[string]htmlSource
+	title   string
+}
+
+// HTMLPage contains the rendered page
+type HTMLPage struct {
+	Title string
+	Body  string
+}
+
+// Tree2HTML renders the JSON tree of the template to HTML
+func Tree2HTML(tree []byte, opts HTMLOptions) (*HTMLPage, error) {
+	var nodes []*node
+	if err := unmarshalTree(tree, &nodes); err != nil {
+		return nil, err
+	}
+	w := &htmlWriter{opts: opts, sources: make(map[string]htmlSource)}
+	w.collectSources(nodes)
+	w.nodes(nodes)
+	return &HTMLPage{Title: w.title, Body: w.buf.String()}, nil
+}
+
+// Menu2HTML renders the JSON tree of the menu to HTML list
+func Menu2HTML(tree []byte, opts HTMLOptions) (string, error) {
+	var nodes []*node
+	if err := unmarshalTree(tree, &nodes); err != nil {
+		return ``, err
+	}
+	w := &htmlWriter{opts: opts, sources: make(map[string]htmlSource)}
+	w.buf.WriteString(`<ul class="menu">`)
+	w.nodes(nodes)
+	w.buf.WriteString(`</ul>`)
+	return w.buf.String(), nil
+}
+
+func unmarshalTree(tree []byte, nodes *[]*node) error {
+	if len(tree) == 0 {
+		return nil
+	}
+	return json.Unmarshal(tree, nodes)
+}
+
+// escape escapes the text of the tree which can be already escaped by the template engine
+func escape(s string) string {
+	return html.EscapeString(html.UnescapeString(s))
+}
+
+func strValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ``
+	case string:
+		return val
+	case float64:
+		return decimal.NewFromFloat(val).String()
+	case bool:
+		return fmt.Sprint(val)
+	default:
+		out, _ := json.Marshal(val)
+		return string(out)
+	}
+}
+
+func strList(v interface{}) []string {
+	list, _ := v.([]interface{})
+	ret := make([]string, len(list))
+	for i, item := range list {
+		ret[i] = strValue(item)
+	}
+	return ret
+}
+
+func (n *node) attr(name string) string {
+	return strValue(n.Attr[name])
+}
+
+func (w *htmlWriter) collectSources(nodes []*node) {
+	for _, n := range nodes {
+		if (n.Tag == `data` || n.Tag == `dbfind`) && len(n.attr(`source`)) > 0 {
+			src := htmlSource{columns: strList(n.Attr[`columns`]), types: strList(n.Attr[`types`])}
+			rows, _ := n.Attr[`data`].([]interface{})
+			for _, row := range rows {
+				src.data = append(src.data, strList(row))
+			}
+			w.sources[n.attr(`source`)] = src
+		}
+		w.collectSources(n.Children)
+	}
+}
+
+func (w *htmlWriter) write(format string, args ...interface{}) {
+	fmt.Fprintf(&w.buf, format, args...)
+}
+
+// attrs writes the attributes of the node with the specified names
+func (w *htmlWriter) attrs(n *node, names ...string) {
+	for _, name := range names {
+		if val := n.attr(name); len(val) > 0 {
+			w.write(` %s="%s"`, name, escape(val))
+		}
+	}
+}
+
+// open writes the opening tag with CSS class and style passthrough
+func (w *htmlWriter) open(tag string, n *node, names ...string) {
+	w.write(`<%s`, tag)
+	w.attrs(n, append([]string{`class`, `style`}, names...)...)
+	w.buf.WriteByte('>')
+}
+
+func (w *htmlWriter) element(tag string, n *node, names ...string) {
+	w.open(tag, n, names...)
+	w.nodes(n.Children)
+	w.write(`</%s>`, tag)
+}
+
+// pageLink returns the link to the page with PageParams
+func (w *htmlWriter) pageLink(n *node) string {
+	link := w.opts.PagePath + url.PathEscape(n.attr(`page`))
+	params, _ := n.Attr[`pageparams`].(map[string]interface{})
+	values := url.Values{}
+	for key, item := range params {
+		if par, ok := item.(map[string]interface{}); ok && strValue(par[`type`]) == `text` {
+			values.Set(key, strValue(par[`text`]))
+		}
+	}
+	if len(values) > 0 {
+		link += `?` + values.Encode()
+	}
+	return link
+}
+
+func (w *htmlWriter) nodes(nodes []*node) {
+	for _, n := range nodes {
+		w.node(n)
+	}
+}
+
+func (w *htmlWriter) node(n *node) {
+	switch n.Tag {
+	case tagText:
+		w.buf.WriteString(escape(n.Text))
+	case `div`, `p`, `span`, `em`, `strong`, `code`, `form`:
+		w.element(n.Tag, n)
+	case `label`:
+		w.element(`label`, n, `for`)
+	case `linkpage`:
+		w.write(`<a href="%s"`, escape(w.pageLink(n)))
+		w.attrs(n, `class`, `style`)
+		w.buf.WriteByte('>')
+		w.nodes(n.Children)
+		w.buf.WriteString(`</a>`)
+	case `button`:
+		w.button(n)
+	case `addtoolbutton`:
+		w.write(`<a class="toolbutton" href="%s">%s</a>`, escape(w.pageLink(n)), escape(n.attr(`title`)))
+	case `image`:
+		w.write(`<img`)
+		w.attrs(n, `src`, `alt`, `class`, `style`)
+		w.buf.WriteByte('>')
+	case `imageinput`:
+		w.write(`<input type="file" accept="image/*"`)
+		w.attrs(n, `name`)
+		w.buf.WriteByte('>')
+	case `input`:
+		w.input(n)
+	case `select`:
+		w.selectTag(n)
+	case `radiogroup`:
+		w.radioGroup(n)
+	case `table`:
+		w.table(n)
+	case `chart`:
+		w.chart(n)
+	case `menuitem`:
+		w.write(`<li><a href="%s">%s</a></li>`, escape(w.pageLink(n)), escape(n.attr(`title`)))
+	case `menugroup`:
+		w.write(`<li><span>%s</span><ul>`, escape(n.attr(`title`)))
+		w.nodes(n.Children)
+		w.buf.WriteString(`</ul></li>`)
+	case `settitle`:
+		w.title = n.attr(`title`)
+	case `data`, `dbfind`, `setvar`, `inputerr`:
+	default:
+		w.nodes(n.Children)
+	}
+}
+
+func (w *htmlWriter) button(n *node) {
+	if len(n.attr(`contract`)) == 0 && len(n.attr(`page`)) > 0 {
+		w.write(`<a href="%s"`, escape(w.pageLink(n)))
+		w.attrs(n, `class`, `style`)
+		w.buf.WriteByte('>')
+		w.nodes(n.Children)
+		w.buf.WriteString(`</a>`)
+		return
+	}
+	// contracts must be signed by the client, so they are described by data attributes
+	w.write(`<button type="button"`)
+	w.attrs(n, `class`, `style`)
+	if contract := n.attr(`contract`); len(contract) > 0 {
+		w.write(` data-contract="%s"`, escape(contract))
+	}
+	if page := n.attr(`page`); len(page) > 0 {
+		w.write(` data-page="%s"`, escape(w.pageLink(n)))
+	}
+	w.buf.WriteByte('>')
+	w.nodes(n.Children)
+	w.buf.WriteString(`</button>`)
+}
+
+func (w *htmlWriter) input(n *node) {
+	inputType := n.attr(`type`)
+	if len(inputType) == 0 {
+		inputType = `text`
+	}
+	if inputType == `textarea` {
+		w.open(`textarea`, n, `name`, `placeholder`)
+		w.write(`%s</textarea>`, escape(n.attr(`value`)))
+		return
+	}
+	w.write(`<input type="%s"`, escape(inputType))
+	w.attrs(n, `name`, `class`, `style`, `placeholder`, `value`)
+	if len(n.attr(`disabled`)) > 0 && n.attr(`disabled`) != `false` {
+		w.buf.WriteString(` disabled`)
+	}
+	if rules, ok := n.Attr[`validate`].(map[string]interface{}); ok {
+		for _, name := range validateAttrs {
+			if val := strValue(rules[name]); len(val) > 0 {
+				w.write(` %s="%s"`, name, escape(val))
+			}
+		}
+	}
+	w.buf.WriteByte('>')
+}
+
+// column returns the index of the column in the source or -1
+func (src htmlSource) column(name string) int {
+	for i, col := range src.columns {
+		if col == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (src htmlSource) value(row []string, col int) string {
+	if col < 0 || col >= len(row) {
+		return ``
+	}
+	return row[col]
+}
+
+func (w *htmlWriter) selectTag(n *node) {
+	src := w.sources[n.attr(`source`)]
+	name, value := src.column(n.attr(`namecolumn`)), src.column(n.attr(`valuecolumn`))
+	w.open(`select`, n, `name`)
+	for _, row := range src.data {
+		val := src.value(row, value)
+		w.write(`<option value="%s"`, escape(val))
+		if val == n.attr(`value`) {
+			w.buf.WriteString(` selected`)
+		}
+		w.write(`>%s</option>`, escape(src.value(row, name)))
+	}
+	w.buf.WriteString(`</select>`)
+}
+
+func (w *htmlWriter) radioGroup(n *node) {
+	src := w.sources[n.attr(`source`)]
+	name, value := src.column(n.attr(`namecolumn`)), src.column(n.attr(`valuecolumn`))
+	w.open(`div`, n)
+	for _, row := range src.data {
+		val := src.value(row, value)
+		w.write(`<label><input type="radio" name="%s" value="%s"`, escape(n.attr(`name`)), escape(val))
+		if val == n.attr(`value`) {
+			w.buf.WriteString(` checked`)
+		}
+		w.write(`> %s</label>`, escape(src.value(row, name)))
+	}
+	w.buf.WriteString(`</div>`)
+}
+
+func (w *htmlWriter) table(n *node) {
+	src := w.sources[n.attr(`source`)]
+	titles := make([]string, 0)
+	cols := make([]int, 0)
+	if list, ok := n.Attr[`columns`].([]interface{}); ok {
+		for _, item := range list {
+			col, _ := item.(map[string]interface{})
+			titles = append(titles, strValue(col[`Title`]))
+			cols = append(cols, src.column(strValue(col[`Name`])))
+		}
+	} else {
+		for i, col := range src.columns {
+			titles = append(titles, col)
+			cols = append(cols, i)
+		}
+	}
+	w.open(`table`, n)
+	w.buf.WriteString(`<thead><tr>`)
+	for _, title := range titles {
+		w.write(`<th>%s</th>`, escape(title))
+	}
+	w.buf.WriteString(`</tr></thead><tbody>`)
+	for _, row := range src.data {
+		w.buf.WriteString(`<tr>`)
+		for _, col := range cols {
+			w.buf.WriteString(`<td>`)
+			if col >= 0 && col < len(src.types) && src.types[col] == `tags` {
+				var nodes []*node
+				if err := json.Unmarshal([]byte(src.value(row, col)), &nodes); err == nil {
+					w.nodes(nodes)
+				}
+			} else {
+				w.buf.WriteString(escape(src.value(row, col)))
+			}
+			w.buf.WriteString(`</td>`)
+		}
+		w.buf.WriteString(`</tr>`)
+	}
+	w.buf.WriteString(`</tbody></table>`)
+}
+
+const (
+	chartWidth  = 400
+	chartHeight = 200
+)
+
+// chart renders Chart as SVG image
+func (w *htmlWriter) chart(n *node) {
+	src := w.sources[n.attr(`source`)]
+	label, value := src.column(n.attr(`fieldlabel`)), src.column(n.attr(`fieldvalue`))
+	colors := strList(n.Attr[`colors`])
+	if len(colors) == 0 {
+		colors = defaultColors
+	}
+	labels := make([]string, len(src.data))
+	values := make([]float64, len(src.data))
+	var max, sum float64
+	for i, row := range src.data {
+		labels[i] = src.value(row, label)
+		if val, err := decimal.NewFromString(src.value(row, value)); err == nil {
+			values[i], _ = val.Float64()
+		}
+		if values[i] < 0 {
+			values[i] = 0
+		}
+		max = math.Max(max, values[i])
+		sum += values[i]
+	}
+	w.write(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %[1]d %[2]d"`,
+		chartWidth, chartHeight)
+	w.attrs(n, `class`, `style`)
+	w.buf.WriteByte('>')
+	switch n.attr(`type`) {
+	case `pie`:
+		w.pieChart(labels, values, sum, colors)
+	case `line`:
+		w.lineChart(labels, values, max, colors)
+	default:
+		w.barChart(labels, values, max, colors)
+	}
+	w.buf.WriteString(`</svg>`)
+}
+
+func (w *htmlWriter) barChart(labels []string, values []float64, max float64, colors []string) {
+	if len(values) == 0 || max == 0 {
+		return
+	}
+	step := float64(chartWidth) / float64(len(values))
+	for i, val := range values {
+		height := val / max * (chartHeight - 20)
+		w.write(`<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s</title></rect>`,
+			float64(i)*step+2, chartHeight-20-height, step-4, height, escape(colors[i%len(colors)]),
+			escape(labels[i]))
+		w.write(`<text x="%.1f" y="%d" text-anchor="middle" font-size="10">%s</text>`,
+			float64(i)*step+step/2, chartHeight-5, escape(labels[i]))
+	}
+}
+
+func (w *htmlWriter) lineChart(labels []string, values []float64, max float64, colors []string) {
+	if len(values) == 0 || max == 0 {
+		return
+	}
+	step := float64(chartWidth) / float64(len(values))
+	points := make([]string, len(values))
+	for i, val := range values {
+		points[i] = fmt.Sprintf(`%.1f,%.1f`, float64(i)*step+step/2, chartHeight-20-val/max*(chartHeight-20))
+		w.write(`<text x="%.1f" y="%d" text-anchor="middle" font-size="10">%s</text>`,
+			float64(i)*step+step/2, chartHeight-5, escape(labels[i]))
+	}
+	w.write(`<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`, escape(colors[0]),
+		strings.Join(points, ` `))
+}
+
+func (w *htmlWriter) pieChart(labels []string, values []float64, sum float64, colors []string) {
+	if sum == 0 {
+		return
+	}
+	const radius = chartHeight/2 - 10
+	cx, cy := float64(chartHeight/2), float64(chartHeight/2)
+	angle := -math.Pi / 2
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return values[order[i]] > values[order[j]] })
+	for _, i := range order {
+		if values[i] == 0 {
+			continue
+		}
+		color := escape(colors[i%len(colors)])
+		if values[i] == sum {
+			w.write(`<circle cx="%.1f" cy="%.1f" r="%d" fill="%s"><title>%s</title></circle>`, cx, cy,
+				radius, color, escape(labels[i]))
+			return
+		}
+		next := angle + 2*math.Pi*values[i]/sum
+		large := 0
+		if next-angle > math.Pi {
+			large = 1
+		}
+		w.write(`<path d="M%.1f,%.1f L%.1f,%.1f A%d,%d 0 %d,1 %.1f,%.1f Z" fill="%s"><title>%s</title></path>`,
+			cx, cy, cx+radius*math.Cos(angle), cy+radius*math.Sin(angle), radius, radius, large,
+			cx+radius*math.Cos(next), cy+radius*math.Sin(next), color, escape(labels[i]))
+		angle = next
+	}
+}
@@ -0,0 +1,112 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package template
+
+import (
+	"fmt"
+	"strings"
+)
+
+// scopeVars are the variables which are passed into the isolated scope of the block
+var scopeVars = []string{`_full`, `ecosystem_id`, `key_id`, `lang`, `vde`}
+
+// blockParam is the parameter declared in the params column of the block
+type blockParam struct {
+	Name     string
+	Default  string
+	Required bool
+}
+
+// parseBlockParams parses the declaration of block parameters like "Title, Class=text-center".
+// The parameters without default values are required.
+func parseBlockParams(decl string) []blockParam {
+	ret := make([]blockParam, 0)
+	for _, item := range strings.Split(decl, `,`) {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+		if off := strings.IndexByte(item, '='); off >= 0 {
+			ret = append(ret, blockParam{Name: strings.TrimSpace(item[:off]),
+				Default: strings.TrimSpace(item[off+1:])})
+		} else {
+			ret = append(ret, blockParam{Name: item, Required: true})
+		}
+	}
+	return ret
+}
+
+// parseIncludeParams parses the Params of Include like "a=1,b=text"
+func parseIncludeParams(input string) map[string]string {
+	ret := make(map[string]string)
+	for _, item := range strings.Split(input, `,`) {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+		if off := strings.IndexByte(item, '='); off >= 0 {
+			ret[strings.TrimSpace(item[:off])] = strings.TrimSpace(item[off+1:])
+		} else {
+			ret[item] = ``
+		}
+	}
+	return ret
+}
+
+// blockVars returns the variables of the isolated scope of the block
+func blockVars(name string, vars map[string]string, decl []blockParam, params map[string]string) (map[string]string, error) {
+	ret := make(map[string]string)
+	for _, key := range scopeVars {
+		if val, ok := vars[key]; ok {
+			ret[key] = val
+		}
+	}
+	declared := make(map[string]bool)
+	for _, par := range decl {
+		declared[par.Name] = true
+		if val, ok := params[par.Name]; ok {
+			ret[par.Name] = val
+		} else if par.Required {
+			return nil, fmt.Errorf(`Parameter %s of block %s is required`, par.Name, name)
+		} else {
+			ret[par.Name] = par.Default
+		}
+	}
+	for key := range params {
+		if !declared[key] {
+			return nil, fmt.Errorf(`Block %s doesn't have parameter %s`, name, key)
+		}
+	}
+	return ret, nil
+}
+
+// checkRecursion returns an error if the block is already being included
+func checkRecursion(includes []string, name string) error {
+	for i, item := range includes {
+		if item == name {
+			return fmt.Errorf(`Recursive include of block %s: %s -> %s`, name,
+				strings.Join(includes[i:], ` -> `), name)
+		}
+	}
+	return nil
+}
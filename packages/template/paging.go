@@ -0,0 +1,105 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package template
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/GACHAIN/go-gachain/packages/consts"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Paging describes the page of DBFind source which is shown by Table
+type Paging struct {
+	Source string `json:"source"`
+	Offset int    `json:"offset"`
+	Limit  int    `json:"limit"`
+	Count  int64  `json:"count"` // -1 if DBFind doesn't have Count tail
+	Order  string `json:"order,omitempty"`
+}
+
+// SourceRequest is the request of regenerating the source with another offset or order
+type SourceRequest struct {
+	Source string
+	Offset int64
+	Order  string
+	node   *node // the node of the regenerated source
+}
+
+var reOrder = regexp.MustCompile(`^([a-z_][a-z0-9_]*)(\s+(asc|desc))?$`)
+
+// parseOrder checks the requested order and returns the column and the direction of sorting
+func parseOrder(order string) (column string, desc bool, err error) {
+	match := reOrder.FindStringSubmatch(strings.ToLower(strings.TrimSpace(order)))
+	if match == nil {
+		return ``, false, fmt.Errorf(`Order %s is not valid`, order)
+	}
+	return match[1], match[3] == `desc`, nil
+}
+
+// countTag enables counting of all rows of DBFind and stores the number in CountVar
+func countTag(par parFunc) string {
+	par.Owner.Attr[`countvar`] = strings.TrimSpace((*par.Pars)[`CountVar`])
+	return ``
+}
+
+// pagingTag marks Table as paged and defines the columns which can be sorted
+func pagingTag(par parFunc) string {
+	setAllAttr(par)
+	par.Owner.Attr[`paging`] = true
+	if sort := strings.TrimSpace((*par.Pars)[`Sort`]); len(sort) > 0 {
+		cols := strings.Split(sort, `,`)
+		for i, col := range cols {
+			cols[i] = strings.TrimSpace(col)
+		}
+		par.Owner.Attr[`sortable`] = cols
+	}
+	return ``
+}
+
+// Template2Source processes the template and returns JSON of the single source which is
+// regenerated with the requested offset and order
+func Template2Source(ctx context.Context, input string, req *SourceRequest, budget *Budget,
+	vars *map[string]string) ([]byte, error) {
+	root := node{}
+	workspace := newWorkspace(ctx, budget, nil, vars)
+	workspace.Paging = req
+	process(input, &root, workspace)
+	if workspace.Err != nil {
+		return nil, workspace.Err
+	}
+	if req.node == nil {
+		return nil, nil
+	}
+	out, err := json.Marshal(req.node)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling source to json")
+		return nil, err
+	}
+	return out, nil
+}
@@ -23,6 +23,7 @@
 package template
 
 import (
+	"context"
 	"encoding/json"
 	"html"
 	"regexp"
@@ -33,6 +34,7 @@ import (
 	"github.com/GACHAIN/go-gachain/packages/consts"
 	"github.com/GACHAIN/go-gachain/packages/converter"
 	"github.com/GACHAIN/go-gachain/packages/language"
+	"github.com/GACHAIN/go-gachain/packages/model"
 	"github.com/GACHAIN/go-gachain/packages/smart"
 	"github.com/GACHAIN/go-gachain/packages/utils/tx"
 
@@ -57,13 +59,20 @@ type node struct {
 type Source struct {
 	Columns *[]string
 	Data    *[][]string
+	Paging  *Paging
 }
 
 type Workspace struct {
 	Sources       *map[string]Source
 	Vars          *map[string]string
 	SmartContract *smart.SmartContract
-	Timeout       *bool
+	Ctx           context.Context
+	Budget        *Budget
+	Deps          *Deps
+	Err           error
+	Paging        *SourceRequest
+	includes      []string // names of the blocks which are being included
+	dbConn        *model.DbTransaction
 }
 
 type parFunc struct {
@@ -93,7 +102,7 @@ type forTails struct {
 	Tails map[string]tailInfo
 }
 
-func newSource(par parFunc) {
+func newSource(par parFunc, paging *Paging) {
 	if par.Node.Attr[`source`] == nil {
 		return
 	}
@@ -104,6 +113,7 @@ func newSource(par parFunc) {
 	(*par.Workspace.Sources)[par.Node.Attr[`source`].(string)] = Source{
 		Columns: par.Node.Attr[`columns`].(*[]string),
 		Data:    par.Node.Attr[`data`].(*[][]string),
+		Paging:  paging,
 	}
 }
 
@@ -299,42 +309,15 @@ func callFunc(curFunc *tplFunc, owner *node, workspace *Workspace, params *[][]r
 		out     string
 		curNode node
 	)
-	pars := make(map[string]string)
 	parFunc := parFunc{
 		Workspace: workspace,
 	}
-	if *workspace.Timeout {
+	if workspace.aborted() || !workspace.useNode() {
 		return
 	}
-	if curFunc.Params == `*` {
-		for i, v := range *params {
-			val := strings.TrimSpace(string(v))
-			off := strings.IndexByte(val, ':')
-			if off != -1 {
-				pars[val[:off]] = macro(strings.Trim(val[off+1:], "\t\r\n \"`"), workspace.Vars)
-			} else {
-				pars[strconv.Itoa(i)] = macro(val, workspace.Vars)
-			}
-		}
-	} else {
-		for i, v := range strings.Split(curFunc.Params, `,`) {
-			if i < len(*params) {
-				val := macro(strings.TrimSpace(string((*params)[i])), workspace.Vars)
-				off := strings.IndexByte(val, ':')
-				if off != -1 && strings.Contains(curFunc.Params, val[:off]) {
-					cut := "\t\r\n \"`"
-					if val[:off] == `Data` {
-						cut = "\t\r\n "
-					}
-					pars[val[:off]] = strings.Trim(val[off+1:], cut)
-				} else {
-					pars[v] = val
-				}
-			} else if _, ok := pars[v]; !ok {
-				pars[v] = ``
-			}
-		}
-	}
+	pars := funcParams(curFunc, params, func(val string) string {
+		return macro(val, workspace.Vars)
+	}, nil)
 	state := int(converter.StrToInt64((*workspace.Vars)[`ecosystem_id`]))
 	if (*workspace.Vars)[`_full`] != `1` {
 		for i, v := range pars {
@@ -359,7 +342,7 @@ func callFunc(curFunc *tplFunc, owner *node, workspace *Workspace, params *[][]r
 		parFunc.Node = &curNode
 		parFunc.Tails = tailpars
 	}
-	if *workspace.Timeout {
+	if workspace.aborted() {
 		return
 	}
 	parFunc.Pars = &pars
@@ -377,12 +360,63 @@ func callFunc(curFunc *tplFunc, owner *node, workspace *Workspace, params *[][]r
 	}
 }
 
+// funcParams assigns the names to the parameters of the function, expand is applied to the values.
+// If index isn't nil then it gets the indexes of the parameters in params.
+func funcParams(curFunc *tplFunc, params *[][]rune, expand func(string) string, index map[string]int) map[string]string {
+	pars := make(map[string]string)
+	setPar := func(name, val string, i int) {
+		pars[name] = val
+		if index != nil {
+			index[name] = i
+		}
+	}
+	if curFunc.Params == `*` {
+		for i, v := range *params {
+			val := strings.TrimSpace(string(v))
+			off := strings.IndexByte(val, ':')
+			if off != -1 {
+				setPar(val[:off], expand(strings.Trim(val[off+1:], "\t\r\n \"`")), i)
+			} else {
+				setPar(strconv.Itoa(i), expand(val), i)
+			}
+		}
+	} else {
+		for i, v := range strings.Split(curFunc.Params, `,`) {
+			if i < len(*params) {
+				val := expand(strings.TrimSpace(string((*params)[i])))
+				off := strings.IndexByte(val, ':')
+				if off != -1 && strings.Contains(curFunc.Params, val[:off]) {
+					cut := "\t\r\n \"`"
+					if val[:off] == `Data` {
+						cut = "\t\r\n "
+					}
+					setPar(val[:off], strings.Trim(val[off+1:], cut), i)
+				} else {
+					setPar(v, val, i)
+				}
+			} else if _, ok := pars[v]; !ok {
+				pars[v] = ``
+			}
+		}
+	}
+	return pars
+}
+
 func getFunc(input string, curFunc tplFunc) (*[][]rune, int, *[]*[][]rune) {
+	params, off, tailpar, _ := parseFunc(input, curFunc)
+	return params, utf8.RuneCountInString(input[:off]), tailpar
+}
+
+// parseFunc splits the call of the function into the parameters and the tails. It returns the offset
+// of the last character of the call and the bracket which isn't closed or zero.
+func parseFunc(input string, curFunc tplFunc) (*[][]rune, int, *[]*[][]rune, rune) {
 	var (
 		curp, skip, off, mode, lenParams int
 		quote                            bool
 		pair, ch                         rune
 		tailpar                          *[]*[][]rune
+		unclosed                         rune
+		closed                           bool
 	)
 	var params [][]rune
 	sizeParam := 32 + len(input)/2
@@ -502,12 +536,8 @@ main:
 								break
 							}
 							if isTail {
-								parTail, shift, _ := getFunc(input[next:], tailFunc.tplFunc)
-								off = next
-								for ; shift > 0; shift-- {
-									_, size := utf8.DecodeRuneInString(input[off:])
-									off += size
-								}
+								parTail, shift, _, tailUnclosed := parseFunc(input[next:], tailFunc.tplFunc)
+								off = next + shift
 								if tailpar == nil {
 									fortail := make([]*[][]rune, 0)
 									tailpar = &fortail
@@ -515,7 +545,12 @@ main:
 								*parTail = append(*parTail, []rune(key))
 								*tailpar = append(*tailpar, parTail)
 								found = true
+								if tailUnclosed != 0 {
+									unclosed = tailUnclosed
+									break main
+								}
 								if tailFunc.Last {
+									closed = true
 									break main
 								}
 								break
@@ -526,13 +561,17 @@ main:
 						break
 					}
 				}
+				closed = true
 				break main
 			}
 		}
 		params[curp] = append(params[curp], ch)
 		continue
 	}
-	return &params, utf8.RuneCountInString(input[:off]), tailpar
+	if !closed && unclosed == 0 {
+		unclosed = modes[mode][1]
+	}
+	return &params, off, tailpar, unclosed
 }
 
 func process(input string, owner *node, workspace *Workspace) {
@@ -551,7 +590,7 @@ func process(input string, owner *node, workspace *Workspace) {
 		}
 		if ch == '(' {
 			if curFunc, isFunc = funcs[string(name[nameOff:])]; isFunc {
-				if *workspace.Timeout {
+				if workspace.aborted() {
 					return
 				}
 				appendText(owner, string(name[:nameOff]))
@@ -576,9 +615,7 @@ func process(input string, owner *node, workspace *Workspace) {
 	appendText(owner, string(name))
 }
 
-// Template2JSON converts templates to JSON data
-func Template2JSON(input string, timeout *bool, vars *map[string]string) []byte {
-	root := node{}
+func newWorkspace(ctx context.Context, budget *Budget, deps *Deps, vars *map[string]string) *Workspace {
 	isvde := (*vars)[`vde`] == `true` || (*vars)[`vde`] == `1`
 
 	sc := smart.SmartContract{
@@ -587,14 +624,35 @@ func Template2JSON(input string, timeout *bool, vars *map[string]string) []byte
 		TxSmart: tx.SmartContract{Header: tx.Header{EcosystemID: converter.StrToInt64((*vars)[`ecosystem_id`]),
 			KeyID: converter.StrToInt64((*vars)[`key_id`])}},
 	}
-	process(input, &root, &Workspace{Vars: vars, Timeout: timeout, SmartContract: &sc})
-	if root.Children == nil || *timeout {
-		return []byte(`[]`)
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if budget == nil {
+		budget = &Budget{}
+	}
+	if deps == nil {
+		deps = NewDeps()
+	}
+	return &Workspace{Vars: vars, Ctx: ctx, Budget: budget, Deps: deps, SmartContract: &sc}
+}
+
+// Template2JSON converts templates to JSON data. Rendering is stopped when ctx is done
+// or the budget is exceeded. If budget is nil then the resources are not limited.
+// The tables which have been read are added to deps if it is not nil.
+func Template2JSON(ctx context.Context, input string, budget *Budget, deps *Deps, vars *map[string]string) ([]byte, error) {
+	root := node{}
+	workspace := newWorkspace(ctx, budget, deps, vars)
+	process(input, &root, workspace)
+	if workspace.Err != nil {
+		return nil, workspace.Err
+	}
+	if root.Children == nil {
+		return []byte(`[]`), nil
 	}
 	out, err := json.Marshal(root.Children)
 	if err != nil {
 		log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling template data to json")
-		return []byte(err.Error())
+		return []byte(err.Error()), nil
 	}
-	return out
+	return out, nil
 }
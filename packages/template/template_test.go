@@ -23,7 +23,14 @@
 package template
 
 import (
+	"context"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/GACHAIN/go-gachain/packages/script"
+	"github.com/GACHAIN/go-gachain/packages/smart"
 )
 
 type tplItem struct {
@@ -34,11 +41,10 @@ type tplItem struct {
 type tplList []tplItem
 
 func TestJSON(t *testing.T) {
-	var timeout bool
 	vars := make(map[string]string)
 	vars[`_full`] = `0`
 	for _, item := range forTest {
-		templ := Template2JSON(item.input, &timeout, &vars)
+		templ, _ := Template2JSON(context.Background(), item.input, nil, nil, &vars)
 		if string(templ) != item.want {
 			t.Errorf("wrong json \r\n%s != \r\n%s", templ, item.want)
 			return
@@ -46,6 +52,148 @@ func TestJSON(t *testing.T) {
 	}
 }
 
+func TestBudget(t *testing.T) {
+	vars := map[string]string{`_full`: `0`}
+	input := `Div(){Span(1)Span(2)}`
+	budget := NewBudget(Limits{Nodes: 3})
+	if _, err := Template2JSON(context.Background(), input, budget, nil, &vars); err != nil {
+		t.Error(err)
+	}
+	if budget.Nodes != 3 {
+		t.Errorf("wrong count of nodes %d", budget.Nodes)
+	}
+	_, err := Template2JSON(context.Background(), input, NewBudget(Limits{Nodes: 2}), nil, &vars)
+	if _, ok := err.(*BudgetError); !ok {
+		t.Errorf("budget error is expected, got %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = Template2JSON(ctx, input, nil, nil, &vars); err != context.Canceled {
+		t.Errorf("context.Canceled is expected, got %v", err)
+	}
+}
+
+func TestBlockVars(t *testing.T) {
+	decl := parseBlockParams(`Title, Class = text-center,Size=`)
+	vars := map[string]string{`ecosystem_id`: `1`, `lang`: `en`, `page`: `main`}
+
+	ret, err := blockVars(`card`, vars, decl, parseIncludeParams(`Title=Hello, Size=10`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{`ecosystem_id`: `1`, `lang`: `en`, `Title`: `Hello`,
+		`Class`: `text-center`, `Size`: `10`}
+	if len(ret) != len(want) {
+		t.Errorf("wrong vars %v", ret)
+	}
+	for key, val := range want {
+		if ret[key] != val {
+			t.Errorf("wrong %s: %s != %s", key, ret[key], val)
+		}
+	}
+	if _, err = blockVars(`card`, vars, decl, parseIncludeParams(`Class=big`)); err == nil ||
+		err.Error() != `Parameter Title of block card is required` {
+		t.Errorf("missing parameter error is expected, got %v", err)
+	}
+	if _, err = blockVars(`card`, vars, decl, parseIncludeParams(`Title=1,Color=red`)); err == nil ||
+		err.Error() != `Block card doesn't have parameter Color` {
+		t.Errorf("unknown parameter error is expected, got %v", err)
+	}
+	if err = checkRecursion([]string{`page`, `card`, `item`}, `card`); err == nil ||
+		err.Error() != `Recursive include of block card: card -> item -> card` {
+		t.Errorf("recursion error is expected, got %v", err)
+	}
+	if err = checkRecursion([]string{`page`, `card`}, `item`); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRenderCache(t *testing.T) {
+	c := newRenderCache()
+	deps := NewDeps()
+	deps.Add(`1_pages`, `1_keys`)
+	c.set(`page`, `tree`, deps, c.generation(), 10)
+	if val, ok := c.get(`page`); !ok || val.(string) != `tree` {
+		t.Fatalf("cached value is expected, got %v", val)
+	}
+	c.invalidate(map[string]bool{`1_contracts`: true})
+	if _, ok := c.get(`page`); !ok {
+		t.Error("value must be cached")
+	}
+	c.invalidate(map[string]bool{`1_keys`: true})
+	if _, ok := c.get(`page`); ok {
+		t.Error("value must be invalidated")
+	}
+	if len(c.byTable) != 0 {
+		t.Errorf("wrong table index %v", c.byTable)
+	}
+
+	// the value which has been rendered before the invalidation is not stored
+	gen := c.generation()
+	c.invalidate(map[string]bool{`1_pages`: true})
+	c.set(`page`, `tree`, deps, gen, 10)
+	if _, ok := c.get(`page`); ok {
+		t.Error("stale value has been cached")
+	}
+	deps.Volatile = true
+	c.set(`page`, `tree`, deps, c.generation(), 10)
+	if _, ok := c.get(`page`); ok {
+		t.Error("volatile value has been cached")
+	}
+	deps.Volatile = false
+	for _, key := range []string{`a`, `b`, `c`} {
+		c.set(key, key, deps, c.generation(), 2)
+	}
+	if len(c.items) != 2 {
+		t.Errorf("wrong size of cache %d", len(c.items))
+	}
+	if CacheKey(`page`, map[string]string{`b`: `2`, `a`: `1`}) != CacheKey(`page`, map[string]string{`a`: `1`, `b`: `2`}) {
+		t.Error("cache key depends on the order of vars")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, item := range []struct {
+		input string
+		want  string
+	}{
+		{`Div(){Span(#name#)}`, `warning 1:12 Variable #name# is not defined in the template`},
+		{`SetVar(name, 1)Div(){Span(#name#)}`, ``},
+		{`Div(){
+	Spn(text)
+}`, `warning 2:2 Unknown function Spn`},
+		{`Div(class){P(text)`, `error 1:11 Unbalanced braces: the body of Div is not closed`},
+		{`Div(class, P(text)`, `error 1:1 Unbalanced parentheses: Div( is not closed`},
+		{`Button(Body: Save, Contract: Save).Alrt(Text: ok)`, `warning 1:36 Unknown tail .Alrt of Button`},
+		{`SetVar(a, b).(n, param).Span(#a#)`, ``},
+		{`Div(){Total(USD)}`, `warning 1:7 Unknown function Total`},
+		{`Button(Body: Save, Contract: Save).Alert(Text: #msg#)`, `warning 1:48 Variable #msg# is not defined in the template`},
+		{`DBFind(keys, src).Columns(id)ForList(src){#id#}Table(mysrc)`, `error 1:54 Source mysrc is not defined`},
+		{`Include(Name: menu)Table(mysrc)`, `warning 1:26 Source mysrc is not defined`},
+		{`LinkPage(Body: "Val(x)", Page: home)`, ``},
+	} {
+		v := Validate(item.input)
+		list := make([]string, 0)
+		for _, d := range v.Diagnostics {
+			list = append(list, fmt.Sprintf(`%s %d:%d %s`, d.Severity, d.Line, d.Column, d.Message))
+		}
+		if got := strings.Join(list, `;`); got != item.want {
+			t.Errorf("wrong diagnostics of %s\n%s != %s", item.input, got, item.want)
+		}
+	}
+	v := Validate(`Button(Body: Save, Contract: NewKey, Page: keys)LinkPage(Body: Keys, Page: keys)Include(card)`)
+	if len(v.Contracts) != 1 || v.Contracts[0].Name != `NewKey` || len(v.Pages) != 1 ||
+		v.Pages[0].Column != 38 || len(v.Blocks) != 1 || v.Blocks[0].Name != `card` {
+		t.Errorf("wrong references %v %v %v", v.Contracts, v.Pages, v.Blocks)
+	}
+	if Validate(`Div(class, P(text)`).Err() == nil {
+		t.Error("validation error is expected")
+	}
+	if err := Validate(`Div(){Total(USD)}`).Err(); err != nil {
+		t.Errorf("unknown function must not be an error: %v", err)
+	}
+}
+
 var forTest = tplList{
 	{`Calculate( Exp: 342278783438/0, Type: money )Calculate( Exp: 5.2/0, Type: float )
 		Calculate( Exp: 7/0)`,
@@ -73,7 +221,7 @@ var forTest = tplList{
 		First Name
 		Second Name
 	)`,
-		`[{"tag":"data","attr":{"columns":["id","name","custom_id","cust"],"data":[["1","First Name","[{\"tag\":\"p\",\"children\":[{\"tag\":\"text\",\"text\":\"first name\"}]}]","[{\"tag\":\"p\",\"children\":[{\"tag\":\"text\",\"text\":\"first name\"}]}]"],["2","Second Name","[{\"tag\":\"p\",\"children\":[{\"tag\":\"text\",\"text\":\"second name\"}]}]","[{\"tag\":\"p\",\"children\":[{\"tag\":\"text\",\"text\":\"second name\"}]}]"]],"source":"mysrc","types":["text","text","tags","tags"]}},{"tag":"data","attr":{"columns":["name"],"data":[["First Name"],["Second Name"]],"types":["text"]}}]`},
+		`[{"tag":"data","attr":{"columns":["id","name","custom_id","cust"],"count":"2","data":[["1","First Name","[{\"tag\":\"p\",\"children\":[{\"tag\":\"text\",\"text\":\"first name\"}]}]","[{\"tag\":\"p\",\"children\":[{\"tag\":\"text\",\"text\":\"first name\"}]}]"],["2","Second Name","[{\"tag\":\"p\",\"children\":[{\"tag\":\"text\",\"text\":\"second name\"}]}]","[{\"tag\":\"p\",\"children\":[{\"tag\":\"text\",\"text\":\"second name\"}]}]"]],"source":"mysrc","types":["text","text","tags","tags"]}},{"tag":"data","attr":{"columns":["name"],"count":"2","data":[["First Name"],["Second Name"]],"types":["text"]}}]`},
 
 	{`Data(Source: mysrc, Columns: "id,name", Data:
 		1,first
@@ -82,7 +230,7 @@ var forTest = tplList{
 	).Custom("synthetic"){
 		Div(text-muted, #name#)
 	}
-	Table(Source: mysrc)`, `[{"tag":"data","attr":{"columns":["id","name","synthetic"],"data":[["1","first","[{\"tag\":\"div\",\"attr\":{\"class\":\"text-muted\"},\"children\":[{\"tag\":\"text\",\"text\":\"first\"}]}]"],["2","second","[{\"tag\":\"div\",\"attr\":{\"class\":\"text-muted\"},\"children\":[{\"tag\":\"text\",\"text\":\"second\"}]}]"],["3","third","[{\"tag\":\"div\",\"attr\":{\"class\":\"text-muted\"},\"children\":[{\"tag\":\"text\",\"text\":\"third\"}]}]"]],"source":"mysrc","types":["text","text","tags"]}},{"tag":"table","attr":{"source":"mysrc"}}]`},
+	Table(Source: mysrc)`, `[{"tag":"data","attr":{"columns":["id","name","synthetic"],"count":"3","data":[["1","first","[{\"tag\":\"div\",\"attr\":{\"class\":\"text-muted\"},\"children\":[{\"tag\":\"text\",\"text\":\"first\"}]}]"],["2","second","[{\"tag\":\"div\",\"attr\":{\"class\":\"text-muted\"},\"children\":[{\"tag\":\"text\",\"text\":\"second\"}]}]"],["3","third","[{\"tag\":\"div\",\"attr\":{\"class\":\"text-muted\"},\"children\":[{\"tag\":\"text\",\"text\":\"third\"}]}]"]],"source":"mysrc","types":["text","text","tags"]}},{"tag":"table","attr":{"source":"mysrc"}}]`},
 	{`Data(myforlist,"id,name",
 		"1",Test message 1
 		2,"Test message 2"
@@ -90,7 +238,7 @@ var forTest = tplList{
 		)ForList(nolist){Problem}ForList(myforlist){
 			Div(){#id#. Em(#name#)}
 		}`,
-		`[{"tag":"data","attr":{"columns":["id","name"],"data":[["1","Test message 1"],["2","Test message 2"],["3","Test message 3"]],"source":"myforlist","types":["text","text"]}},{"tag":"forlist","attr":{"source":"myforlist"},"children":[{"tag":"div","children":[{"tag":"text","text":"1. "},{"tag":"em","children":[{"tag":"text","text":"Test message 1"}]}]},{"tag":"div","children":[{"tag":"text","text":"2. "},{"tag":"em","children":[{"tag":"text","text":"Test message 2"}]}]},{"tag":"div","children":[{"tag":"text","text":"3. "},{"tag":"em","children":[{"tag":"text","text":"Test message 3"}]}]}]}]`},
+		`[{"tag":"data","attr":{"columns":["id","name"],"count":"3","data":[["1","Test message 1"],["2","Test message 2"],["3","Test message 3"]],"source":"myforlist","types":["text","text"]}},{"tag":"forlist","attr":{"source":"myforlist"},"children":[{"tag":"div","children":[{"tag":"text","text":"1. "},{"tag":"em","children":[{"tag":"text","text":"Test message 1"}]}]},{"tag":"div","children":[{"tag":"text","text":"2. "},{"tag":"em","children":[{"tag":"text","text":"Test message 2"}]}]},{"tag":"div","children":[{"tag":"text","text":"3. "},{"tag":"em","children":[{"tag":"text","text":"Test message 3"}]}]}]}]`},
 	{`SetTitle(My pageР)AddToolButton(Title: Open, Page: default)`,
 		`[{"tag":"settitle","attr":{"title":"My pageР"}},{"tag":"addtoolbutton","attr":{"page":"default","title":"Open"}}]`},
 	{`DateTime(2017-11-07T17:51:08)+DateTime(2015-08-27T09:01:00,HH:MI DD.MM.YYYY)
@@ -108,7 +256,7 @@ var forTest = tplList{
 		"1",John Silver,2
 		2,"Mark, Smith"
 	)`,
-		`[{"tag":"data","attr":{"columns":["id","name"],"data":[],"error":"line 2, column 0: wrong number of fields in line","source":"mysrc","types":["text","text"]}}]`},
+		`[{"tag":"data","attr":{"columns":["id","name"],"count":"0","data":[],"error":"line 2, column 0: wrong number of fields in line","source":"mysrc","types":["text","text"]}}]`},
 	{`Select(myselect,mysrc,name,id,0,myclass)`,
 		`[{"tag":"select","attr":{"class":"myclass","name":"myselect","namecolumn":"name","source":"mysrc","value":"0","valuecolumn":"id"}}]`},
 	{`Data(mysrc,"id,name"){
@@ -116,7 +264,7 @@ var forTest = tplList{
 		2,"Mark, Smith"
 		3,"Unknown ""Person"""
 		}`,
-		`[{"tag":"data","attr":{"columns":["id","name"],"data":[["1","John Silver"],["2","Mark, Smith"],["3","Unknown \"Person\""]],"source":"mysrc","types":["text","text"]}}]`},
+		`[{"tag":"data","attr":{"columns":["id","name"],"count":"3","data":[["1","John Silver"],["2","Mark, Smith"],["3","Unknown \"Person\""]],"source":"mysrc","types":["text","text"]}}]`},
 	{`If(true) {OK}.Else {false} Div(){test} If(false, FALSE).ElseIf(0) { Skip }.ElseIf(1) {Else OK
 		}.Else {Fourth}If(0).Else{ALL right}`,
 		`[{"tag":"text","text":"OK"},{"tag":"div","children":[{"tag":"text","text":"test"}]},{"tag":"text","text":"Else OK"},{"tag":"text","text":"ALL right"}]`},
@@ -186,12 +334,182 @@ var forTest = tplList{
 		`[{"tag":"chart","attr":{"colors":["red","green"],"fieldlabel":"name","fieldvalue":"count","source":"src","type":"bar"}}]`},
 }
 
+func TestPaging(t *testing.T) {
+	for order, want := range map[string]string{`name`: `name`, `Amount DESC`: `amount desc`,
+		` id asc`: `id`, `name; drop table`: ``, `id desc, name`: ``} {
+		column, desc, err := parseOrder(order)
+		if len(want) == 0 {
+			if err == nil {
+				t.Errorf("order %s must be rejected", order)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if desc {
+			column += ` desc`
+		}
+		if column != want {
+			t.Errorf("wrong order %s != %s", column, want)
+		}
+	}
+	vars := map[string]string{`_full`: `0`}
+	out, err := Template2JSON(context.Background(), `Data(src, "id"){
+1
+2
+}Table(src).Paging("id, name")`, nil, nil, &vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"tag":"data","attr":{"columns":["id"],"count":"2","data":[["1"],["2"]],"source":"src","types":["text"]}},{"tag":"table","attr":{"sortable":["id","name"],"source":"src"}}]`
+	if string(out) != want {
+		t.Errorf("wrong json %s != %s", out, want)
+	}
+}
+
+func TestTimeFuncs(t *testing.T) {
+	defer func(now func() time.Time) { timeNow = now }(timeNow)
+	timeNow = func() time.Time { return time.Date(2018, 3, 5, 12, 0, 0, 0, time.UTC) }
+
+	vars := map[string]string{`_full`: `0`, `lang`: `en`}
+	for _, item := range []struct {
+		input, want string
+	}{
+		{`Money(123456789, 2)`, `1,234,567.89`},
+		{`Money(123456789, 2, de)`, `1.234.567,89`},
+		{`Money(-5, 3, ru)`, `-0,005`},
+		{`Money(1500, 0)`, `1,500`},
+		{`Money(123, 18)`, `0.000000000000000123`},
+		{`Money(1234567890123456789, 18)`, `1.234567890123456789`},
+		{`Money(-123456789012345678, 17)`, `-1.23456789012345678`},
+		{`Money(1, 100000)`, `Digit 100000 is out of range 0..18`},
+		{`Money(1, -2)`, `Digit -2 is out of range 0..18`},
+		{`DateTime(2018-03-05 22:30:00, "HH:MI DD.MM", Europe/Berlin)`, `23:30 05.03`},
+		{`DateTime(2018-07-05 22:30:00, "YYYY-MM-DD HH:MI", Europe/Berlin)`, `2018-07-06 00:30`},
+		{`DateTime(2018-03-05 22:30:00, short, Locale: de)`, `05.03.2018`},
+		{`DateTime(now, long, Locale: fr)`, `05/03/2018 12:00:00`},
+		{`DateTime(1520251200, "YYYY-MM-DD HH:MI:SS")`, `2018-03-05 12:00:00`},
+		{`DateTime(2018-03-05, Location: Mars/Olympus)`, `unknown time zone Mars/Olympus`},
+		{`AddDate(2018-01-31, "+1 month")`, `2018-03-03 00:00:00`},
+		{`AddDate(now, "-2 days 3 hours", "DD.MM.YYYY HH:MI")`, `03.03.2018 15:00`},
+		{`AddDate(2018-03-05 10:00:00, "1 week")`, `2018-03-12 10:00:00`},
+		{`AddDate(2018-03-05, "+1 fortnight")`, `Unknown unit fortnight`},
+		{`TimeAgo(2018-03-05 11:59:58)`, `2 seconds ago`},
+		{`TimeAgo(2018-03-05 11:00:00)`, `1 hour ago`},
+		{`TimeAgo(2018-02-20)`, `1 week ago`},
+		{`TimeAgo(2018-03-08 12:00:00)`, `in 3 days`},
+		{`TimeAgo(2016-01-01)`, `2 years ago`},
+		{`TimeAgo(2018-03-05 12:00:00)`, `just now`},
+		{`TimeAgo()none`, `none`},
+	} {
+		out, err := Template2JSON(context.Background(), item.input, nil, nil, &vars)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		want := `[{"tag":"text","text":"` + item.want + `"}]`
+		if string(out) != want {
+			t.Errorf("%s: wrong result %s != %s", item.input, out, want)
+		}
+	}
+}
+
+func TestContractForm(t *testing.T) {
+	err := smart.Compile(`contract FormItem {
+		data {
+			Name   string
+			Amount money
+			Count  int "optional"
+			Photo  bytes "image optional"
+			Key    string "hidden"
+		}
+	}`, &script.OwnerInfo{StateID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]string{`_full`: `0`, `ecosystem_id`: `1`}
+	out, err := Template2JSON(context.Background(), `ContractForm(FormItem, myform)`, nil, nil, &vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`{"tag":"form","attr":{"class":"myform"}`,
+		`{"tag":"label","attr":{"for":"Name"},"children":[{"tag":"text","text":"Name"}]}`,
+		`{"tag":"input","attr":{"name":"Name","type":"text","validate":{"required":"true"}}}`,
+		`{"tag":"input","attr":{"name":"Amount","type":"number","validate":{"min":"0","required":"true"}}}`,
+		`{"tag":"input","attr":{"name":"Count","type":"number","validate":{"pattern":"^-?[0-9]+$"}}}`,
+		`{"tag":"imageinput","attr":{"name":"Photo"}}`,
+		`{"tag":"input","attr":{"name":"Key","type":"hidden"}}`,
+		`{"tag":"button","attr":{"class":"btn btn-primary","contract":"FormItem","params":{"Amount":`,
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("%s is not found in %s", want, out)
+		}
+	}
+	// the class is the value of the attribute and it isn't parsed as the template
+	out, err = Template2JSON(context.Background(), `ContractForm(FormItem, "x){Span(injected)}Div(")`, nil, nil, &vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(out), `[{"tag":"form","attr":{"class":"x){Span(injected)}Div("}`) ||
+		strings.Contains(string(out), `"tag":"span"`) {
+		t.Errorf("class is parsed as the template %s", out)
+	}
+	out, _ = Template2JSON(context.Background(), `ContractForm(UnknownForm)`, nil, nil, &vars)
+	if string(out) != `[{"tag":"text","text":"Contract UnknownForm has not been found"}]` {
+		t.Errorf("wrong result %s", out)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	vars := map[string]string{`_full`: `0`}
+	tree, err := Template2JSON(context.Background(), `SetTitle(My <page>)Div(panel, Text & <b>)
+	Data(src, "name,value"){
+"Alpha",10
+"Beta",30
+	}
+	Table(src, "Name=name,Value=value").Style(wide)
+	Input(Name: amount, Class: form).Validate(minLength: 2)
+	LinkPage(Next, next, , "id=5")Chart(Type: pie, Source: src, FieldLabel: name, FieldValue: value)`,
+		nil, nil, &vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := Tree2HTML(tree, HTMLOptions{PagePath: `/page/`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Title != `My <page>` {
+		t.Errorf("wrong title %s", page.Title)
+	}
+	for _, want := range []string{
+		`<div class="panel">Text &amp; &lt;b&gt;</div>`,
+		`<thead><tr><th>Name</th><th>Value</th></tr></thead><tbody><tr><td>Alpha</td><td>10</td></tr>`,
+		`<input type="text" name="amount" class="form" minlength="2">`,
+		`<a href="/page/next?id=5">Next</a>`,
+		`<svg xmlns="http://www.w3.org/2000/svg"`,
+		`<title>Beta</title>`,
+	} {
+		if !strings.Contains(page.Body, want) {
+			t.Errorf("%s is not found in %s", want, page.Body)
+		}
+	}
+	if strings.Contains(page.Body, `<b>`) || strings.Contains(page.Body, `&amp;lt;`) {
+		t.Errorf("wrong escaping %s", page.Body)
+	}
+	menu, err := Menu2HTML([]byte(`[{"tag":"menuitem","attr":{"page":"home","title":"Home"}}]`), HTMLOptions{})
+	if err != nil || menu != `<ul class="menu"><li><a href="home">Home</a></li></ul>` {
+		t.Errorf("wrong menu %s %v", menu, err)
+	}
+}
+
 func TestFullJSON(t *testing.T) {
-	var timeout bool
 	vars := make(map[string]string)
 	vars[`_full`] = `1`
 	for _, item := range forFullTest {
-		templ := Template2JSON(item.input, &timeout, &vars)
+		templ, _ := Template2JSON(context.Background(), item.input, nil, nil, &vars)
 		if string(templ) != item.want {
 			t.Errorf(`wrong json %s != %s`, templ, item.want)
 			return
@@ -0,0 +1,484 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package template
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/GACHAIN/go-gachain/packages/consts"
+	"github.com/GACHAIN/go-gachain/packages/converter"
+	"github.com/GACHAIN/go-gachain/packages/model"
+	"github.com/GACHAIN/go-gachain/packages/smart"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Severity levels of diagnostics
+const (
+	SeverityError   = `error`
+	SeverityWarning = `warning`
+)
+
+// builtinVars are the variables which are defined for every page
+var builtinVars = map[string]bool{`_full`: true, `ecosystem_id`: true, `key_id`: true, `lang`: true, `vde`: true}
+
+// Diagnostic is the problem which has been found in the template
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Message  string `json:"message"`
+}
+
+// Reference is the contract, page or block which is used by the template
+type Reference struct {
+	Name   string
+	Line   int
+	Column int
+}
+
+// Validation is the result of the static validation of the template
+type Validation struct {
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	Contracts   []Reference  `json:"-"`
+	Pages       []Reference  `json:"-"`
+	Blocks      []Reference  `json:"-"`
+}
+
+// Err returns the first error of the validation or nil
+func (v *Validation) Err() error {
+	for _, item := range v.Diagnostics {
+		if item.Severity == SeverityError {
+			return fmt.Errorf(`line %d, column %d: %s`, item.Line, item.Column, item.Message)
+		}
+	}
+	return nil
+}
+
+type sourceUse struct {
+	name string
+	off  int
+}
+
+type validator struct {
+	src      string
+	lines    []int // offsets of the beginnings of lines
+	result   *Validation
+	vars     map[string]bool
+	prefixes []string
+	sources  map[string]bool
+	uses     []sourceUse
+	include  bool
+	refs     map[string]bool
+}
+
+// Validate checks the source of the template without rendering it. Params are the names of
+// the variables which are defined outside of the template, for example, the parameters of the block.
+// The template is parsed by the parser of the renderer, so the unknown functions and tails
+// are the plain text for it and they are reported as warnings.
+// The validation is used only by the API and the editor. Contracts don't call it, because
+// its diagnostics can change between versions and nodes replaying old blocks would fork.
+func Validate(input string, params ...string) *Validation {
+	v := &validator{
+		src:     input,
+		lines:   []int{0},
+		result:  &Validation{Diagnostics: make([]Diagnostic, 0)},
+		vars:    make(map[string]bool),
+		sources: make(map[string]bool),
+		refs:    make(map[string]bool),
+	}
+	for i := 0; i < len(input); i++ {
+		if input[i] == '\n' {
+			v.lines = append(v.lines, i+1)
+		}
+	}
+	for _, name := range params {
+		v.vars[name] = true
+	}
+	v.collectVars()
+	v.process(0, len(input), false)
+	for _, use := range v.uses {
+		if v.sources[use.name] {
+			continue
+		}
+		// the source can be defined by the included block
+		severity := SeverityError
+		if v.include {
+			severity = SeverityWarning
+		}
+		v.add(severity, use.off, `Source %s is not defined`, use.name)
+	}
+	v.result.sort()
+	return v.result
+}
+
+func (v *Validation) sort() {
+	sort.SliceStable(v.Diagnostics, func(i, j int) bool {
+		a, b := v.Diagnostics[i], v.Diagnostics[j]
+		return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+	})
+}
+
+func (v *validator) position(off int) (line, column int) {
+	line = sort.Search(len(v.lines), func(i int) bool { return v.lines[i] > off })
+	return line, utf8.RuneCountInString(v.src[v.lines[line-1]:off]) + 1
+}
+
+func (v *validator) add(severity string, off int, format string, args ...interface{}) {
+	line, column := v.position(off)
+	v.result.Diagnostics = append(v.result.Diagnostics, Diagnostic{Severity: severity,
+		Line: line, Column: column, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) addRef(list *[]Reference, kind, name string, off int) {
+	if len(name) == 0 || strings.ContainsAny(name, `#$`) || v.refs[kind+name] {
+		return
+	}
+	v.refs[kind+name] = true
+	line, column := v.position(off)
+	*list = append(*list, Reference{Name: name, Line: line, Column: column})
+}
+
+// collectVars gathers the variables which are defined by SetVar and DBFind.Vars in any place
+// of the template, because they can be used in the included blocks before the definition
+func (v *validator) collectVars() {
+	for _, prefix := range []string{`SetVar(`, `.Vars(`} {
+		for _, item := range strings.Split(v.src, prefix)[1:] {
+			end := strings.IndexAny(item, `,)`)
+			if end < 0 {
+				continue
+			}
+			name := strings.TrimSpace(item[:end])
+			if off := strings.IndexByte(name, ':'); off >= 0 {
+				name = strings.TrimSpace(name[off+1:])
+			}
+			name = strings.Trim(name, "\"`")
+			if prefix == `SetVar(` {
+				v.vars[name] = true
+			} else {
+				v.prefixes = append(v.prefixes, name+`_`)
+			}
+		}
+	}
+}
+
+func isLetter(ch rune) bool {
+	return (ch >= 'A' && ch <= 'Z') || (ch >= 'a' && ch <= 'z')
+}
+
+// process checks the text between start and end in the same way as process of the renderer
+// goes through the template. If rowVars is true then the text is the body of ForList or Custom
+// where the columns of the source are available as variables.
+func (v *validator) process(start, end int, rowVars bool) {
+	nameOff := start
+	for off := start; off < end; {
+		ch, size := utf8.DecodeRuneInString(v.src[off:end])
+		switch {
+		case ch == '(':
+			name := v.src[nameOff:off]
+			if f, ok := funcs[name]; ok {
+				off = v.call(f, name, nameOff, off, end, rowVars)
+				nameOff = off
+				continue
+			}
+			// the unknown tail has been reported by the call
+			if len(name) > 1 && name[0] >= 'A' && name[0] <= 'Z' && (nameOff == start || v.src[nameOff-1] != '.') {
+				v.add(SeverityWarning, nameOff, `Unknown function %s`, name)
+			}
+		case ch == '#' && !rowVars:
+			off = v.variable(off, end) + 1
+			nameOff = off
+			continue
+		}
+		if !isLetter(ch) {
+			nameOff = off + size
+		}
+		off += size
+	}
+}
+
+// variables checks #name# references between start and end
+func (v *validator) variables(start, end int) {
+	for off := start; off < end; off++ {
+		if v.src[off] == '#' {
+			off = v.variable(off, end)
+		}
+	}
+}
+
+// variable checks #name# reference and returns the offset of the closing #
+func (v *validator) variable(off, end int) int {
+	for i := off + 1; i < end && i-off <= 65; i++ {
+		ch := v.src[i]
+		if ch <= ' ' {
+			return i
+		}
+		if ch != '#' {
+			continue
+		}
+		name := v.src[off+1 : i]
+		if !isVarName(name) || v.vars[name] || builtinVars[name] {
+			return i
+		}
+		for _, prefix := range v.prefixes {
+			if strings.HasPrefix(name, prefix) {
+				return i
+			}
+		}
+		v.add(SeverityWarning, off, `Variable #%s# is not defined in the template`, name)
+		return i
+	}
+	return off
+}
+
+func isVarName(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	for _, ch := range name {
+		if !isLetter(ch) && ch != '_' && (ch < '0' || ch > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func isName(key string) bool {
+	if len(key) == 0 {
+		return false
+	}
+	for i, ch := range key {
+		if !isLetter(ch) && !(i == 0 && ch == '@') {
+			return false
+		}
+	}
+	return true
+}
+
+// locate returns the offset of the text between start and end or -1.
+// The parser drops the quotes, so the text before the first quote is looked for too.
+func (v *validator) locate(text string, start, end int) int {
+	text = strings.TrimSpace(text)
+	if quote := strings.IndexAny(text, "\"`"); quote >= 0 {
+		text = strings.TrimSpace(text[:quote])
+	}
+	if len(text) == 0 || start >= end {
+		return -1
+	}
+	if off := strings.Index(v.src[start:end], text); off >= 0 {
+		return start + off
+	}
+	return -1
+}
+
+// call checks the call of the function which is parsed by the parser of the renderer
+// with its repeated calls and tails, it returns the offset after the call
+func (v *validator) call(f tplFunc, name string, nameOff, open, end int, rowVars bool) int {
+	params, last, tailpars, unclosed := parseFunc(v.src[open:end], f)
+	if unclosed != 0 {
+		if unclosed == '}' {
+			brace := open + strings.IndexByte(v.src[open:end], '{')
+			if brace < open {
+				brace = nameOff
+			}
+			v.add(SeverityError, brace, `Unbalanced braces: the body of %s is not closed`, name)
+		} else {
+			v.add(SeverityError, nameOff, `Unbalanced parentheses: %s( is not closed`, name)
+		}
+		return end
+	}
+	next := open + last + 1
+	v.checkCall(f, name, params, tailpars, open, next, rowVars)
+	// the function can be called again like SetVar(a, 1).(b, 2)
+	for next+2 < end && v.src[next:next+2] == `.(` {
+		again := next + 1
+		params, last, tailpars, unclosed = parseFunc(v.src[again:end], f)
+		if unclosed != 0 {
+			v.add(SeverityError, next, `Unbalanced parentheses: %s.( is not closed`, name)
+			return end
+		}
+		next = again + last + 1
+		v.checkCall(f, name, params, tailpars, again, next, rowVars)
+	}
+	if _, ok := tails[f.Tag]; ok && next+1 < end && v.src[next] == '.' {
+		i := next + 1
+		for i < end && isLetter(rune(v.src[i])) {
+			i++
+		}
+		if i > next+1 && i < end && (v.src[i] == '(' || v.src[i] == '{') {
+			v.add(SeverityWarning, next+1, `Unknown tail .%s of %s`, v.src[next+1:i], name)
+		}
+	}
+	return next
+}
+
+// checkCall checks the parameters, the body and the tails of the call between start and end
+func (v *validator) checkCall(f tplFunc, name string, params *[][]rune, tailpars *[]*[][]rune,
+	start, end int, rowVars bool) {
+
+	cursor := start
+	pars := v.params(f, name, params, &cursor, end, rowVars || name == `ForList`)
+	v.use(name, pars)
+	if tailpars == nil {
+		return
+	}
+	for _, tailpar := range *tailpars {
+		tailName := string((*tailpar)[len(*tailpar)-1])
+		*tailpar = (*tailpar)[:len(*tailpar)-1]
+		if off := v.locate(`.`+tailName, cursor, end); off >= 0 {
+			cursor = off
+		}
+		info := tails[f.Tag].Tails[tailName]
+		pars = v.params(info.tplFunc, name+`.`+tailName, tailpar, &cursor, end, rowVars || tailName == `Custom`)
+		v.use(name+`.`+tailName, pars)
+	}
+}
+
+// params finds the parameters of the call in the source, checks the parameters which are processed
+// as the template and the variables of the other parameters
+func (v *validator) params(f tplFunc, name string, params *[][]rune, cursor *int, end int,
+	rowVars bool) map[string]sourceUse {
+
+	index := make(map[string]int)
+	values := funcParams(&f, params, func(val string) string { return val }, index)
+	offsets := make([]int, len(*params))
+	for i, par := range *params {
+		raw := string(par)
+		off := v.locate(raw, *cursor, end)
+		if colon := strings.IndexByte(raw, ':'); off < 0 && colon > 0 {
+			// the body in braces is added as the parameter with the name
+			off = v.locate(raw[colon+1:], *cursor, end)
+		}
+		offsets[i] = off
+		if off < 0 {
+			continue
+		}
+		*cursor = off
+		if colon := strings.IndexByte(raw, ':'); colon > 0 && f.Params != `*` {
+			key := strings.TrimSpace(raw[:colon])
+			if isName(key) && !strings.Contains(f.Params, key) && key[0] >= 'A' && key[0] <= 'Z' &&
+				strings.ToUpper(key) != key {
+				v.add(SeverityWarning, off, `Unknown parameter %s of %s`, key, name)
+			}
+		}
+	}
+
+	ret := make(map[string]sourceUse)
+	for parName, val := range values {
+		i, ok := index[parName]
+		if !ok || offsets[i] < 0 {
+			continue
+		}
+		ret[strings.TrimPrefix(parName, `@`)] = sourceUse{name: val, off: offsets[i]}
+		if len(val) == 0 {
+			continue
+		}
+		valOff := v.locate(val, offsets[i], end)
+		if valOff < 0 {
+			continue
+		}
+		valEnd := valOff + len(val)
+		if valEnd > end {
+			valEnd = end
+		}
+		quoted := valOff > 0 && strings.ContainsRune("\"`", rune(v.src[valOff-1]))
+		switch {
+		case parName == `Data`:
+		case !quoted && (parName == `Body` || parName == `Condition` || parName[0] == '@' ||
+			name == `SetVar` && parName == `Value`):
+			// these parameters are processed by the renderer as the template
+			v.process(valOff, valEnd, rowVars)
+		case !rowVars:
+			v.variables(valOff, valEnd)
+		}
+	}
+	return ret
+}
+
+// use registers the sources, contracts, pages and blocks which are used by the function
+func (v *validator) use(name string, pars map[string]sourceUse) {
+	switch name {
+	case `DBFind`, `Data`, `EcosysParam`:
+		if src := pars[`Source`]; len(src.name) > 0 {
+			v.sources[src.name] = true
+		}
+	case `ForList`, `Table`, `Select`, `RadioGroup`, `Chart`:
+		if src := pars[`Source`]; len(src.name) > 0 && !strings.ContainsAny(src.name, `#$`) {
+			v.uses = append(v.uses, src)
+		}
+	case `SetVar`:
+		v.vars[pars[`Name`].name] = true
+	case `Include`:
+		v.include = true
+		v.addRef(&v.result.Blocks, `block`, pars[`Name`].name, pars[`Name`].off)
+	}
+	if contract, ok := pars[`Contract`]; ok {
+		v.addRef(&v.result.Contracts, `contract`, contract.name, contract.off)
+	}
+	if page, ok := pars[`Page`]; ok {
+		v.addRef(&v.result.Pages, `page`, page.name, page.off)
+	}
+}
+
+// CheckRefs adds the warnings about the contracts, pages and blocks which don't exist in the ecosystem
+func CheckRefs(v *Validation, ecosystemID int64, vde bool) error {
+	prefix := converter.Int64ToStr(ecosystemID)
+	if vde {
+		prefix += `_vde`
+	}
+	vm := smart.GetVM(vde, ecosystemID)
+	for _, ref := range v.Contracts {
+		if smart.VMGetContract(vm, ref.Name, uint32(ecosystemID)) == nil {
+			v.addWarning(ref, `Contract %s doesn't exist`)
+		}
+	}
+	for _, item := range []struct {
+		refs    []Reference
+		table   string
+		message string
+	}{
+		{v.Pages, `pages`, `Page %s doesn't exist`},
+		{v.Blocks, `blocks`, `Block %s doesn't exist`},
+	} {
+		for _, ref := range item.refs {
+			count, err := model.Single(`select count(*) from "`+prefix+`_`+item.table+`" where name=?`, ref.Name).Int64()
+			if err != nil {
+				log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("checking template reference")
+				return err
+			}
+			if count == 0 {
+				v.addWarning(ref, item.message)
+			}
+		}
+	}
+	v.sort()
+	return nil
+}
+
+func (v *Validation) addWarning(ref Reference, format string) {
+	v.Diagnostics = append(v.Diagnostics, Diagnostic{Severity: SeverityWarning, Line: ref.Line,
+		Column: ref.Column, Message: fmt.Sprintf(format, ref.Name)})
+}
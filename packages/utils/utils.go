@@ -37,15 +37,23 @@ import (
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"syscall"
 	"time"
 
 	"github.com/GACHAIN/go-gachain/packages/conf"
 	"github.com/GACHAIN/go-gachain/packages/consts"
 	"github.com/GACHAIN/go-gachain/packages/converter"
 	"github.com/GACHAIN/go-gachain/packages/crypto"
+	"github.com/GACHAIN/go-gachain/packages/network"
 	log "github.com/sirupsen/logrus"
 )
 
+// maxBlockSize is the max size of the block body which can be received from the host
+const maxBlockSize = 10485760
+
+// maxBlocksRangeSize is the max total size of the blocks which can be received from the host by one request
+const maxBlocksRangeSize = 6 * maxBlockSize
+
 // BlockData is a structure of the block's header
 type BlockData struct {
 	BlockID      int64
@@ -87,6 +95,21 @@ func GetHTTPTextAnswer(url string) (string, error) {
 	return string(htmlData), err
 }
 
+// infoError is the error with the place where it has happened
+type infoError struct {
+	err error
+	msg string
+}
+
+func (e *infoError) Error() string {
+	return e.msg
+}
+
+// Cause returns the original error, so the callers can check its type
+func (e *infoError) Cause() error {
+	return e.err
+}
+
 // ErrInfoFmt fomats the error message
 func ErrInfoFmt(err string, a ...interface{}) error {
 	return fmt.Errorf("%s (%s)", fmt.Sprintf(err, a...), Caller(1))
@@ -103,9 +126,9 @@ func ErrInfo(verr interface{}, additionally ...string) error {
 	}
 	if err != nil {
 		if len(additionally) > 0 {
-			return fmt.Errorf("%s # %s (%s)", err, additionally, Caller(1))
+			return &infoError{err: err, msg: fmt.Sprintf("%s # %s (%s)", err, additionally, Caller(1))}
 		}
-		return fmt.Errorf("%s (%s)", err, Caller(1))
+		return &infoError{err: err, msg: fmt.Sprintf("%s (%s)", err, Caller(1))}
 	}
 	return err
 }
@@ -287,8 +310,41 @@ func TypeInt(txType string) int64 {
 	return 0
 }
 
-// TCPConn connects to the address
+// TCPConn connects to the address, exchanges handshakes with the remote node and
+// returns the encrypted connection
 func TCPConn(Addr string) (net.Conn, error) {
+	conn, err := dialTCP(Addr)
+	if err != nil {
+		return nil, err
+	}
+	secure, err := handshake(conn, Addr)
+	if err == nil {
+		return secure, nil
+	}
+	conn.Close()
+	if !closedByNode(err) || !network.AllowPlaintext() {
+		log.WithFields(log.Fields{"type": consts.ProtocolError, "error": err, "address": Addr}).Warning("handshake with the node")
+		return nil, err
+	}
+	log.WithFields(log.Fields{"address": Addr}).Debug("node doesn't support handshake")
+	network.SetHostTypes(Addr, nil)
+	return dialTCP(Addr)
+}
+
+// closedByNode returns true if the node has closed the connection during the handshake.
+// Old nodes close the connection on the unknown request type without reading the rest of the handshake,
+// so the connection is reset instead of the normal closing.
+func closedByNode(err error) bool {
+	if opErr, ok := err.(*net.OpError); ok {
+		err = opErr.Err
+	}
+	if sysErr, ok := err.(*os.SyscallError); ok {
+		err = sysErr.Err
+	}
+	return err == io.EOF || err == syscall.ECONNRESET || err == syscall.EPIPE
+}
+
+func dialTCP(Addr string) (net.Conn, error) {
 	conn, err := net.DialTimeout("tcp", Addr, 10*time.Second)
 	if err != nil {
 		log.WithFields(log.Fields{"type": consts.ConnectionError, "error": err, "address": Addr}).Debug("dialing tcp")
@@ -299,6 +355,46 @@ func TCPConn(Addr string) (net.Conn, error) {
 	return conn, nil
 }
 
+// handshake sends the handshake of this node, checks the answer of the remote node
+// and encrypts the connection if the remote node supports it
+func handshake(conn net.Conn, Addr string) (net.Conn, error) {
+	privateKey, publicKey, err := GetNodeKeys()
+	if err != nil {
+		return nil, err
+	}
+	pub, err := hex.DecodeString(publicKey)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.ConversionError, "error": err}).Error("decoding node public key from hex")
+		return nil, err
+	}
+	local, err := network.NewHandshake(privateKey, pub, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = network.WriteHandshake(conn, local); err != nil {
+		return nil, err
+	}
+	remote, err := network.ReadAnswer(conn)
+	if err != nil {
+		return nil, err
+	}
+	if err = remote.Check(local.Nonce); err != nil {
+		return nil, err
+	}
+	if err = remote.CheckHost(Addr); err != nil {
+		log.WithFields(log.Fields{"type": consts.ProtocolError, "error": err, "address": Addr, "key_id": remote.KeyID}).Warning("checking key of full node")
+		return nil, err
+	}
+	network.SetHostTypes(Addr, remote.Types)
+	if !remote.Encrypted() {
+		if !network.AllowPlaintext() {
+			return nil, network.ErrPlaintext
+		}
+		return conn, nil
+	}
+	return network.SecureConn(conn, local, remote)
+}
+
 // GetCurrentDir returns the current directory
 func GetCurrentDir() string {
 	dir, err := filepath.Abs(filepath.Dir(os.Args[0]))
@@ -341,7 +437,7 @@ func GetBlockBody(host string, blockID int64, dataTypeBlockBody int64) ([]byte,
 	// if the data size is less than 10mb, we will receive them
 	dataSize := converter.BinToDec(buf)
 	var binaryBlock []byte
-	if dataSize < 10485760 && dataSize > 0 {
+	if dataSize < maxBlockSize && dataSize > 0 {
 		binaryBlock = make([]byte, dataSize)
 
 		_, err = io.ReadFull(conn, binaryBlock)
@@ -357,6 +453,83 @@ func GetBlockBody(host string, blockID int64, dataTypeBlockBody int64) ([]byte,
 
 }
 
+// GetBlocksRange gets the bodies of count blocks starting from blockID through one connection.
+// The host can return fewer blocks if it doesn't have all of them.
+func GetBlocksRange(host string, blockID, count int64) ([][]byte, error) {
+	if !network.HostSupports(host, consts.DATA_TYPE_BLOCK_RANGE) {
+		return nil, network.ErrUnsupported
+	}
+	conn, err := TCPConn(host)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	request := append(converter.DecToBin(consts.DATA_TYPE_BLOCK_RANGE, 2), converter.DecToBin(blockID, 4)...)
+	request = append(request, converter.DecToBin(count, 4)...)
+	if _, err = conn.Write(request); err != nil {
+		log.WithFields(log.Fields{"type": consts.IOError, "error": err, "host": host}).Error("writing block range request to connection")
+		return nil, err
+	}
+	return ReadBlocksRange(conn, count, func() {
+		conn.SetReadDeadline(time.Now().Add(consts.READ_TIMEOUT * time.Second))
+	})
+}
+
+// WriteBlocksRange writes the bodies of blocks prefixed with their sizes and finishes the stream with zero size
+func WriteBlocksRange(w io.Writer, blocks [][]byte) error {
+	for _, data := range append(blocks, nil) {
+		if _, err := w.Write(converter.DecToBin(len(data), 4)); err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadBlocksRange reads the stream of WriteBlocksRange, it fails if there are more than max blocks
+// or their total size is too large.
+// The extend function is called before reading of every block if it is not nil.
+func ReadBlocksRange(r io.Reader, max int64, extend func()) ([][]byte, error) {
+	var blocks [][]byte
+	limit := int64(maxBlocksRangeSize)
+	if max < limit/maxBlockSize {
+		limit = max * maxBlockSize
+	}
+	var total int64
+	buf := make([]byte, 4)
+	for {
+		if extend != nil {
+			extend()
+		}
+		if _, err := io.ReadFull(r, buf); err != nil {
+			log.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("reading block data size from connection")
+			return nil, err
+		}
+		dataSize := converter.BinToDec(buf)
+		if dataSize == 0 {
+			break
+		}
+		if dataSize >= maxBlockSize || int64(len(blocks)) >= max {
+			log.WithFields(log.Fields{"type": consts.ProtocolError, "size": dataSize, "count": len(blocks)}).Error("wrong block range")
+			return nil, errors.New("wrong block range")
+		}
+		if total += dataSize; total > limit {
+			log.WithFields(log.Fields{"type": consts.ProtocolError, "size": total, "limit": limit}).Error("block range is too large")
+			return nil, errors.New("block range is too large")
+		}
+		data := make([]byte, dataSize)
+		if _, err := io.ReadFull(r, data); err != nil {
+			log.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("reading block data from connection")
+			return nil, err
+		}
+		blocks = append(blocks, data)
+	}
+	return blocks, nil
+}
+
 // ShellExecute runs cmdline
 func ShellExecute(cmdline string) {
 	time.Sleep(500 * time.Millisecond)
@@ -0,0 +1,91 @@
+// MIT License
+//
+// Copyright (c) 2016-2018 GACHAIN
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package utils
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GACHAIN/go-gachain/packages/conf"
+	"github.com/GACHAIN/go-gachain/packages/consts"
+	"github.com/GACHAIN/go-gachain/packages/crypto"
+	"github.com/GACHAIN/go-gachain/packages/network"
+)
+
+func TestTCPConnLegacy(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `utils`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	priv, _, err := crypto.GenHexKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, consts.NodePrivateKeyFilename), []byte(priv), 0600); err != nil {
+		t.Fatal(err)
+	}
+	privateDir, plaintext := conf.Config.PrivateDir, conf.Config.LegacyPlaintext
+	defer func() {
+		conf.Config.PrivateDir, conf.Config.LegacyPlaintext = privateDir, plaintext
+	}()
+	conf.Config.PrivateDir = dir
+
+	listener, err := net.Listen(`tcp`, `127.0.0.1:0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			// the old node reads the request type and closes the connection
+			// without reading the handshake, so the connection is reset
+			conn.Read(make([]byte, 2))
+			conn.Close()
+		}
+	}()
+	addr := listener.Addr().String()
+
+	conf.Config.LegacyPlaintext = false
+	if conn, err := TCPConn(addr); err == nil {
+		conn.Close()
+		t.Error(`plaintext connection must be rejected`)
+	}
+
+	conf.Config.LegacyPlaintext = true
+	conn, err := TCPConn(addr)
+	if err != nil {
+		t.Fatalf(`old node must be connected without handshake: %s`, err)
+	}
+	conn.Close()
+	if network.HostSupports(addr, consts.DATA_TYPE_BLOCK_RANGE) {
+		t.Error(`old node must support only legacy request types`)
+	}
+}
@@ -0,0 +1,138 @@
+package client
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/GACHAIN/go-gachain/packages/consts"
+	"github.com/GACHAIN/go-gachain/packages/converter"
+	"github.com/GACHAIN/go-gachain/packages/crypto"
+)
+
+// Client sends requests to the api of the node
+type Client struct {
+	URL        string
+	PrivateKey string
+	PublicKey  string
+	token      string
+}
+
+type TxStatus struct {
+	BlockID string `json:"blockid"`
+	Message *struct {
+		Type  string `json:"type,omitempty"`
+		Error string `json:"error,omitempty"`
+	} `json:"errmsg,omitempty"`
+	Result string `json:"result"`
+}
+
+// NewClient returns the client with the specified private key in hex
+func NewClient(nodeURL, privateKey string) (*Client, error) {
+	key, err := hex.DecodeString(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := crypto.PrivateToPublic(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{URL: strings.TrimRight(nodeURL, `/`), PrivateKey: privateKey,
+		PublicKey: hex.EncodeToString(pub)}, nil
+}
+
+func (c *Client) send(method, path string, form url.Values, v interface{}) error {
+	req, err := http.NewRequest(method, c.URL+consts.ApiPath+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if len(c.token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%d %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (c *Client) sign(forSign string) (string, error) {
+	sign, err := crypto.Sign(c.PrivateKey, forSign)
+	if err != nil {
+		return ``, err
+	}
+	return hex.EncodeToString(sign), nil
+}
+
+// Login authorizes the client in the ecosystem
+func (c *Client) Login(ecosystem int64) error {
+	var uid struct {
+		UID   string `json:"uid"`
+		Token string `json:"token"`
+	}
+	if err := c.send(`GET`, `getuid`, nil, &uid); err != nil {
+		return err
+	}
+	c.token = uid.Token
+	sign, err := c.sign(uid.UID)
+	if err != nil {
+		return err
+	}
+	var login struct {
+		Token string `json:"token"`
+	}
+	if err = c.send(`POST`, `login`, url.Values{`pubkey`: {c.PublicKey}, `signature`: {sign},
+		`ecosystem`: {converter.Int64ToStr(ecosystem)}}, &login); err != nil {
+		return err
+	}
+	c.token = login.Token
+	return nil
+}
+
+// SendContract signs and sends the contract transaction, it returns the hash of the transaction
+func (c *Client) SendContract(name string, params url.Values) (string, error) {
+	var prepare struct {
+		ForSign string `json:"forsign"`
+		Time    string `json:"time"`
+	}
+	if err := c.send(`POST`, `prepare/`+name, params, &prepare); err != nil {
+		return ``, err
+	}
+	sign, err := c.sign(prepare.ForSign)
+	if err != nil {
+		return ``, err
+	}
+	form := url.Values{`time`: {prepare.Time}, `signature`: {sign}, `pubkey`: {c.PublicKey}}
+	for key, val := range params {
+		form[key] = val
+	}
+	var ret struct {
+		Hash string `json:"hash"`
+	}
+	if err = c.send(`POST`, `contract/`+name, form, &ret); err != nil {
+		return ``, err
+	}
+	return ret.Hash, nil
+}
+
+// TxStatus returns the status of the transaction
+func (c *Client) TxStatus(hash string) (*TxStatus, error) {
+	var status TxStatus
+	if err := c.send(`GET`, `txstatus/`+hash, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/GACHAIN/go-gachain/tools/table_import/client"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var nodeURL *string = flag.String("node", "http://127.0.0.1:7079", "url of the node api")
+var keyPath *string = flag.String("keyPath", "PrivateKey", "path to the file with the private key in hex")
+var ecosystem *int64 = flag.Int64("ecosystem", 1, "ecosystem id")
+var tableName *string = flag.String("table", "", "name of the table for importing")
+var csvPath *string = flag.String("csv", "", "path to the csv file, the first line must contain the names of columns")
+var batchSize *int = flag.Int("batch", 100, "count of rows in one transaction")
+var contractName *string = flag.String("contract", "Import", "contract which inserts data with DBInsert")
+var progressPath *string = flag.String("progress", "", "path to the progress file, by default it is the csv file with .progress extension")
+var waitTimeout *int = flag.Int("waitTimeout", 60, "timeout in seconds for waiting of the transaction status")
+
+// Batch is the state of the transaction with the part of rows
+type Batch struct {
+	Hash    string `json:"hash,omitempty"`
+	BlockID string `json:"block_id,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Progress is stored after each transaction so the import can be resumed
+type Progress struct {
+	Table   string  `json:"table"`
+	Batch   int     `json:"batch"`
+	Batches []Batch `json:"batches"`
+}
+
+func loadProgress(fileName string) (*Progress, error) {
+	progress := &Progress{Table: *tableName, Batch: *batchSize}
+	data, err := ioutil.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return progress, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, progress); err != nil {
+		return nil, err
+	}
+	if progress.Table != *tableName || progress.Batch != *batchSize {
+		return nil, fmt.Errorf("progress file %s has been created for table %s and batch %d", fileName,
+			progress.Table, progress.Batch)
+	}
+	return progress, nil
+}
+
+func (p *Progress) save(fileName string) error {
+	data, err := json.MarshalIndent(p, ``, `  `)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fileName, data, 0644)
+}
+
+func readBatches(fileName string, size int) (columns []string, batches [][][]string, err error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	if columns, err = reader.Read(); err != nil {
+		return
+	}
+	var rows [][]string
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		rows = append(rows, row)
+		if len(rows) == size {
+			batches = append(batches, rows)
+			rows = nil
+		}
+	}
+	if len(rows) > 0 {
+		batches = append(batches, rows)
+	}
+	return
+}
+
+// waitTx returns true if the transaction has been written into the block
+func waitTx(c *client.Client, batch *Batch) (bool, error) {
+	for i := 0; i < *waitTimeout; i++ {
+		status, err := c.TxStatus(batch.Hash)
+		if err != nil {
+			return false, err
+		}
+		if len(status.BlockID) > 0 {
+			batch.BlockID = status.BlockID
+			return true, nil
+		}
+		if status.Message != nil {
+			batch.Error = status.Message.Error
+			return false, nil
+		}
+		time.Sleep(time.Second)
+	}
+	return false, fmt.Errorf("timeout of waiting for transaction %s", batch.Hash)
+}
+
+func importBatch(c *client.Client, columns []string, rows [][]string) (string, error) {
+	data, err := json.Marshal(map[string]interface{}{`data`: []interface{}{map[string]interface{}{
+		`Table`: *tableName, `Columns`: columns, `Data`: rows}}})
+	if err != nil {
+		return ``, err
+	}
+	return c.SendContract(*contractName, url.Values{`Data`: {string(data)}})
+}
+
+func main() {
+	flag.Parse()
+	if len(*tableName) == 0 || len(*csvPath) == 0 {
+		log.Fatal("table and csv must be specified")
+	}
+	if len(*progressPath) == 0 {
+		*progressPath = *csvPath + `.progress`
+	}
+	key, err := ioutil.ReadFile(*keyPath)
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Fatal("reading private key")
+	}
+	c, err := client.NewClient(*nodeURL, strings.TrimSpace(string(key)))
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Fatal("creating client")
+	}
+	if err = c.Login(*ecosystem); err != nil {
+		log.WithFields(log.Fields{"error": err}).Fatal("login")
+	}
+	columns, batches, err := readBatches(*csvPath, *batchSize)
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Fatal("reading csv")
+	}
+	progress, err := loadProgress(*progressPath)
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Fatal("loading progress")
+	}
+	var total, imported int
+	for _, rows := range batches {
+		total += len(rows)
+	}
+	for len(progress.Batches) < len(batches) {
+		progress.Batches = append(progress.Batches, Batch{})
+	}
+	for i, rows := range batches {
+		batch := &progress.Batches[i]
+		if len(batch.BlockID) > 0 {
+			imported += len(rows)
+			continue
+		}
+		if len(batch.Hash) == 0 || len(batch.Error) > 0 {
+			batch.Error = ``
+			if batch.Hash, err = importBatch(c, columns, rows); err != nil {
+				log.WithFields(log.Fields{"batch": i, "error": err}).Fatal("sending transaction")
+			}
+			if err = progress.save(*progressPath); err != nil {
+				log.WithFields(log.Fields{"error": err}).Fatal("saving progress")
+			}
+		}
+		done, err := waitTx(c, batch)
+		if err := progress.save(*progressPath); err != nil {
+			log.WithFields(log.Fields{"error": err}).Fatal("saving progress")
+		}
+		if err != nil {
+			log.WithFields(log.Fields{"batch": i, "hash": batch.Hash, "error": err}).Fatal("waiting for transaction")
+		}
+		if !done {
+			log.WithFields(log.Fields{"batch": i, "hash": batch.Hash, "error": batch.Error}).Fatal("transaction has failed")
+		}
+		imported += len(rows)
+		fmt.Printf("%d/%d rows have been imported\n", imported, total)
+	}
+	fmt.Println("import has been completed")
+}